@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// defaultAgentAddress is the address agent listens on when --listen is not set.
+// Agent has no authentication so by default it is reachable only from localhost.
+const defaultAgentAddress = "127.0.0.1:7700"
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run agent of distributed benchmark",
+	Long: `Agent runs its part of the benchmark started with benchmark --agents and sends results back.
+Files used by the benchmark like feeder, certificates or descriptor set have to exist on the agent host.
+
+Agent has no authentication and runs any benchmark it is sent, so anyone who can reach it can use it
+to send load to any URL. By default it listens only on localhost, listen on the address of a trusted
+network reachable by the coordinator, never on a public one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("listen", cmd.Flags().Lookup("listen"))
+
+		listen := viper.GetString("listen")
+
+		log.Printf("Agent listening on %s", listen)
+		if err := http.ListenAndServe(listen, katyusha.NewAgent()); err != nil {
+			log.Fatalf("Agent error: %v", err)
+		}
+	},
+}
+
+func init() {
+	agentCmd.Flags().StringP("listen", "l", defaultAgentAddress, "Address agent listens on")
+
+	viper.BindPFlags(agentCmd.Flags())
+
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// baselineClearCmd represents the baseline clear command
+var baselineClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove baseline of benchmark configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("benchmark", cmd.Flags().Lookup("benchmark"))
+
+		inv, err := katyusha.NewInventory(viper.GetString("db"))
+		if err != nil {
+			log.Fatalf("Can't create database file: %v", err)
+		}
+
+		err = inv.ClearBaseline(context.Background(), viper.GetInt64("benchmark"))
+		if err != nil {
+			log.Fatalf("Can't clear baseline: %v", err)
+		}
+
+		log.Printf("Baseline cleared\n")
+	},
+}
+
+func init() {
+	baselineClearCmd.Flags().Int64P("benchmark", "b", 0, "Benchmark configuration ID")
+
+	baselineClearCmd.MarkFlagRequired("benchmark")
+	viper.BindPFlags(baselineClearCmd.Flags())
+
+	baselineCmd.AddCommand(baselineClearCmd)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// baselineSetCmd represents the baseline set command
+var baselineSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Pin summary as the baseline of its benchmark configuration, replacing the previous baseline",
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := katyusha.NewInventory(viper.GetString("db"))
+		if err != nil {
+			log.Fatalf("Can't create database file: %v", err)
+		}
+
+		err = inv.SetBaseline(context.Background(), viper.GetInt64("summary"))
+		if err != nil {
+			log.Fatalf("Can't set baseline: %v", err)
+		}
+
+		log.Printf("Summary %d set as baseline\n", viper.GetInt64("summary"))
+	},
+}
+
+func init() {
+	baselineSetCmd.Flags().Int64P("summary", "s", 0, "Summary ID")
+
+	baselineSetCmd.MarkFlagRequired("summary")
+	viper.BindPFlags(baselineSetCmd.Flags())
+
+	baselineCmd.AddCommand(baselineSetCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// baselineShowCmd represents the baseline show command
+var baselineShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show baseline summary of benchmark configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("benchmark", cmd.Flags().Lookup("benchmark"))
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+
+		output := viper.GetString("output")
+		if err := checkOutputFormat(output); err != nil {
+			log.Fatal(err)
+		}
+
+		inv, err := katyusha.NewInventory(viper.GetString("db"))
+		if err != nil {
+			log.Fatalf("Can't create database file: %v", err)
+		}
+
+		baseline, err := inv.FindBaseline(context.Background(), viper.GetInt64("benchmark"))
+		if err != nil {
+			log.Fatalf("Can't get baseline summary: %v", err)
+		}
+
+		if baseline == nil {
+			fmt.Fprintf(os.Stderr, "Baseline for benchmark %d is not set\n", viper.GetInt64("benchmark"))
+			return
+		}
+
+		if output != outputText {
+			err = writeSummaries(output, []*katyusha.BenchmarkSummary{baseline}, false)
+			if err != nil {
+				log.Fatalf("Can't write baseline: %v", err)
+			}
+
+			return
+		}
+
+		fmt.Printf("Baseline summary ID %d\n", baseline.ID)
+		fmt.Printf("%s\n", baseline)
+	},
+}
+
+func init() {
+	baselineShowCmd.Flags().Int64P("benchmark", "b", 0, "Benchmark configuration ID")
+	baselineShowCmd.Flags().StringP("output", "o", outputText, "Output format: text, json, yaml or csv")
+
+	baselineShowCmd.MarkFlagRequired("benchmark")
+	viper.BindPFlags(baselineShowCmd.Flags())
+
+	baselineCmd.AddCommand(baselineShowCmd)
+}
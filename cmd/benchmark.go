@@ -11,10 +11,14 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/tmwalaszek/katyusha/katyusha"
+	"golang.org/x/term"
 )
 
 var benchmarkConf string
 
+// thresholdsExitCode is returned when the benchmark does not meet at least one threshold
+const thresholdsExitCode = 2
+
 // benchmarkCmd represents the benchmark command
 var benchmarkCmd = &cobra.Command{
 	Use:   "benchmark",
@@ -22,6 +26,13 @@ var benchmarkCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// workaround for https://github.com/spf13/viper/issues/233
 		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+		viper.BindPFlag("tolerance", cmd.Flags().Lookup("tolerance"))
+
+		output := viper.GetString("output")
+		if err := checkOutputFormat(output); err != nil {
+			log.Fatal(err)
+		}
 
 		var bcID int64
 		var bcs []*katyusha.BenchmarkConfiguration
@@ -102,24 +113,109 @@ var benchmarkCmd = &cobra.Command{
 			}
 		}
 
+		// Status line is refreshed in place on terminal, otherwise every progress is printed on its own line
+		refresh := term.IsTerminal(int(os.Stderr.Fd()))
+		if viper.GetBool("progress") {
+			benchmark.OnProgress = func(p katyusha.Progress) {
+				if refresh {
+					fmt.Fprintf(os.Stderr, "\r\033[K%s", p)
+				} else {
+					fmt.Fprintf(os.Stderr, "%s\n", p)
+				}
+			}
+		}
+
 		var summary *katyusha.Summary
 		if !viper.GetBool("norun") {
-			summary = benchmark.StartBenchmark(ctx)
-			fmt.Println(summary)
+			if agents := viper.GetStringSlice("agents"); len(agents) > 0 {
+				summary, err = benchmark.StartDistributed(ctx, agents)
+			} else {
+				summary = benchmark.StartBenchmark(ctx)
+			}
+
+			if viper.GetBool("progress") && refresh {
+				fmt.Fprintf(os.Stderr, "\r\033[K")
+			}
+
+			if err != nil {
+				log.Fatalf("Distributed benchmark error: %v", err)
+			}
+
+			if output == outputText {
+				fmt.Println(summary)
+			} else if output == outputCSV {
+				err = writeOutput(os.Stdout, output, summaryRows(summary))
+			} else {
+				err = writeOutput(os.Stdout, output, summary)
+			}
+
+			if err != nil {
+				log.Fatalf("Can't write summary: %v", err)
+			}
 		}
 
 		if !viper.GetBool("norun") && viper.GetBool("save") {
-			err = inv.InsertBenchmarkSummary(ctx, summary, bcID)
+			smID, err := inv.InsertBenchmarkSummary(ctx, summary, bcID)
 			if err != nil {
 				log.Fatalf("Error saving summary: %v", err)
 			}
+
+			baseline, err := inv.FindBaseline(ctx, bcID)
+			if err != nil {
+				log.Fatalf("Can't get baseline summary: %v", err)
+			}
+
+			if baseline != nil {
+				current := &katyusha.BenchmarkSummary{ID: smID, Summary: *summary}
+				comparison := katyusha.CompareSummaries(baseline, current, viper.GetFloat64("tolerance"))
+
+				// Keep stdout parsable when machine readable output is used
+				w := os.Stdout
+				if output != outputText {
+					w = os.Stderr
+				}
+
+				fmt.Fprint(w, comparison)
+			}
+		}
+
+		if !viper.GetBool("norun") && len(benchmarkParams.Thresholds) > 0 {
+			results, err := katyusha.CheckThresholds(summary, benchmarkParams.Thresholds)
+			if err != nil {
+				log.Fatalf("Can't check thresholds: %v", err)
+			}
+
+			// Keep stdout parsable when machine readable output is used
+			w := os.Stdout
+			if output != outputText {
+				w = os.Stderr
+			}
+
+			failed := false
+			fmt.Fprintln(w, "Thresholds:")
+			for _, result := range results {
+				fmt.Fprintln(w, result)
+				if !result.Pass {
+					failed = true
+				}
+			}
+
+			if failed {
+				os.Exit(thresholdsExitCode)
+			}
 		}
 	},
 }
 
 func benchmarkOptionsToStruct() (*katyusha.BenchmarkParameters, error) {
+	var scenario []katyusha.Endpoint
+	if err := viper.UnmarshalKey("scenario", &scenario); err != nil {
+		return nil, fmt.Errorf("Can't parse scenario: %w", err)
+	}
+
+	// Host is not needed when all scenario endpoints have full URLs
 	host := viper.GetString("host")
-	if host == "" {
+	if host == "" && len(scenario) == 0 {
 		return nil, fmt.Errorf("Host not provided")
 	}
 
@@ -140,23 +236,77 @@ func benchmarkOptionsToStruct() (*katyusha.BenchmarkParameters, error) {
 		}
 	}
 
+	var stages []katyusha.Stage
+	if err := viper.UnmarshalKey("stages", &stages); err != nil {
+		return nil, fmt.Errorf("Can't parse stages: %w", err)
+	}
+
+	var assertions []katyusha.Assertion
+	if err := viper.UnmarshalKey("assertions", &assertions); err != nil {
+		return nil, fmt.Errorf("Can't parse assertions: %w", err)
+	}
+
+	var feeder *katyusha.Feeder
+	if viper.IsSet("feeder") {
+		feeder = &katyusha.Feeder{}
+		if err := viper.UnmarshalKey("feeder", feeder); err != nil {
+			return nil, fmt.Errorf("Can't parse feeder: %w", err)
+		}
+	}
+
+	var webSocket *katyusha.WebSocket
+	if viper.IsSet("websocket") {
+		webSocket = &katyusha.WebSocket{}
+		if err := viper.UnmarshalKey("websocket", webSocket); err != nil {
+			return nil, fmt.Errorf("Can't parse websocket: %w", err)
+		}
+	}
+
+	var grpc *katyusha.GRPC
+	if viper.IsSet("grpc") {
+		grpc = &katyusha.GRPC{}
+		if err := viper.UnmarshalKey("grpc", grpc); err != nil {
+			return nil, fmt.Errorf("Can't parse grpc: %w", err)
+		}
+	}
+
+	var streaming *katyusha.Streaming
+	if viper.IsSet("streaming") {
+		streaming = &katyusha.Streaming{}
+		if err := viper.UnmarshalKey("streaming", streaming); err != nil {
+			return nil, fmt.Errorf("Can't parse streaming: %w", err)
+		}
+	}
+
 	return &katyusha.BenchmarkParameters{
 		URL:             host,
 		Method:          viper.GetString("method"),
 		ReqCount:        viper.GetInt("requests"),
 		AbortAfter:      viper.GetInt("abort"),
 		ConcurrentConns: viper.GetInt("connections"),
+		Rate:            viper.GetInt("rate"),
+		Protocol:        viper.GetString("protocol"),
 		SkipVerify:      viper.GetBool("insecure"),
 		CA:              viper.GetString("ca"),
 		Cert:            viper.GetString("cert"),
 		Key:             viper.GetString("key"),
 		Duration:        viper.GetDuration("duration"),
+		ReportInterval:  viper.GetDuration("report_interval"),
 		KeepAlive:       viper.GetDuration("keep_alive"),
 		RequestDelay:    viper.GetDuration("request_delay"),
 		ReadTimeout:     viper.GetDuration("read_timeout"),
 		WriteTimeout:    viper.GetDuration("write_timeout"),
 		Headers:         headers,
 		Parameters:      params,
+		Stages:          stages,
+		Scenario:        scenario,
+		Thresholds:      viper.GetStringSlice("threshold"),
+		Feeder:          feeder,
+		ExpectedStatus:  viper.GetStringSlice("expected_status"),
+		Assertions:      assertions,
+		WebSocket:       webSocket,
+		GRPC:            grpc,
+		Streaming:       streaming,
 	}, nil
 }
 
@@ -165,23 +315,32 @@ func init() {
 	benchmarkCmd.Flags().String("description", "Default benchmark description", "Benchmark description used in database")
 	benchmarkCmd.Flags().String("host", "", "Host")
 	benchmarkCmd.Flags().StringP("method", "m", "", "HTTP Method")
+	benchmarkCmd.Flags().String("protocol", katyusha.ProtocolHTTP1, "HTTP protocol: http1, http2 or http3, http2 uses h2c for http URLs")
 	benchmarkCmd.Flags().StringP("ca", "c", "", "CA path")
 	benchmarkCmd.Flags().StringP("cert", "F", "", "Cert path")
 	benchmarkCmd.Flags().StringP("key", "K", "", "Key path")
 	benchmarkCmd.Flags().BoolP("save", "S", false, "Save benchamrk configuration and result")
 	benchmarkCmd.Flags().BoolP("insecure", "i", false, "TLS Skip verify")
 	benchmarkCmd.Flags().BoolP("norun", "N", false, "Do not start benchmark")
+	benchmarkCmd.Flags().Bool("progress", term.IsTerminal(int(os.Stderr.Fd())), "Show live progress on stderr, on by default when stderr is a terminal")
+	benchmarkCmd.Flags().StringP("output", "o", outputText, "Output format: text, json, yaml or csv")
 	benchmarkCmd.Flags().DurationP("duration", "d", time.Duration(0), "Benchmark duration")
+	benchmarkCmd.Flags().Duration("report_interval", katyusha.DefaultReportInterval, "Length of intervals in summary time series")
 	benchmarkCmd.Flags().DurationP("keep_alive", "k", time.Duration(0), "HTTP Keep Alive")
 	benchmarkCmd.Flags().DurationP("request_delay", "D", time.Duration(0), "Request delay")
 	benchmarkCmd.Flags().DurationP("read_timeout", "R", time.Duration(0), "Read Timeout")
 	benchmarkCmd.Flags().DurationP("write_timeout", "W", time.Duration(0), "Write Timeout")
 	benchmarkCmd.Flags().IntP("requests", "r", 0, "Requests count")
 	benchmarkCmd.Flags().IntP("connections", "C", 0, "Concurrent connections")
+	benchmarkCmd.Flags().Int("rate", 0, "Requests per second scheduled independently of response times")
 	benchmarkCmd.Flags().IntP("abort", "a", 0, "Number of connections after which benchmark will be aborted")
 	benchmarkCmd.Flags().Int64P("id", "I", 0, "Benchmark configuration ID from database")
 	benchmarkCmd.Flags().StringSliceP("header", "H", nil, "Header, can be used multiple times")
 	benchmarkCmd.Flags().StringSliceP("parameter", "P", nil, "HTTP parameters, can be used multiple times")
+	benchmarkCmd.Flags().Float64P("tolerance", "t", katyusha.DefaultTolerance, "Percent change of a metric against baseline which is not reported as regression")
+	benchmarkCmd.Flags().StringSlice("expected_status", nil, "Status code, range or class of successful response e.g. 201, 200-204 or 2xx, can be used multiple times")
+	benchmarkCmd.Flags().StringSlice("threshold", nil, "Threshold which result has to meet, e.g. \"p99 < 250ms\", can be used multiple times")
+	benchmarkCmd.Flags().StringSlice("agents", nil, "Addresses of agents started with agent command which run the benchmark together")
 
 	viper.BindPFlags(benchmarkCmd.Flags())
 
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// regressionsExitCode is returned when compared summary has regressions
+const regressionsExitCode = 2
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare two benchmark summaries, or the latest summary of a benchmark against the baseline",
+	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("benchmark", cmd.Flags().Lookup("benchmark"))
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+		viper.BindPFlag("tolerance", cmd.Flags().Lookup("tolerance"))
+
+		output := viper.GetString("output")
+		if err := checkOutputFormat(output); err != nil {
+			log.Fatal(err)
+		}
+
+		inv, err := katyusha.NewInventory(viper.GetString("db"))
+		if err != nil {
+			log.Fatalf("Can't create database file: %v", err)
+		}
+
+		ctx := context.Background()
+
+		var base, current *katyusha.BenchmarkSummary
+		if bcID := viper.GetInt64("benchmark"); bcID != 0 {
+			base, err = inv.FindBaseline(ctx, bcID)
+			if err == nil && base == nil {
+				// Without pinned baseline the first summary is used
+				base, err = inv.FindFirstSummary(ctx, bcID)
+			}
+
+			if err != nil {
+				log.Fatalf("Can't get baseline summary: %v", err)
+			}
+
+			current, err = inv.FindLatestSummary(ctx, bcID)
+			if err != nil {
+				log.Fatalf("Can't get latest summary: %v", err)
+			}
+
+			if base == nil || current == nil {
+				log.Fatalf("No summaries for benchmark configuration %d", bcID)
+			}
+		} else {
+			if viper.GetInt64("base") == 0 || viper.GetInt64("current") == 0 {
+				cmd.Usage()
+				log.Fatalf("Provide --base and --current summary IDs or --benchmark ID")
+			}
+
+			base, err = inv.FindSummaryByID(ctx, viper.GetInt64("base"))
+			if err != nil {
+				log.Fatalf("Can't get base summary: %v", err)
+			}
+
+			if base == nil {
+				log.Fatalf("No summary at ID %d", viper.GetInt64("base"))
+			}
+
+			current, err = inv.FindSummaryByID(ctx, viper.GetInt64("current"))
+			if err != nil {
+				log.Fatalf("Can't get current summary: %v", err)
+			}
+
+			if current == nil {
+				log.Fatalf("No summary at ID %d", viper.GetInt64("current"))
+			}
+		}
+
+		comparison := katyusha.CompareSummaries(base, current, viper.GetFloat64("tolerance"))
+		if err := writeComparison(output, comparison); err != nil {
+			log.Fatalf("Can't write comparison: %v", err)
+		}
+
+		if len(comparison.Regressions()) > 0 {
+			os.Exit(regressionsExitCode)
+		}
+	},
+}
+
+// writeComparison writes comparison in given output format, CSV has a record for each metric
+func writeComparison(output string, comparison *katyusha.Comparison) error {
+	switch output {
+	case outputText:
+		_, err := fmt.Print(comparison)
+		return err
+	case outputCSV:
+		return writeOutput(os.Stdout, output, comparison.Metrics)
+	}
+
+	return writeOutput(os.Stdout, output, comparison)
+}
+
+func init() {
+	compareCmd.Flags().Int64("base", 0, "Base summary ID")
+	compareCmd.Flags().Int64("current", 0, "Current summary ID")
+	compareCmd.Flags().Int64P("benchmark", "b", 0, "Benchmark configuration ID, compares its latest summary against the baseline")
+	compareCmd.Flags().Float64P("tolerance", "t", katyusha.DefaultTolerance, "Percent change of a metric which is not reported as regression")
+	compareCmd.Flags().StringP("output", "o", outputText, "Output format: text, json, yaml or csv")
+
+	viper.BindPFlags(compareCmd.Flags())
+
+	inventoryCmd.AddCommand(compareCmd)
+}
@@ -14,6 +14,9 @@ var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete benchmark configurations with all data associated",
 	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("benchmark", cmd.Flags().Lookup("benchmark"))
+
 		inv, err := katyusha.NewInventory(viper.GetString("db"))
 		if err != nil {
 			log.Fatalf("Can't initialize database: %v", err)
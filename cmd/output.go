@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmwalaszek/katyusha/katyusha"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats supported by --output option.
+// Durations are written in nanoseconds in all machine-readable formats.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputCSV  = "csv"
+)
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputYAML, outputCSV:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown output format %s, use one of text, json, yaml or csv", format)
+}
+
+// writeOutput writes v in machine-readable format
+// For csv v needs to be a slice of structs, nested structs are flattened and nested slices of structs are skipped
+func writeOutput(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		// YAML is created from JSON so both formats have the same field names and units
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		var generic interface{}
+		if err := yaml.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+
+		b, err = yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(b)
+		return err
+	case outputCSV:
+		return writeCSV(w, v)
+	}
+
+	return fmt.Errorf("Unknown output format %s", format)
+}
+
+// writeCSV writes slice of structs as CSV with header taken from JSON field names
+func writeCSV(w io.Writer, v interface{}) error {
+	rows := reflect.ValueOf(v)
+	if rows.Kind() != reflect.Slice {
+		return fmt.Errorf("CSV output needs a list")
+	}
+
+	cw := csv.NewWriter(w)
+	for i := 0; i < rows.Len(); i++ {
+		var header, record []string
+		csvFields(reflect.Indirect(rows.Index(i)), "", false, &header, &record)
+
+		if i == 0 {
+			if err := cw.Write(header); err != nil {
+				return err
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFields flattens struct fields into header and record.
+// Nested structs are written as columns prefixed by the field name e.g. phases.dns.p99,
+// fields of nil struct pointers are empty so every record has the same columns.
+func csvFields(v reflect.Value, prefix string, empty bool, header, record *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			csvFields(value, prefix, empty, header, record)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		s, ok := csvValue(value)
+		if !ok {
+			switch {
+			case value.Kind() == reflect.Struct:
+				csvFields(value, prefix+name+".", empty, header, record)
+			case value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct:
+				if value.IsNil() {
+					csvFields(reflect.Zero(value.Type().Elem()), prefix+name+".", true, header, record)
+				} else {
+					csvFields(value.Elem(), prefix+name+".", empty, header, record)
+				}
+			}
+
+			continue
+		}
+
+		if empty {
+			s = ""
+		}
+
+		*header = append(*header, prefix+name)
+		*record = append(*record, s)
+	}
+}
+
+// csvValue formats a single value, it returns false for values which don't fit into one column
+// like structs, they are flattened by csvFields.
+// Maps are written as key=value pairs separated by semicolon, list items are separated by |
+func csvValue(v reflect.Value) (string, bool) {
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return strconv.FormatInt(int64(value), 10), true
+	case time.Time:
+		return value.Format(time.RFC3339Nano), true
+	case []byte:
+		return string(value), true
+	case []katyusha.Stage:
+		stages := make([]string, len(value))
+		for i, stage := range value {
+			stages[i] = fmt.Sprintf("%d/%d/%d", stage.Duration, stage.Connections, stage.Rate)
+		}
+
+		return strings.Join(stages, "|"), true
+	case []katyusha.Endpoint:
+		endpoints := make([]string, len(value))
+		for i, endpoint := range value {
+			endpoints[i] = fmt.Sprintf("%s/%s/%s/%d", endpoint.Name, endpoint.Method, endpoint.URL, endpoint.Weight)
+		}
+
+		return strings.Join(endpoints, "|"), true
+	case []katyusha.Assertion:
+		assertions := make([]string, len(value))
+		for i, assertion := range value {
+			assertions[i] = assertion.String()
+		}
+
+		return strings.Join(assertions, "|"), true
+	case *katyusha.Feeder:
+		if value == nil {
+			return "", true
+		}
+
+		return fmt.Sprintf("%s/%s/%s/%s", value.File, value.Format, value.Strategy, value.Exhausted), true
+	case *katyusha.WebSocket:
+		if value == nil {
+			return "", true
+		}
+
+		return value.String(), true
+	case *katyusha.GRPC:
+		if value == nil {
+			return "", true
+		}
+
+		return value.String(), true
+	case *katyusha.Streaming:
+		if value == nil {
+			return "", true
+		}
+
+		return value.String(), true
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		pairs := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", key.Interface(), v.MapIndex(key).Interface()))
+		}
+		sort.Strings(pairs)
+
+		return strings.Join(pairs, ";"), true
+	case reflect.Slice:
+		// Lists of results like stages or intervals are written as separate records by the caller
+		kind := v.Type().Elem().Kind()
+		if kind == reflect.Struct || kind == reflect.Ptr {
+			return "", false
+		}
+
+		items := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items[i], _ = csvValue(v.Index(i))
+		}
+
+		return strings.Join(items, "|"), true
+	case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan, reflect.Struct:
+		return "", false
+	}
+
+	return fmt.Sprint(v.Interface()), true
+}
+
+// summaryRows returns summary followed by its stages and endpoints as CSV records
+func summaryRows(summary *katyusha.Summary) []*katyusha.Summary {
+	rows := append([]*katyusha.Summary{summary}, summary.Stages...)
+	return append(rows, summary.Endpoints...)
+}
+
+// intervalRow is CSV record of summary time series
+type intervalRow struct {
+	SummaryID int64 `json:"summary_id"`
+	katyusha.Interval
+}
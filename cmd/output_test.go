@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tmwalaszek/katyusha/katyusha"
+	"gopkg.in/yaml.v2"
+)
+
+// testSummary returns summary with stages, endpoints, errors and phases
+func testSummary() *katyusha.Summary {
+	return &katyusha.Summary{
+		URL:         "http://katyusha.test",
+		Start:       time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		TotalTime:   2 * time.Second,
+		ReqCount:    10,
+		SuccessReq:  7,
+		FailReq:     3,
+		P99ReqTime:  40 * time.Millisecond,
+		Errors:      map[string]int{"500": 2, "timeout": 1},
+		StatusCodes: map[int]int{200: 7, 500: 2},
+		Phases: katyusha.Phases{
+			DNS:  katyusha.PhaseStat{Count: 1, P99: 3 * time.Millisecond},
+			TTFB: katyusha.PhaseStat{Count: 10, P99: 30 * time.Millisecond},
+		},
+		Streams: &katyusha.StreamStat{Connections: 2, Streams: 10},
+		Stages: []*katyusha.Summary{
+			{URL: "http://katyusha.test", Stage: 1, ReqCount: 4},
+			{URL: "http://katyusha.test", Stage: 2, ReqCount: 6, Errors: map[string]int{"500": 2}},
+		},
+		Endpoints: []*katyusha.Summary{
+			{URL: "http://katyusha.test/login", Endpoint: "login", ReqCount: 10, Phases: katyusha.Phases{DNS: katyusha.PhaseStat{Count: 1}}},
+		},
+	}
+}
+
+// lookup returns value at path in decoded JSON or YAML document
+func lookup(doc interface{}, path ...interface{}) interface{} {
+	for _, key := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[fmt.Sprint(key)]
+		case map[interface{}]interface{}:
+			doc = v[key]
+		case []interface{}:
+			i, ok := key.(int)
+			if !ok || i >= len(v) {
+				return nil
+			}
+			doc = v[i]
+		default:
+			return nil
+		}
+	}
+
+	return doc
+}
+
+func TestWriteOutputDocument(t *testing.T) {
+	tt := []struct {
+		format string
+		decode func([]byte) (interface{}, error)
+	}{
+		{
+			format: outputJSON,
+			decode: func(b []byte) (interface{}, error) {
+				var doc interface{}
+				dec := json.NewDecoder(bytes.NewReader(b))
+				dec.UseNumber()
+				return doc, dec.Decode(&doc)
+			},
+		},
+		{
+			format: outputYAML,
+			decode: func(b []byte) (interface{}, error) {
+				var doc interface{}
+				return doc, yaml.Unmarshal(b, &doc)
+			},
+		},
+	}
+
+	fields := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{[]interface{}{"url"}, "http://katyusha.test"},
+		{[]interface{}{"duration"}, "2000000000"},
+		{[]interface{}{"p99_req_time"}, "40000000"},
+		{[]interface{}{"errors", "500"}, "2"},
+		{[]interface{}{"errors", "timeout"}, "1"},
+		{[]interface{}{"phases", "dns", "p99"}, "3000000"},
+		{[]interface{}{"phases", "ttfb", "count"}, "10"},
+		{[]interface{}{"streams", "connections"}, "2"},
+		{[]interface{}{"stages", 1, "stage"}, "2"},
+		{[]interface{}{"stages", 1, "errors", "500"}, "2"},
+		{[]interface{}{"endpoints", 0, "endpoint"}, "login"},
+		{[]interface{}{"endpoints", 0, "phases", "dns", "count"}, "1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOutput(&buf, tc.format, testSummary()); err != nil {
+				t.Fatalf("Can't write output: %v", err)
+			}
+
+			doc, err := tc.decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Can't decode output: %v", err)
+			}
+
+			for _, field := range fields {
+				value := lookup(doc, field.path...)
+				if value == nil || fmt.Sprint(value) != field.expected {
+					t.Errorf("Field %v should be %s but it is %v", field.path, field.expected, value)
+				}
+			}
+
+			if value := lookup(doc, "websocket"); value != nil {
+				t.Errorf("Empty websocket should be omitted but it is %v", value)
+			}
+		})
+	}
+}
+
+func TestWriteOutputCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, outputCSV, summaryRows(testSummary())); err != nil {
+		t.Fatalf("Can't write output: %v", err)
+	}
+
+	// Reader fails when records have different number of columns than header
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Can't read CSV output: %v", err)
+	}
+
+	if len(records) != 5 {
+		t.Fatalf("Output should have header and 4 records but it has %d lines", len(records))
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		if _, ok := columns[name]; ok {
+			t.Errorf("Column %s is duplicated", name)
+		}
+		columns[name] = i
+	}
+
+	for _, name := range []string{
+		"url", "stage", "endpoint", "start", "duration", "requests_count", "p99_req_time", "std_deviation",
+		"errors", "status_codes", "phases.dns.count", "phases.dns.p99", "phases.ttfb.p99", "phases.transfer.max",
+		"streams.connections", "streams.max_concurrent", "websocket.connections", "websocket.setup.p99",
+		"streaming.events", "streaming.first_event.p50", "streaming.lifetime.max",
+	} {
+		if _, ok := columns[name]; !ok {
+			t.Errorf("Header has no %s column: %v", name, records[0])
+		}
+	}
+
+	for _, name := range []string{"stages", "endpoints", "intervals", "phases", "streams"} {
+		if _, ok := columns[name]; ok {
+			t.Errorf("Header should not have %s column", name)
+		}
+	}
+
+	tt := []struct {
+		record   int
+		column   string
+		expected string
+	}{
+		{1, "url", "http://katyusha.test"},
+		{1, "stage", "0"},
+		{1, "start", "2024-03-01T12:00:00Z"},
+		{1, "duration", strconv.Itoa(int(2 * time.Second))},
+		{1, "errors", "500=2;timeout=1"},
+		{1, "status_codes", "200=7;500=2"},
+		{1, "phases.dns.p99", "3000000"},
+		{1, "phases.ttfb.count", "10"},
+		{1, "streams.connections", "2"},
+		{1, "websocket.connections", ""},
+		{2, "stage", "1"},
+		{2, "requests_count", "4"},
+		{2, "streams.connections", ""},
+		{3, "stage", "2"},
+		{3, "errors", "500=2"},
+		{4, "endpoint", "login"},
+		{4, "phases.dns.count", "1"},
+		{4, "url", "http://katyusha.test/login"},
+	}
+
+	for _, tc := range tt {
+		i, ok := columns[tc.column]
+		if !ok {
+			continue
+		}
+
+		if value := records[tc.record][i]; value != tc.expected {
+			t.Errorf("Record %d column %s should be %q but it is %q", tc.record, tc.column, tc.expected, value)
+		}
+	}
+}
+
+func TestWriteOutputInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, outputCSV, testSummary()); err == nil {
+		t.Errorf("CSV output of a single summary should fail")
+	}
+
+	if err := writeOutput(&buf, "xml", testSummary()); err == nil {
+		t.Errorf("Unknown output format should fail")
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tmwalaszek/katyusha/katyusha"
+)
+
+// defaultServeAddress is the address API server listens on when --listen is not set
+const defaultServeAddress = "127.0.0.1:8080"
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run HTTP API server to manage and run benchmarks",
+	Long: `Serve provides REST API over the inventory to manage benchmark configurations,
+start and cancel runs, follow their progress and get summaries.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// workaround for https://github.com/spf13/viper/issues/233
+		viper.BindPFlag("listen", cmd.Flags().Lookup("listen"))
+
+		inv, err := katyusha.NewInventory(viper.GetString("db"))
+		if err != nil {
+			log.Fatalf("Could not create inventory: %v", err)
+		}
+
+		s := katyusha.NewServer(inv)
+		server := &http.Server{
+			Addr:    viper.GetString("listen"),
+			Handler: s,
+		}
+
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+
+		done := make(chan struct{})
+		go func() {
+			<-c
+			log.Print("Received signal and will stop server")
+
+			// Running benchmark is cancelled and its summary is saved before clients following it are disconnected
+			s.Close()
+			server.Shutdown(context.Background())
+			close(done)
+		}()
+
+		log.Printf("API server listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("API server error: %v", err)
+		}
+
+		<-done
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringP("listen", "l", defaultServeAddress, "Address API server listens on")
+
+	viper.BindPFlags(serveCmd.Flags())
+
+	rootCmd.AddCommand(serveCmd)
+}
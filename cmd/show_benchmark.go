@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,6 +18,12 @@ var showBenchmarkCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// workaround for https://github.com/spf13/viper/issues/233
 		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+
+		output := viper.GetString("output")
+		if err := checkOutputFormat(output); err != nil {
+			log.Fatal(err)
+		}
 
 		var bcs []*katyusha.BenchmarkConfiguration
 		var err error
@@ -43,6 +50,15 @@ var showBenchmarkCmd = &cobra.Command{
 			}
 		}
 
+		if output != outputText {
+			err = writeOutput(os.Stdout, output, bcs)
+			if err != nil {
+				log.Fatalf("Can't write benchmarks: %v", err)
+			}
+
+			return
+		}
+
 		fmt.Printf("Found %d benchmarks\n", len(bcs))
 
 		for i, bc := range bcs {
@@ -62,6 +78,7 @@ func init() {
 	showBenchmarkCmd.Flags().StringP("url", "u", "", "Benchmark URL")
 	showBenchmarkCmd.Flags().BoolP("all", "a", true, "Show all benchmarks")
 	showBenchmarkCmd.Flags().BoolP("full", "f", false, "Show full benchmark summary")
+	showBenchmarkCmd.Flags().StringP("output", "o", outputText, "Output format: text, json, yaml or csv")
 
 	viper.BindPFlags(showBenchmarkCmd.Flags())
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,6 +18,12 @@ var showSummaryCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// workaround for https://github.com/spf13/viper/issues/233
 		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+
+		output := viper.GetString("output")
+		if err := checkOutputFormat(output); err != nil {
+			log.Fatal(err)
+		}
 
 		inv, err := katyusha.NewInventory(viper.GetString("db"))
 		if err != nil {
@@ -28,17 +35,59 @@ var showSummaryCmd = &cobra.Command{
 			log.Fatalf("Coould not receive benchmark summary; %v", err)
 		}
 
+		if output != outputText {
+			err = writeSummaries(output, summaries, viper.GetBool("intervals"))
+			if err != nil {
+				log.Fatalf("Can't write summaries: %v", err)
+			}
+
+			return
+		}
+
 		fmt.Printf("Found %d summaries for given benchmark\n", len(summaries))
 
 		for i, sm := range summaries {
 			fmt.Printf("Summary %d\n", i)
 			fmt.Printf("%s\n", sm)
+			if viper.GetBool("intervals") {
+				fmt.Printf("%s\n", sm.IntervalsTable())
+			}
 		}
 	},
 }
 
+// writeSummaries writes summaries in machine-readable format
+// CSV has a record for each summary, its stages and endpoints, or for each interval when intervals is set
+func writeSummaries(output string, summaries []*katyusha.BenchmarkSummary, intervals bool) error {
+	if output != outputCSV {
+		return writeOutput(os.Stdout, output, summaries)
+	}
+
+	if intervals {
+		rows := make([]intervalRow, 0)
+		for _, sm := range summaries {
+			for _, interval := range sm.Intervals {
+				rows = append(rows, intervalRow{SummaryID: sm.ID, Interval: interval})
+			}
+		}
+
+		return writeOutput(os.Stdout, output, rows)
+	}
+
+	rows := make([]*katyusha.BenchmarkSummary, 0)
+	for _, sm := range summaries {
+		for _, summary := range summaryRows(&sm.Summary) {
+			rows = append(rows, &katyusha.BenchmarkSummary{ID: sm.ID, Summary: *summary})
+		}
+	}
+
+	return writeOutput(os.Stdout, output, rows)
+}
+
 func init() {
 	showSummaryCmd.Flags().Int64P("id", "i", 0, "Benchmark ID")
+	showSummaryCmd.Flags().BoolP("intervals", "t", false, "Show summary time series")
+	showSummaryCmd.Flags().StringP("output", "o", outputText, "Output format: text, json, yaml or csv")
 
 	showSummaryCmd.MarkFlagRequired("id")
 	viper.BindPFlags(showSummaryCmd.Flags())
@@ -0,0 +1,180 @@
+package katyusha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// agentRun is sent by the coordinator to start agent part of distributed benchmark
+type agentRun struct {
+	ID               string              `json:"id"`
+	Parameters       BenchmarkParameters `json:"parameters"`
+	Delay            time.Duration       `json:"delay"` // Agent starts the benchmark after the delay so all agents start together
+	ProgressInterval time.Duration       `json:"progress_interval"`
+}
+
+// agentMessage is one line of the agent response.
+// Agent sends progress of the benchmark while it is running and its results or error at the end.
+type agentMessage struct {
+	Progress *Progress        `json:"progress,omitempty"`
+	Results  *resultsSnapshot `json:"results,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// Agent runs parts of distributed benchmarks started by the coordinator with StartDistributed.
+// It runs one benchmark at a time.
+//
+//	POST /run        starts benchmark and streams JSON lines with progress and the results
+//	POST /stop?id=   stops the running benchmark which sends results collected so far
+type Agent struct {
+	mu    sync.Mutex
+	runID string
+	stop  context.CancelFunc
+}
+
+func NewAgent() *Agent {
+	return &Agent{}
+}
+
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAgentError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+
+	switch r.URL.Path {
+	case "/run":
+		a.run(w, r)
+	case "/stop":
+		a.stopRun(w, r)
+	default:
+		writeAgentError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s", r.URL.Path))
+	}
+}
+
+// run runs the benchmark, it is stopped when the coordinator closes the connection
+func (a *Agent) run(w http.ResponseWriter, r *http.Request) {
+	var run agentRun
+	if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+		writeAgentError(w, http.StatusBadRequest, fmt.Errorf("Can't parse benchmark: %w", err))
+		return
+	}
+
+	b, err := NewBenchmark(&run.Parameters)
+	if err != nil {
+		writeAgentError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	if err := a.start(run.ID, cancel); err != nil {
+		writeAgentError(w, http.StatusConflict, err)
+		return
+	}
+	defer a.finish()
+
+	// Progress is dropped when the previous one was not sent yet, so the benchmark is not blocked
+	progress := make(chan Progress, 1)
+	b.ProgressInterval = run.ProgressInterval
+	b.OnProgress = func(p Progress) {
+		select {
+		case progress <- p:
+		default:
+		}
+	}
+
+	done := make(chan *results)
+	go func() {
+		timer := time.NewTimer(run.Delay)
+		defer timer.Stop()
+
+		// Benchmark stopped before the start ends without requests
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+
+		done <- b.run(ctx, true)
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for {
+		var msg agentMessage
+		var res *results
+
+		select {
+		case p := <-progress:
+			msg.Progress = &p
+		case res = <-done:
+			msg.Results = res.snapshot()
+		}
+
+		if err := enc.Encode(msg); err != nil {
+			// Coordinator is gone, benchmark is stopped by the request context
+			if res == nil {
+				<-done
+			}
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if res != nil {
+			return
+		}
+	}
+}
+
+// start registers the running benchmark, it fails when another one is running
+func (a *Agent) start(id string, stop context.CancelFunc) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stop != nil {
+		return fmt.Errorf("Agent is running benchmark %s", a.runID)
+	}
+
+	a.runID, a.stop = id, stop
+	return nil
+}
+
+func (a *Agent) finish() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.runID, a.stop = "", nil
+}
+
+// stopRun stops the running benchmark with the ID
+func (a *Agent) stopRun(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := r.URL.Query().Get("id")
+	if a.stop == nil || id != a.runID {
+		writeAgentError(w, http.StatusNotFound, fmt.Errorf("Benchmark %s is not running", id))
+		return
+	}
+
+	a.stop()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAgentError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(agentMessage{Error: err.Error()})
+}
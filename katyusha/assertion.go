@@ -0,0 +1,238 @@
+package katyusha
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Assertion is a condition which every response has to meet to be successful.
+// Exactly one of Status, Contains, Regex, JSON, Header and size range has to be set.
+// Failed assertion is reported by its name in Summary errors.
+type Assertion struct {
+	Name string `mapstructure:"name" json:"name,omitempty"` // Name used in errors, by default it describes the condition
+
+	Status   []string `mapstructure:"status" json:"status,omitempty"`     // Accepted status codes, ranges or classes e.g. 201, 200-299 or 2xx
+	Contains string   `mapstructure:"contains" json:"contains,omitempty"` // Text which body has to contain
+	Regex    string   `mapstructure:"regex" json:"regex,omitempty"`       // Regular expression which body has to match
+	JSON     string   `mapstructure:"json" json:"json,omitempty"`         // Path of JSON field which has to exist even as null, with Equals it has to have the value
+	Equals   *string  `mapstructure:"equals" json:"equals,omitempty"`     // Value of JSON field, it can be empty string and null field equals to null
+	Header   string   `mapstructure:"header" json:"header,omitempty"`     // Header which has to be present, its value can be empty
+	MinSize  int      `mapstructure:"min_size" json:"min_size,omitempty"` // Body size range in bytes, 0 is not checked
+	MaxSize  int      `mapstructure:"max_size" json:"max_size,omitempty"`
+}
+
+func (a Assertion) String() string {
+	if a.Name != "" {
+		return a.Name
+	}
+
+	switch {
+	case len(a.Status) > 0:
+		return fmt.Sprintf("status %s", strings.Join(a.Status, ","))
+	case a.Contains != "":
+		return fmt.Sprintf("body contains %q", a.Contains)
+	case a.Regex != "":
+		return fmt.Sprintf("body matches %s", a.Regex)
+	case a.JSON != "" && a.Equals != nil:
+		return fmt.Sprintf("json %s == %s", a.JSON, *a.Equals)
+	case a.JSON != "":
+		return fmt.Sprintf("json %s exists", a.JSON)
+	case a.Header != "":
+		return fmt.Sprintf("header %s", a.Header)
+	case a.MaxSize == 0:
+		return fmt.Sprintf("body size >= %d", a.MinSize)
+	case a.MinSize == 0:
+		return fmt.Sprintf("body size <= %d", a.MaxSize)
+	}
+
+	return fmt.Sprintf("body size %d-%d", a.MinSize, a.MaxSize)
+}
+
+// statusRange is a range of status codes
+type statusRange struct {
+	min int
+	max int
+}
+
+// parseStatusRange parses status code like 201, range like 200-299 or class like 2xx
+func parseStatusRange(s string) (statusRange, error) {
+	s = strings.TrimSpace(s)
+
+	if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") && s[0] >= '1' && s[0] <= '5' {
+		min := int(s[0]-'0') * 100
+		return statusRange{min: min, max: min + 99}, nil
+	}
+
+	bounds := strings.SplitN(s, "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return statusRange{}, fmt.Errorf("Invalid status code %s", s)
+	}
+
+	max := min
+	if len(bounds) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return statusRange{}, fmt.Errorf("Invalid status code %s", s)
+		}
+	}
+
+	if min < 100 || max > 599 || min > max {
+		return statusRange{}, fmt.Errorf("Invalid status code %s", s)
+	}
+
+	return statusRange{min: min, max: max}, nil
+}
+
+// assertion is compiled Assertion
+type assertion struct {
+	err error // Error reported when assertion fails
+
+	status   []statusRange
+	contains []byte
+	regex    *regexp.Regexp
+	jsonPath []string
+	equals   *string // Nil when only existence of JSON field is checked
+	header   string
+	minSize  int
+	maxSize  int
+}
+
+func newAssertion(a Assertion) (*assertion, error) {
+	var set int
+	for _, isSet := range []bool{len(a.Status) > 0, a.Contains != "", a.Regex != "", a.JSON != "", a.Header != "",
+		a.MinSize != 0 || a.MaxSize != 0} {
+		if isSet {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("Assertion %s needs exactly one of status, contains, regex, json, header or size range", a)
+	}
+
+	if a.Equals != nil && a.JSON == "" {
+		return nil, fmt.Errorf("Assertion %s has equals without json", a)
+	}
+
+	if a.MinSize < 0 || a.MaxSize < 0 || (a.MaxSize != 0 && a.MinSize > a.MaxSize) {
+		return nil, fmt.Errorf("Assertion %s has invalid size range", a)
+	}
+
+	as := &assertion{
+		err:      errors.New("Assertion failed: " + a.String()),
+		contains: []byte(a.Contains),
+		equals:   a.Equals,
+		header:   a.Header,
+		minSize:  a.MinSize,
+		maxSize:  a.MaxSize,
+	}
+
+	for _, s := range a.Status {
+		r, err := parseStatusRange(s)
+		if err != nil {
+			return nil, fmt.Errorf("Assertion %s: %w", a, err)
+		}
+
+		as.status = append(as.status, r)
+	}
+
+	if a.Regex != "" {
+		r, err := regexp.Compile(a.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("Can't compile regex of assertion %s: %w", a, err)
+		}
+
+		as.regex = r
+	}
+
+	if a.JSON != "" {
+		as.jsonPath = strings.Split(a.JSON, ".")
+	}
+
+	return as, nil
+}
+
+// check returns true when response meets the assertion.
+// JSON document is decoded by the first JSON assertion and reused by the next ones.
+func (a *assertion) check(resp *fasthttp.Response, doc *interface{}, decoded *bool) bool {
+	body := resp.Body()
+
+	switch {
+	case a.status != nil:
+		return matchStatus(a.status, resp.StatusCode())
+	case len(a.contains) > 0:
+		return bytes.Contains(body, a.contains)
+	case a.regex != nil:
+		return a.regex.Match(body)
+	case a.jsonPath != nil:
+		if !*decoded {
+			*decoded = true
+			if err := decodeJSON(body, doc); err != nil {
+				*doc = nil
+			}
+		}
+
+		value, ok := jsonLookup(*doc, a.jsonPath)
+		if !ok || a.equals == nil {
+			return ok
+		}
+
+		if value == nil {
+			return *a.equals == "null"
+		}
+
+		return jsonString(value) == *a.equals
+	case a.header != "":
+		return hasHeader(&resp.Header, a.header)
+	}
+
+	return len(body) >= a.minSize && (a.maxSize == 0 || len(body) <= a.maxSize)
+}
+
+// hasHeader returns true when response has the header, its value can be empty
+func hasHeader(h *fasthttp.ResponseHeader, name string) bool {
+	if len(h.Peek(name)) > 0 {
+		return true
+	}
+
+	var found bool
+	h.VisitAll(func(key, value []byte) {
+		if bytes.EqualFold(key, []byte(name)) {
+			found = true
+		}
+	})
+
+	return found
+}
+
+// matchStatus returns true when code is in one of ranges
+func matchStatus(ranges []statusRange, code int) bool {
+	for _, r := range ranges {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAssertions returns error of the first failed assertion
+func checkAssertions(assertions []*assertion, resp *fasthttp.Response) error {
+	var doc interface{}
+	var decoded bool
+
+	for _, a := range assertions {
+		if !a.check(resp, &doc, &decoded) {
+			return a.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package katyusha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// stringPtr returns pointer to s for optional options like Equals
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestAssertions(t *testing.T) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	resp.SetStatusCode(fasthttp.StatusCreated)
+	resp.Header.Set("X-Request-Id", "42")
+	resp.Header.Set("X-Empty", "")
+	resp.SetBodyString(`{"error": false, "data": {"items": [{"id": 7}], "order": 9007199254740993, "deleted_at": null, "note": ""}}`)
+
+	tt := []struct {
+		assertion Assertion
+		pass      bool
+	}{
+		{Assertion{Status: []string{"201"}}, true},
+		{Assertion{Status: []string{"200", "202-204"}}, false},
+		{Assertion{Status: []string{"200-299"}}, true},
+		{Assertion{Status: []string{"2xx"}}, true},
+		{Assertion{Status: []string{"3xx", "4XX"}}, false},
+		{Assertion{Contains: `"error": false`}, true},
+		{Assertion{Contains: "exception"}, false},
+		{Assertion{Regex: `"id":\s*\d+`}, true},
+		{Assertion{Regex: `^\[`}, false},
+		{Assertion{JSON: "error", Equals: stringPtr("false")}, true},
+		{Assertion{JSON: "data.items.0.id", Equals: stringPtr("7")}, true},
+		{Assertion{JSON: "data.items.0.id", Equals: stringPtr("8")}, false},
+		{Assertion{JSON: "data.order", Equals: stringPtr("9007199254740993")}, true},
+		{Assertion{JSON: "data.order", Equals: stringPtr("9007199254740992")}, false},
+		{Assertion{JSON: "data.items"}, true},
+		{Assertion{JSON: "data.total"}, false},
+		{Assertion{JSON: "data.deleted_at"}, true},
+		{Assertion{JSON: "data.deleted_at", Equals: stringPtr("null")}, true},
+		{Assertion{JSON: "data.deleted_at", Equals: stringPtr("")}, false},
+		{Assertion{JSON: "data.note", Equals: stringPtr("")}, true},
+		{Assertion{JSON: "data.note", Equals: stringPtr("null")}, false},
+		{Assertion{JSON: "error", Equals: stringPtr("")}, false},
+		{Assertion{JSON: "data.total", Equals: stringPtr("")}, false},
+		{Assertion{Header: "X-Request-Id"}, true},
+		{Assertion{Header: "x-request-id"}, true},
+		{Assertion{Header: "X-Empty"}, true},
+		{Assertion{Header: "X-Missing"}, false},
+		{Assertion{MinSize: 10}, true},
+		{Assertion{MinSize: 10, MaxSize: 20}, false},
+		{Assertion{MaxSize: 1000}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.assertion.String(), func(t *testing.T) {
+			a, err := newAssertion(tc.assertion)
+			if err != nil {
+				t.Fatalf("Can't create assertion: %v", err)
+			}
+
+			err = checkAssertions([]*assertion{a}, resp)
+			if tc.pass && err != nil {
+				t.Errorf("Assertion should pass: %v", err)
+			} else if !tc.pass && err == nil {
+				t.Errorf("Assertion should fail")
+			}
+		})
+	}
+}
+
+func TestAssertionsRequests(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"error": false}`)
+		case "/error":
+			fmt.Fprintf(w, `{"error": true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	tt := []struct {
+		name       string
+		path       string
+		assertions []Assertion
+		success    int
+		errors     map[string]int
+	}{
+		{
+			name:    "Created without assertions",
+			path:    "/created",
+			success: 0,
+			errors:  map[string]int{"Created": 10},
+		},
+		{
+			name:       "Created with status assertion",
+			path:       "/created",
+			assertions: []Assertion{{Status: []string{"200-204"}}, {JSON: "error", Equals: stringPtr("false")}},
+			success:    10,
+			errors:     map[string]int{},
+		},
+		{
+			name:       "Error body",
+			path:       "/error",
+			assertions: []Assertion{{Name: "no error", JSON: "error", Equals: stringPtr("false")}},
+			success:    0,
+			errors:     map[string]int{"Assertion failed: no error": 10},
+		},
+		{
+			name:       "Not found with status assertion",
+			path:       "/missing",
+			assertions: []Assertion{{Status: []string{"200-204"}}, {Contains: "error"}},
+			success:    0,
+			errors:     map[string]int{"Assertion failed: status 200-204": 10},
+		},
+		{
+			name:       "Not found without status assertion",
+			path:       "/missing",
+			assertions: []Assertion{{Contains: "error"}},
+			success:    0,
+			errors:     map[string]int{"Not Found": 10},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &BenchmarkParameters{
+				URL:             server.URL + tc.path,
+				ConcurrentConns: 1,
+				ReqCount:        10,
+				Assertions:      tc.assertions,
+			}
+
+			benchmark, err := NewBenchmark(req)
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != tc.success {
+				t.Errorf("Expected %d successful requests got %d", tc.success, summary.SuccessReq)
+			}
+
+			if fmt.Sprint(summary.Errors) != fmt.Sprint(tc.errors) {
+				t.Errorf("Expected errors %v got %v", tc.errors, summary.Errors)
+			}
+		})
+	}
+}
+
+func TestInvalidAssertions(t *testing.T) {
+	tt := []Assertion{
+		{},
+		{Status: []string{"200"}, Contains: "ok"},
+		{Status: []string{"ok"}},
+		{Status: []string{"299-200"}},
+		{Status: []string{"700"}},
+		{Status: []string{"6xx"}},
+		{Regex: "("},
+		{Equals: stringPtr("1")},
+		{MinSize: 100, MaxSize: 10},
+		{MinSize: -1},
+	}
+
+	for _, a := range tt {
+		t.Run(a.String(), func(t *testing.T) {
+			req := &BenchmarkParameters{
+				URL:        "http://katyusha.test",
+				ReqCount:   1,
+				Assertions: []Assertion{a},
+			}
+
+			if _, err := NewBenchmark(req); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
@@ -4,19 +4,24 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"regexp"
-	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/bytefmt"
 	"github.com/valyala/fasthttp"
 )
 
+// errBenchmarkIsOver closes streams which are still open when Duration or Stages of the benchmark are over
+var errBenchmarkIsOver = errors.New("Benchmark is over")
+
 // KatyushaName is set in fasthttp.Client
 // I don't have better place for this variable now
 const (
@@ -30,55 +35,104 @@ type RequestStat struct {
 	End      time.Time
 	Duration time.Duration
 
+	// Intended is the time at which the request should have been sent.
+	// Latency is measured from Intended to End so it includes time spent waiting for a worker.
+	Intended time.Time
+	Latency  time.Duration
+
+	Stage    int // Index of the stage in which the request was sent
+	Endpoint int // Index of the scenario endpoint
+
 	BodySize int
 
 	RetCode int
 	Error   error
+
+	// Success is true when the response has expected status code and meets all assertions
+	Success bool
+
+	// Phases of the request, DNS, Connect and TLS are set only when the request opened a new connection.
+	// TTFB is measured from sending the request, Transfer from the first byte to the end of the response.
+	NewConnection bool
+	DNS           time.Duration
+	Connect       time.Duration
+	TLS           time.Duration
+	TTFB          time.Duration
+	Transfer      time.Duration
+
+	// WebSocket message which opened the connection has its Setup time including the handshake.
+	// Dropped message got no reply and its connection was closed.
+	Setup   time.Duration
+	Dropped bool
+
+	// Streamed response has its Lifetime when it was opened with expected status code.
+	// FirstEvent is measured from sending the request, EventGaps are times between consecutive events.
+	Lifetime   time.Duration
+	Events     int
+	FirstEvent time.Duration
+	EventGaps  []time.Duration
 }
 
-type ReqTimes []time.Duration
+// Summary struct provides benchmark end results.
+// Durations are serialized to JSON in nanoseconds.
+type Summary struct {
+	URL string `json:"url"`
 
-func (r ReqTimes) Len() int           { return len(r) }
-func (r ReqTimes) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
-func (r ReqTimes) Less(i, j int) bool { return r[i] < r[j] }
+	Stage  int        `json:"stage"`            // Stage number starting from 1, 0 for the whole benchmark
+	Stages []*Summary `json:"stages,omitempty"` // Results of each stage
 
-// a ReqTimes needs to be sorted
-func percentile(a ReqTimes, q float64) time.Duration {
-	n := (q / 100) * float64(len(a))
-	p := int(math.Ceil(n))
+	Endpoint  string     `json:"endpoint"`            // Scenario endpoint name, empty for the whole benchmark
+	Endpoints []*Summary `json:"endpoints,omitempty"` // Results of each scenario endpoint
 
-	return a[p-1]
-}
+	Intervals []Interval `json:"intervals,omitempty"` // Results of each ReportInterval of the benchmark
 
-// Summary struct provides benchmark end results.
-type Summary struct {
-	URL string
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	TotalTime time.Duration `json:"duration"`
 
-	Start     time.Time
-	End       time.Time
-	TotalTime time.Duration
+	ReqCount       int `json:"requests_count"`
+	SuccessReq     int `json:"success_req"` // Requests with expected status code which met all assertions
+	FailReq        int `json:"fail_req"`    // Requests with errors, unexpected status code or failed assertion
+	DataTransfered int `json:"data_transfered"`
 
-	ReqCount       int
-	SuccessReq     int // Requests with return code 200
-	FailReq        int // Requests with return code != 200
-	DataTransfered int
+	ScheduledReq int `json:"scheduled_req"` // Requests scheduled by the open-loop scheduler (Rate)
+	LateReq      int `json:"late_req"`      // Scheduled requests which had to wait for a free worker
+	DroppedReq   int `json:"dropped_req"`   // Scheduled requests dropped because all workers were busy
 
-	ReqPerSec float64 // Request per second
+	ReqPerSec float64 `json:"req_per_sec"` // Request per second
 
-	AvgReqTime time.Duration // Average request time
-	MinReqTime time.Duration // Min request time
-	MaxReqTime time.Duration // Max request time
+	AvgReqTime time.Duration `json:"avg_req_time"` // Average request time
+	MinReqTime time.Duration `json:"min_req_time"` // Min request time
+	MaxReqTime time.Duration `json:"max_req_time"` // Max request time
 
-	P50ReqTime time.Duration // 50th percentile
-	P75ReqTime time.Duration // 75th percentile
-	P90ReqTime time.Duration // 90th percentile
-	P99ReqTime time.Duration // 99th percentile
+	P50ReqTime time.Duration `json:"p50_req_time"` // 50th percentile
+	P75ReqTime time.Duration `json:"p75_req_time"` // 75th percentile
+	P90ReqTime time.Duration `json:"p90_req_time"` // 90th percentile
+	P99ReqTime time.Duration `json:"p99_req_time"` // 99th percentile
 
-	StdDeviation float64 // Standard deviation
+	// Latency is measured from the intended send time and it is corrected for coordinated omission.
+	// With Rate the intended time comes from the schedule, with RequestDelay the missing
+	// requests are accounted for when request time exceeds the delay.
+	AvgLatency time.Duration `json:"avg_latency"` // Average latency
+	MaxLatency time.Duration `json:"max_latency"` // Max latency
+	P50Latency time.Duration `json:"p50_latency"` // 50th percentile
+	P75Latency time.Duration `json:"p75_latency"` // 75th percentile
+	P90Latency time.Duration `json:"p90_latency"` // 90th percentile
+	P99Latency time.Duration `json:"p99_latency"` // 99th percentile
 
-	Errors map[string]int // Errors map. Key is the HTTP response code.
+	StdDeviation float64 `json:"std_deviation"` // Standard deviation of request time in nanoseconds
 
-	requestsTimes ReqTimes
+	Errors map[string]int `json:"errors"` // Errors map. Key is the HTTP response code.
+
+	StatusCodes map[int]int `json:"status_codes"` // Number of responses with each status code
+
+	Phases Phases `json:"phases"` // Timings of request phases
+
+	Streams *StreamStat `json:"streams,omitempty"` // HTTP/2 streams multiplexed over connections
+
+	WebSocket *WebSocketStat `json:"websocket,omitempty"` // Connections of WebSocket benchmark
+
+	Streaming *StreamingStat `json:"streaming,omitempty"` // Events of streamed responses
 }
 
 func (s Summary) String() string {
@@ -99,9 +153,134 @@ func (s Summary) String() string {
   P75 Request time:			%v
   P90 Request time:			%v
   P99 Request time:			%v
+  Standard deviation:			%v
+  Average Latency:			%v
+  Max Latency:				%v
+  P50 Latency:				%v
+  P75 Latency:				%v
+  P90 Latency:				%v
+  P99 Latency:				%v
+  Status codes:				%v
+  Errors:				%v
+`, s.URL, s.Start, s.End, s.TotalTime, s.ReqCount, s.ReqPerSec, s.SuccessReq, s.FailReq, bytefmt.ByteSize(uint64(s.DataTransfered)),
+		s.AvgReqTime, s.MinReqTime, s.MaxReqTime, s.P50ReqTime, s.P75ReqTime, s.P90ReqTime, s.P99ReqTime, time.Duration(s.StdDeviation),
+		s.AvgLatency, s.MaxLatency, s.P50Latency, s.P75Latency, s.P90Latency, s.P99Latency, s.StatusCodes, s.Errors) + s.phasesString() + s.streamsString() + s.webSocketString() + s.streamingString() + s.scheduleString() + s.stagesString() + s.endpointsString()
+}
+
+// stagesString returns short results of each stage
+func (s Summary) stagesString() string {
+	var sb strings.Builder
+	for _, stage := range s.Stages {
+		fmt.Fprintf(&sb, `Stage %d:
+  Start:				%v
+  End:					%v
+  Test Duration:			%v
+  Total Requests:			%d
+  Requests per Second:			%.2f
+  Successful requests:			%d
+  Failed requests:			%d
+  Average Request time:			%v
+  P50 Request time:			%v
+  P90 Request time:			%v
+  P99 Request time:			%v
+  P99 Latency:				%v
+  Errors:				%v
+`, stage.Stage, stage.Start, stage.End, stage.TotalTime, stage.ReqCount, stage.ReqPerSec, stage.SuccessReq, stage.FailReq,
+			stage.AvgReqTime, stage.P50ReqTime, stage.P90ReqTime, stage.P99ReqTime, stage.P99Latency, stage.Errors)
+	}
+
+	return sb.String()
+}
+
+// endpointsString returns short results of each scenario endpoint
+func (s Summary) endpointsString() string {
+	var sb strings.Builder
+	for _, e := range s.Endpoints {
+		fmt.Fprintf(&sb, `Endpoint %s:
+  URL:					%s
+  Total Requests:			%d
+  Requests per Second:			%.2f
+  Successful requests:			%d
+  Failed requests:			%d
+  Data transfered:			%s
+  Average Request time:			%v
+  P50 Request time:			%v
+  P90 Request time:			%v
+  P99 Request time:			%v
+  P99 Latency:				%v
+  Status codes:				%v
   Errors:				%v
-	`, s.URL, s.Start, s.End, s.TotalTime, s.ReqCount, s.ReqPerSec, s.SuccessReq, s.FailReq, bytefmt.ByteSize(uint64(s.DataTransfered)),
-		s.AvgReqTime, s.MinReqTime, s.MaxReqTime, s.P50ReqTime, s.P75ReqTime, s.P90ReqTime, s.P99ReqTime, s.Errors)
+`, e.Endpoint, e.URL, e.ReqCount, e.ReqPerSec, e.SuccessReq, e.FailReq, bytefmt.ByteSize(uint64(e.DataTransfered)),
+			e.AvgReqTime, e.P50ReqTime, e.P90ReqTime, e.P99ReqTime, e.P99Latency, e.StatusCodes, e.Errors)
+	}
+
+	return sb.String()
+}
+
+// phasesString returns timings of request phases, empty when no phase was measured
+func (s Summary) phasesString() string {
+	phases := s.Phases.String()
+	if phases == "" {
+		return ""
+	}
+
+	return "Phases:\n" + phases
+}
+
+// streamsString returns HTTP/2 streams, empty for HTTP/1.1 benchmarks
+func (s Summary) streamsString() string {
+	if s.Streams == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`Streams:
+  Connections:				%d
+  Streams:				%d
+  Streams per connection:		%.2f
+  Max concurrent streams:		%d
+`, s.Streams.Connections, s.Streams.Streams, s.Streams.AvgStreams, s.Streams.MaxConcurrent)
+}
+
+// webSocketString returns WebSocket connections, empty for HTTP benchmarks
+func (s Summary) webSocketString() string {
+	if s.WebSocket == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`WebSocket:
+  Connections:				%d
+  Connection setup:			%v
+  Dropped messages:			%d
+`, s.WebSocket.Connections, s.WebSocket.Setup, s.WebSocket.Dropped)
+}
+
+// streamingString returns events of streamed responses, empty when responses are not streamed
+func (s Summary) streamingString() string {
+	if s.Streaming == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`Streaming:
+  Streams:				%d
+  Events:				%d
+  Events per second per connection:	%.2f
+  Time to first event:			%v
+  Gap between events:			%v
+  Stream lifetime:			%v
+`, s.Streaming.Streams, s.Streaming.Events, s.Streaming.EventsPerSec, s.Streaming.FirstEvent, s.Streaming.EventGap, s.Streaming.Lifetime)
+}
+
+// scheduleString returns open-loop scheduler results, empty for closed-loop benchmarks
+func (s Summary) scheduleString() string {
+	if s.ScheduledReq == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(`Schedule:
+  Scheduled requests:			%d
+  Late requests:			%d
+  Dropped requests:			%d
+`, s.ScheduledReq, s.LateReq, s.DroppedReq)
 }
 
 type headers map[string]string
@@ -150,29 +329,67 @@ func (p *parameters) Set(value string) error {
 
 // BenchmarkParameters is used to configure HTTP requests
 type BenchmarkParameters struct {
-	URL    string
-	Method string
+	URL    string `json:"url"`
+	Method string `json:"method"`
+
+	ReqCount        int `json:"requests_count"`
+	AbortAfter      int `json:"abort_after"`
+	ConcurrentConns int `json:"concurrent_conns"`
 
-	ReqCount        int
-	AbortAfter      int
-	ConcurrentConns int
+	// Rate is the number of requests per second scheduled on a fixed timeline.
+	// When it is set requests are sent independently of response times (open-loop).
+	Rate int `json:"rate"`
 
 	// TLS settings
-	SkipVerify bool
-	CA         string
-	Cert       string
-	Key        string
+	SkipVerify bool   `json:"skip_verify"`
+	CA         string `json:"ca"`
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+
+	Duration       time.Duration `json:"duration"`
+	ReportInterval time.Duration `json:"report_interval"` // Length of Summary intervals, DefaultReportInterval when not set
+	KeepAlive      time.Duration `json:"keep_alive"`
+	RequestDelay   time.Duration `json:"request_delay"`
+	ReadTimeout    time.Duration `json:"read_timeout"`
+	WriteTimeout   time.Duration `json:"write_timeout"`
+
+	Headers    headers    `json:"headers"`
+	Parameters parameters `json:"parameters"`
+
+	Body []byte `json:"body"`
+
+	// Stages describe multi-stage load profile.
+	// When stages are set Duration and ReqCount are not used.
+	Stages []Stage `json:"stages,omitempty"`
+
+	// Scenario is a list of weighted request templates.
+	// When it is set each request is sent to an endpoint picked according to the weights.
+	Scenario []Endpoint `json:"scenario,omitempty"`
+
+	// Thresholds are conditions checked against the Summary, for example "p99 < 250ms"
+	Thresholds []string `json:"thresholds,omitempty"`
 
-	Duration     time.Duration
-	KeepAlive    time.Duration
-	RequestDelay time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	// Protocol is http1 (default), http2 or http3
+	Protocol string `json:"protocol"`
 
-	Headers    headers
-	Parameters parameters
+	// Feeder reads variables for request templates from CSV or JSONL file
+	Feeder *Feeder `json:"feeder,omitempty"`
 
-	Body []byte
+	// ExpectedStatus are status codes, ranges or classes of successful responses e.g. 201, 200-299 or 2xx.
+	// When it is not set only 200 is expected, or any status code when status is checked by assertions.
+	ExpectedStatus []string `json:"expected_status,omitempty"`
+
+	// Assertions are checked for every response.
+	Assertions []Assertion `json:"assertions,omitempty"`
+
+	// WebSocket sends messages over WebSocket connections instead of HTTP requests, URL has to be ws or wss
+	WebSocket *WebSocket `json:"websocket,omitempty"`
+
+	// GRPC calls gRPC method instead of sending HTTP requests, URL has to be grpc or grpcs
+	GRPC *GRPC `json:"grpc,omitempty"`
+
+	// Streaming reads events of streamed responses like Server-Sent Events instead of the whole body
+	Streaming *Streaming `json:"streaming,omitempty"`
 }
 
 // Benchmark is the main type.
@@ -181,69 +398,305 @@ type BenchmarkParameters struct {
 type Benchmark struct {
 	BenchmarkParameters
 
-	client *fasthttp.Client
+	// OnProgress is called every ProgressInterval while the benchmark is running.
+	// It is called from the goroutine collecting results so it should not block.
+	OnProgress       func(Progress)
+	ProgressInterval time.Duration
+
+	tlsConfig *tls.Config
+	dial      fasthttp.DialFunc // Used instead of TCP dial when set
+	h2        *http2Transport   // Connections shared by workers of the running HTTP/2 benchmark
+	scenario  *scenario
+	feeder    *feeder
+	webSocket *webSocket
+	grpc      *grpcMethod
+	streaming *streaming
+
+	assertions     []*assertion
+	expectedStatus []statusRange
+}
+
+// scheduleStat counts requests handled by the open-loop scheduler.
+// It is updated atomically by manageWorkers goroutine.
+type scheduleStat struct {
+	scheduled int64
+	late      int64
+	dropped   int64
+}
+
+// job is sent to a worker to make a request
+type job struct {
+	intended time.Time // time at which the request should be sent
+	stage    int       // index of the stage in Stages
+	row      []string  // feeder row values
+}
+
+// workerPool keeps track of running workers
+type workerPool struct {
+	b        *Benchmark
+	ctx      context.Context
+	streams  context.Context // Context of streamed responses, it is cancelled when the benchmark time is over
+	req      chan job
+	statChan chan *RequestStat
+
+	done []chan struct{}
+	wg   sync.WaitGroup
+}
+
+// resize starts or stops workers so n workers are running.
+// Stopped worker finishes its current request.
+func (p *workerPool) resize(n int) {
+	for len(p.done) < n {
+		p.wg.Add(1)
+		p.done = append(p.done, p.b.worker(p.ctx, p.streams, p.req, p.statChan, &p.wg))
+	}
+
+	for len(p.done) > n {
+		close(p.done[len(p.done)-1])
+		p.done = p.done[:len(p.done)-1]
+	}
+}
+
+// stop stops all workers and waits until they finish
+func (p *workerPool) stop() {
+	p.resize(0)
+	p.wg.Wait()
 }
 
 // manageWorkers runs in a separate goroutine
 // It starts the workers goroutines and sends them signal to make a request via req channel
-func (b *Benchmark) manageWorkers(ctx context.Context) (chan *RequestStat, chan struct{}) {
-	statChan := make(chan *RequestStat, b.ConcurrentConns) // Workers will sends stats through this channel
+// doneChan is closed when all workers finished and sent their stats
+func (b *Benchmark) manageWorkers(ctx context.Context, sched *scheduleStat) (chan *RequestStat, chan struct{}) {
+	statChan := make(chan *RequestStat, b.maxConns()) // Workers will sends stats through this channel
 	doneChan := make(chan struct{})
 
 	go func() {
-		doneChannels := make([]chan struct{}, b.ConcurrentConns)
-		req := make(chan struct{})
+		streams, closeStreams := context.WithCancelCause(ctx)
+		defer closeStreams(nil)
+
+		pool := &workerPool{
+			b:        b,
+			ctx:      ctx,
+			streams:  streams,
+			req:      make(chan job),
+			statChan: statChan,
+		}
 
-		for i := 0; i < b.ConcurrentConns; i++ {
-			doneChannels[i] = b.worker(req, statChan)
+		if b.Rate != 0 {
+			b.scheduleRequests(ctx, pool, sched)
+		} else {
+			b.dispatchRequests(ctx, pool)
 		}
 
-		if b.Duration != time.Duration(0) {
-			breakAfter := time.After(b.Duration)
-		MAIN1:
-			for {
-				select {
-				case <-ctx.Done():
-					break MAIN1
-				case <-breakAfter:
-					break MAIN1
-				default:
-					req <- struct{}{}
-				}
+		// Requests of ReqCount benchmark are finished but streams which never end would keep workers busy after the time is over
+		if b.Duration != time.Duration(0) || len(b.Stages) > 0 {
+			closeStreams(errBenchmarkIsOver)
+		}
+
+		pool.stop()
+		close(doneChan)
+	}()
+
+	return statChan, doneChan
+}
+
+// dispatchRequests sends signals on req channel as fast as workers take them (closed-loop).
+// It ends after ReqCount requests or when Duration or Stages are over.
+func (b *Benchmark) dispatchRequests(ctx context.Context, pool *workerPool) {
+	// Load is checked even when all workers are busy, e.g. reading streams which never end
+	var tick <-chan time.Time
+	if len(b.Stages) > 0 || b.Duration != time.Duration(0) {
+		ticker := time.NewTicker(profileTick)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// Feeder row is kept until a worker takes the job
+	var row []string
+
+	start := time.Now()
+	for i := 0; b.Duration != time.Duration(0) || len(b.Stages) > 0 || i < b.ReqCount; {
+		now := time.Now()
+		l, ok := b.loadAt(now.Sub(start))
+		if !ok {
+			return
+		}
+
+		if b.feeder != nil && row == nil {
+			if row, ok = b.feeder.next(); !ok {
+				return
 			}
-		} else {
-		MAIN2:
-			for i := 0; i < b.ReqCount; i++ {
-				select {
-				case <-ctx.Done():
-					break MAIN2
-				default:
-					req <- struct{}{}
+		}
+
+		pool.resize(l.conns)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case pool.req <- job{intended: now, stage: l.stage, row: row}:
+			row = nil
+			i++
+		}
+	}
+}
+
+// scheduleRequests sends signals on req channel on a fixed timeline defined by Rate or Stages.
+// When all workers are busy at the scheduled time the request is late and waits for a free worker.
+// If no worker is freed before the next scheduled request, the request is dropped.
+func (b *Benchmark) scheduleRequests(ctx context.Context, pool *workerPool, sched *scheduleStat) {
+	start := time.Now()
+	intended := start
+	credit := 1.0 // The first request is sent at the start
+
+	for i := 0; b.Duration != time.Duration(0) || len(b.Stages) > 0 || i < b.ReqCount; {
+		l, ok := b.loadAt(intended.Sub(start))
+		if !ok {
+			return
+		}
+
+		pool.resize(l.conns)
+
+		// Move the timeline until the next request is due.
+		// The rate can change in the meantime so we don't move further than profileTick.
+		if credit < 1 {
+			step := profileTick
+			if l.rate > 0 {
+				if due := time.Duration(math.Ceil((1 - credit) / l.rate * float64(time.Second))); due < step {
+					step = due
 				}
 			}
+
+			credit += l.rate * step.Seconds()
+			intended = intended.Add(step)
+			continue
 		}
 
-		for _, done := range doneChannels {
-			done <- struct{}{}
+		credit--
+		i++
+
+		next := intended.Add(profileTick)
+		if l.rate > 0 {
+			next = intended.Add(time.Duration(float64(time.Second) / l.rate))
 		}
 
-		doneChan <- struct{}{}
-	}()
+		if wait := time.Until(intended); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
 
-	return statChan, doneChan
+		j := job{intended: intended, stage: l.stage}
+		if b.feeder != nil {
+			// Dropped request uses its row as well
+			if j.row, ok = b.feeder.next(); !ok {
+				return
+			}
+		}
+
+		atomic.AddInt64(&sched.scheduled, 1)
+
+		select {
+		case <-ctx.Done():
+			return
+		case pool.req <- j:
+			continue
+		default:
+		}
+
+		// All workers are busy
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case pool.req <- j:
+			atomic.AddInt64(&sched.late, 1)
+		case <-timer.C:
+			atomic.AddInt64(&sched.dropped, 1)
+		}
+		timer.Stop()
+	}
 }
 
 // Worker make HTTP request when it gets notification on req channel
-func (b *Benchmark) worker(req chan struct{}, statChan chan *RequestStat) chan struct{} {
+// The notification carries the time at which the request should be sent.
+// Worker returns when done channel is closed.
+// Streamed responses are read until streams context is done.
+func (b *Benchmark) worker(ctx, streams context.Context, req chan job, statChan chan *RequestStat, wg *sync.WaitGroup) chan struct{} {
 	done := make(chan struct{})
 	go func() {
+		defer wg.Done()
+		var send func(e *endpoint, flow *flowState) *RequestStat
+		switch {
+		case b.webSocket != nil:
+			ws := newWSClient(b)
+			defer ws.close()
+			send = ws.send
+		case b.grpc != nil:
+			gc := newGRPCClient(b)
+			defer gc.close()
+			send = func(e *endpoint, flow *flowState) *RequestStat {
+				return gc.call(ctx, streams, e, flow)
+			}
+		case b.streaming != nil:
+			sc := newStreamClient(b)
+			defer sc.closeIdleConnections()
+			send = func(e *endpoint, flow *flowState) *RequestStat {
+				return sc.send(streams, e, flow)
+			}
+		default:
+			client := b.newClient()
+			defer client.closeIdleConnections()
+			send = func(e *endpoint, flow *flowState) *RequestStat {
+				return b.doRequest(client, e, flow)
+			}
+		}
+
+		flow := newFlowState()
 		for {
 			select {
 			case <-done:
 				return
-			case <-req:
-				stat := b.doRequest()
-				statChan <- stat
+			default:
+			}
+
+			select {
+			case <-done:
+				return
+			case j := <-req:
+				flow.reset()
+				if j.row != nil {
+					b.feeder.set(j.row, flow.vars)
+				}
+
+				for n, e := range b.scenario.pick() {
+					stat := send(&b.scenario.endpoints[e], flow)
+					stat.Endpoint = e
+					stat.Stage = j.stage
+
+					// Only the first request of a flow waits for the worker
+					stat.Intended = j.intended
+					if n > 0 {
+						stat.Intended = stat.Start
+					}
+					stat.Latency = stat.End.Sub(stat.Intended)
+
+					select {
+					case statChan <- stat:
+					case <-ctx.Done():
+						return
+					}
+
+					if !stat.Success {
+						break
+					}
+				}
+
 				if b.RequestDelay != time.Duration(0) {
 					time.Sleep(b.RequestDelay)
 				}
@@ -257,116 +710,183 @@ func (b *Benchmark) worker(req chan struct{}, statChan chan *RequestStat) chan s
 // StartBenchmark runs the actual configured benchmark.
 // It returns end results and can be start multiple times.
 func (b *Benchmark) StartBenchmark(ctx context.Context) *Summary {
-	var maxDuration, minDuration, avgDuration time.Duration
-
-	var success, fail int
-	var dataTransfered int
-	var reqPerSecond float64
-
-	errors := make(map[string]int)
+	return b.run(ctx, false).summary()
+}
 
+// run runs the benchmark and returns its results.
+// With keepIntervals histograms of intervals are kept so results can be merged with results of other agents.
+func (b *Benchmark) run(ctx context.Context, keepIntervals bool) *results {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	statChan, doneChan := b.manageWorkers(ctx)
+	if b.Protocol == ProtocolHTTP2 {
+		b.h2 = newHTTP2Transport(b)
+		defer b.h2.closeIdleConnections()
+	}
 
-	requestTimes := make(ReqTimes, 0)
 	start := time.Now()
+	r := newResults(b, start)
+	r.timeline.keep = keepIntervals
+	pr := newProgress(b, start)
+
+	statChan, doneChan := b.manageWorkers(ctx, &r.sched)
+
+	var progressTick <-chan time.Time
+	if b.OnProgress != nil {
+		interval := b.ProgressInterval
+		if interval <= 0 {
+			interval = DefaultProgressInterval
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		progressTick = ticker.C
+	}
+
+	// collect adds stat to the results and returns false when benchmark should be aborted
+	collect := func(stat *RequestStat) bool {
+		r.add(stat)
+		if b.OnProgress != nil {
+			pr.add(stat)
+		}
+
+		return b.AbortAfter == 0 || r.total.fail < b.AbortAfter
+	}
+
 	// We are collecting results in this loop
 MAIN:
 	for {
 		select {
 		case stat := <-statChan:
-			requestTimes = append(requestTimes, stat.Duration)
-
-			if stat.RetCode == 200 && stat.Error == nil {
-				success++
-				dataTransfered += stat.BodySize
-			} else {
-				fail++
-				var errString string
-				if stat.Error != nil {
-					errString = stat.Error.Error()
-				} else {
-					errString = fasthttp.StatusMessage(stat.RetCode)
-				}
-
-				if _, ok := errors[errString]; !ok {
-					errors[errString] = 1
-				} else {
-					errors[errString]++
-				}
-			}
-
-			if fail >= b.AbortAfter && b.AbortAfter != 0 {
+			if !collect(stat) {
 				cancel()
 				break MAIN
 			}
+		case now := <-progressTick:
+			b.OnProgress(pr.report(now, r.total))
 		case <-doneChan:
-			break MAIN
+			// All workers are finished, collect stats which are still in the channel
+			for {
+				select {
+				case stat := <-statChan:
+					collect(stat)
+				default:
+					break MAIN
+				}
+			}
 		case <-ctx.Done():
 			break MAIN
 		}
 	}
 
-	end := time.Now()
-	totalTime := time.Since(start)
+	r.end = time.Now()
+	r.intervals = r.timeline.intervals(r.end)
+
+	if b.h2 != nil {
+		r.streams = b.h2.streams.stat()
+	}
 
-	sort.Sort(requestTimes)
+	return r
+}
 
-	minDuration = requestTimes[0]
-	maxDuration = requestTimes[len(requestTimes)-1]
+// NewBenchmark configure Benchmark and return its.
+// It will setup fasthttp.Client and check benchmark requests parameters
+func NewBenchmark(reqParams *BenchmarkParameters) (*Benchmark, error) {
+	var tlsConfig tls.Config
 
-	for _, reqTime := range requestTimes {
-		avgDuration += reqTime
+	if reqParams.ReportInterval < 0 {
+		return nil, fmt.Errorf("Report interval can't be negative: %v", reqParams.ReportInterval)
 	}
 
-	p50 := percentile(requestTimes, 50)
-	p75 := percentile(requestTimes, 75)
-	p90 := percentile(requestTimes, 90)
-	p99 := percentile(requestTimes, 99)
+	if reqParams.Rate < 0 {
+		return nil, fmt.Errorf("Rate can't be negative: %d", reqParams.Rate)
+	}
 
-	if success != 0 {
-		avgDuration = time.Duration(int64(avgDuration) / int64(success))
-	} else {
-		avgDuration = 0
+	if err := reqParams.validateStages(); err != nil {
+		return nil, err
+	}
+
+	if err := validateProtocol(reqParams.Protocol); err != nil {
+		return nil, err
 	}
 
-	reqCount := success + fail
+	if err := ValidateThresholds(reqParams.Thresholds); err != nil {
+		return nil, err
+	}
 
-	if totalTime > time.Duration(time.Second) {
-		reqPerSecond = float64(success) / float64(totalTime/time.Second)
-	} else {
-		reqPerSecond = float64(success)
-	}
-
-	summary := &Summary{
-		URL:            b.URL,
-		Start:          start,
-		End:            end,
-		TotalTime:      totalTime,
-		DataTransfered: dataTransfered,
-		ReqPerSec:      reqPerSecond,
-		ReqCount:       reqCount,
-		SuccessReq:     success,
-		FailReq:        fail,
-		AvgReqTime:     avgDuration,
-		MinReqTime:     minDuration,
-		MaxReqTime:     maxDuration,
-		P50ReqTime:     p50,
-		P75ReqTime:     p75,
-		P90ReqTime:     p90,
-		P99ReqTime:     p99,
-		Errors:         errors,
-	}
-
-	return summary
-}
+	var fd *feeder
+	var columns []string
+	if reqParams.Feeder != nil {
+		var err error
+		fd, err = newFeeder(reqParams.Feeder)
+		if err != nil {
+			return nil, err
+		}
 
-// NewBenchmark configure Benchmark and return its.
-// It will setup fasthttp.Client and check benchmark requests parameters
-func NewBenchmark(reqParams *BenchmarkParameters) (*Benchmark, error) {
-	var tlsConfig tls.Config
+		columns = fd.columns
+	}
+
+	scenario, err := newScenario(reqParams, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	if reqParams.Protocol == ProtocolHTTP3 {
+		if err := validateHTTP3(scenario); err != nil {
+			return nil, err
+		}
+	}
+
+	var ws *webSocket
+	if reqParams.WebSocket != nil {
+		ws, err = newWebSocket(reqParams, columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var gm *grpcMethod
+	if reqParams.GRPC != nil {
+		gm, err = newGRPCMethod(reqParams, columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var st *streaming
+	if reqParams.Streaming != nil {
+		st, err = newStreaming(reqParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	expectedStatus := []statusRange{{min: fasthttp.StatusOK, max: fasthttp.StatusOK}}
+	var assertions []*assertion
+	for _, a := range reqParams.Assertions {
+		as, err := newAssertion(a)
+		if err != nil {
+			return nil, err
+		}
+
+		assertions = append(assertions, as)
+		if as.status != nil {
+			// Status is checked by the assertion
+			expectedStatus = []statusRange{{min: 100, max: 599}}
+		}
+	}
+
+	if len(reqParams.ExpectedStatus) > 0 {
+		expectedStatus = nil
+		for _, status := range reqParams.ExpectedStatus {
+			r, err := parseStatusRange(status)
+			if err != nil {
+				return nil, fmt.Errorf("Expected status: %w", err)
+			}
+
+			expectedStatus = append(expectedStatus, r)
+		}
+	}
 
 	if reqParams.SkipVerify {
 		tlsConfig.InsecureSkipVerify = reqParams.SkipVerify
@@ -393,35 +913,92 @@ func NewBenchmark(reqParams *BenchmarkParameters) (*Benchmark, error) {
 		}
 	}
 
-	client := &fasthttp.Client{
-		Name:                KatyushaName,
-		MaxConnsPerHost:     reqParams.ConcurrentConns,
-		ReadTimeout:         reqParams.ReadTimeout,
-		WriteTimeout:        reqParams.WriteTimeout,
-		MaxIdleConnDuration: reqParams.KeepAlive,
-		TLSConfig:           &tlsConfig,
-	}
-
 	b := &Benchmark{
 		BenchmarkParameters: *reqParams,
-		client:              client,
+		tlsConfig:           &tlsConfig,
+		scenario:            scenario,
+		feeder:              fd,
+		webSocket:           ws,
+		grpc:                gm,
+		streaming:           st,
+		assertions:          assertions,
+		expectedStatus:      expectedStatus,
 	}
 
 	return b, nil
 }
 
-// doRequest perform the HTTP request based on the paramters in BenchmarkParameters
-func (b *Benchmark) doRequest() *RequestStat {
+// doRequest perform the HTTP request to the endpoint with Parameters from BenchmarkParameters
+// Templates are rendered with flow variables and the variables extracted from the response are added to the flow.
+func (b *Benchmark) doRequest(client client, e *endpoint, flow *flowState) *RequestStat {
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 	args := fasthttp.AcquireArgs()
 
-	req.SetRequestURI(b.URL)
-	req.Header.SetMethod(b.Method)
+	b.prepareRequest(req, args, e, flow)
+
+	start := time.Now()
+	err := client.do(req, resp)
+
+	bodySize := len(resp.Body())
+
+	end := time.Now()
+	duration := time.Since(start)
+
+	// Status code is not known when the request failed
+	var statusCode int
+	if err == nil {
+		statusCode = resp.StatusCode()
+	}
+
+	// Unexpected status codes are reported by the status message
+	success := err == nil && matchStatus(b.expectedStatus, statusCode)
+	if success && len(b.assertions) > 0 {
+		err = checkAssertions(b.assertions, resp)
+		success = err == nil
+	}
+
+	if success && len(e.extract) > 0 {
+		err = extractValues(e.extract, resp, flow.vars)
+		success = err == nil
+	}
+
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseResponse(resp)
+	fasthttp.ReleaseArgs(args)
+
+	stat := &RequestStat{
+		Start:    start,
+		End:      end,
+		Duration: duration,
+		BodySize: bodySize,
+		RetCode:  statusCode,
+		Error:    err,
+		Success:  success,
+	}
+	client.setPhases(stat)
+
+	return stat
+}
+
+// prepareRequest sets URL, method, headers, query args and body of the endpoint request rendered with flow variables
+func (b *Benchmark) prepareRequest(req *fasthttp.Request, args *fasthttp.Args, e *endpoint, flow *flowState) {
+	if e.url.text != "" {
+		req.SetRequestURI(e.url.text)
+	} else {
+		flow.buf = e.url.appendTo(flow.buf[:0], flow.vars)
+		req.SetRequestURIBytes(flow.buf)
+	}
+	req.Header.SetMethod(e.method)
 
 	// Set all Headers into Request
-	for key, value := range b.Headers {
-		req.Header.Add(key, value)
+	for _, h := range e.headers {
+		if h.value.text != "" {
+			req.Header.Add(h.key, h.value.text)
+		} else {
+			flow.buf = h.value.appendTo(flow.buf[:0], flow.vars)
+			req.Header.AddBytesV(h.key, flow.buf)
+		}
 	}
 
 	if len(b.Parameters) > 0 {
@@ -434,38 +1011,17 @@ func (b *Benchmark) doRequest() *RequestStat {
 		}
 	}
 
-	if b.Method == fasthttp.MethodGet {
+	if e.method == fasthttp.MethodGet {
 		reqArgs := req.URI().QueryArgs()
 		args.CopyTo(reqArgs)
-	} else {
+	} else if args.Len() > 0 {
+		req.Header.SetContentType("application/x-www-form-urlencoded")
 		reqArgs := req.PostArgs()
 		args.CopyTo(reqArgs)
 	}
 
-	if len(b.Body) != 0 && (b.Method == fasthttp.MethodPost || b.Method == fasthttp.MethodPut) {
-		req.SetBody(b.Body)
-	}
-
-	start := time.Now()
-	err := b.client.Do(req, resp)
-
-	bodySize := len(resp.Body())
-
-	end := time.Now()
-	duration := time.Since(start)
-
-	statusCode := resp.StatusCode()
-
-	fasthttp.ReleaseRequest(req)
-	fasthttp.ReleaseResponse(resp)
-	fasthttp.ReleaseArgs(args)
-
-	return &RequestStat{
-		Start:    start,
-		End:      end,
-		Duration: duration,
-		BodySize: bodySize,
-		RetCode:  statusCode,
-		Error:    err,
+	if !e.body.empty() && (e.method == fasthttp.MethodPost || e.method == fasthttp.MethodPut) {
+		flow.buf = e.body.appendTo(flow.buf[:0], flow.vars)
+		req.SetBody(flow.buf)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"io/ioutil"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -338,6 +339,379 @@ func TestMultipleRequests(t *testing.T) {
 	}
 }
 
+func TestRateRequests(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 2,
+		ReqCount:        20,
+		Rate:            100,
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.ScheduledReq != 20 {
+		t.Errorf("Scheduled requests should be 20 but it is %d", summary.ScheduledReq)
+	}
+
+	if summary.ReqCount+summary.DroppedReq != 20 {
+		t.Errorf("Sent and dropped requests should be 20 but sent is %d and dropped is %d", summary.ReqCount, summary.DroppedReq)
+	}
+
+	// 20 requests at 100 req/s are scheduled over 190ms
+	if summary.TotalTime < 190*time.Millisecond {
+		t.Errorf("Benchmark should last at least 190ms but it took %v", summary.TotalTime)
+	}
+}
+
+func TestRateDroppedRequests(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 1,
+		ReqCount:        10,
+		Rate:            50,
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.ScheduledReq != 10 {
+		t.Errorf("Scheduled requests should be 10 but it is %d", summary.ScheduledReq)
+	}
+
+	if summary.DroppedReq == 0 {
+		t.Errorf("One worker can't keep up with 50 req/s and requests should be dropped")
+	}
+
+	if summary.ReqCount+summary.DroppedReq != 10 {
+		t.Errorf("Sent and dropped requests should be 10 but sent is %d and dropped is %d", summary.ReqCount, summary.DroppedReq)
+	}
+}
+
+func TestRequestDelayLatency(t *testing.T) {
+	var requests int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// Only the first request stalls
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 1,
+		ReqCount:        20,
+		RequestDelay:    10 * time.Millisecond,
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.P90ReqTime > 100*time.Millisecond {
+		t.Errorf("Only one request stalled and P90 request time should be low but it is %v", summary.P90ReqTime)
+	}
+
+	// The stall should be visible for requests which could not be sent in the meantime
+	if summary.P90Latency < 100*time.Millisecond {
+		t.Errorf("P90 latency should include the stall but it is %v", summary.P90Latency)
+	}
+
+	if summary.MaxLatency < summary.MaxReqTime {
+		t.Errorf("Max latency %v should not be lower than max request time %v", summary.MaxLatency, summary.MaxReqTime)
+	}
+}
+
+func TestStages(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 1,
+		ReportInterval:  100 * time.Millisecond,
+		Stages: []Stage{
+			{Duration: 300 * time.Millisecond, Connections: 4},
+			{Duration: 300 * time.Millisecond, Connections: 4},
+			{Duration: 300 * time.Millisecond, Connections: 1},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if len(summary.Stages) != 3 {
+		t.Fatalf("Summary should have 3 stages but it has %d", len(summary.Stages))
+	}
+
+	var reqCount int
+	for i, stage := range summary.Stages {
+		if stage.Stage != i+1 {
+			t.Errorf("Stage number should be %d but it is %d", i+1, stage.Stage)
+		}
+
+		if stage.ReqCount == 0 {
+			t.Errorf("Stage %d should have requests", stage.Stage)
+		}
+
+		reqCount += stage.ReqCount
+	}
+
+	if reqCount != summary.ReqCount {
+		t.Errorf("Stages requests should sum up to %d but it is %d", summary.ReqCount, reqCount)
+	}
+
+	// The plateau runs with 4 connections and it should handle more requests than the ramp
+	if summary.Stages[1].ReqCount <= summary.Stages[0].ReqCount {
+		t.Errorf("Plateau should have more requests (%d) than ramp up (%d)", summary.Stages[1].ReqCount, summary.Stages[0].ReqCount)
+	}
+
+	if summary.TotalTime < 900*time.Millisecond {
+		t.Errorf("Benchmark should last at least 900ms but it took %v", summary.TotalTime)
+	}
+
+	if len(summary.Intervals) < 9 {
+		t.Errorf("Summary should have at least 9 intervals but it has %d", len(summary.Intervals))
+	}
+
+	reqCount = 0
+	for _, interval := range summary.Intervals {
+		reqCount += interval.ReqCount
+	}
+
+	if reqCount != summary.ReqCount {
+		t.Errorf("Intervals requests should sum up to %d but it is %d", summary.ReqCount, reqCount)
+	}
+}
+
+func TestRateStages(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 2,
+		Rate:            100,
+		Stages: []Stage{
+			{Duration: 500 * time.Millisecond, Connections: 2, Rate: 100},
+			{Duration: 500 * time.Millisecond, Connections: 2, Rate: 0},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	// 50 requests in the first stage and 25 while ramping down to 0
+	if summary.ScheduledReq < 70 || summary.ScheduledReq > 80 {
+		t.Errorf("About 75 requests should be scheduled but it is %d", summary.ScheduledReq)
+	}
+
+	if len(summary.Stages) != 2 {
+		t.Fatalf("Summary should have 2 stages but it has %d", len(summary.Stages))
+	}
+
+	if summary.Stages[0].ReqCount <= summary.Stages[1].ReqCount {
+		t.Errorf("First stage should have more requests (%d) than ramp down (%d)", summary.Stages[0].ReqCount, summary.Stages[1].ReqCount)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 2,
+		Duration:        550 * time.Millisecond,
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	var reports []Progress
+	benchmark.ProgressInterval = 100 * time.Millisecond
+	benchmark.OnProgress = func(p Progress) {
+		reports = append(reports, p)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if len(reports) < 4 {
+		t.Fatalf("There should be at least 4 progress reports but there are %d", len(reports))
+	}
+
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Elapsed <= reports[i-1].Elapsed {
+			t.Errorf("Elapsed time should grow but it is %v after %v", reports[i].Elapsed, reports[i-1].Elapsed)
+		}
+
+		if reports[i].Remaining >= reports[i-1].Remaining {
+			t.Errorf("Remaining time should decrease but it is %v after %v", reports[i].Remaining, reports[i-1].Remaining)
+		}
+
+		if reports[i].ReqCount < reports[i-1].ReqCount {
+			t.Errorf("Requests count should not decrease")
+		}
+	}
+
+	last := reports[len(reports)-1]
+	if last.ReqPerSec == 0 || last.P99ReqTime == 0 {
+		t.Errorf("Progress should report requests per second and P99 but it is %+v", last)
+	}
+
+	if last.ReqCount > summary.ReqCount {
+		t.Errorf("Progress reported more requests (%d) than summary (%d)", last.ReqCount, summary.ReqCount)
+	}
+}
+
+func TestInvalidStages(t *testing.T) {
+	tt := []struct {
+		name   string
+		params BenchmarkParameters
+	}{
+		{"zero duration", BenchmarkParameters{Stages: []Stage{{Connections: 1}}}},
+		{"negative connections", BenchmarkParameters{Stages: []Stage{{Duration: time.Second, Connections: -1}}}},
+		{"rate without Rate", BenchmarkParameters{Stages: []Stage{{Duration: time.Second, Connections: 1, Rate: 10}}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewBenchmark(&tc.params)
+			if err == nil {
+				t.Errorf("Benchmark with invalid stages should not be created")
+			}
+		})
+	}
+}
+
+func TestNegativeRate(t *testing.T) {
+	_, err := NewBenchmark(&BenchmarkParameters{Rate: -1})
+	if err == nil {
+		t.Errorf("Benchmark with negative rate should not be created")
+	}
+}
+
+func TestExpectedStatus(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	tt := []struct {
+		name     string
+		method   string
+		expected []string
+		abort    int
+		success  int
+		codes    map[int]int
+		errors   map[string]int
+	}{
+		{"Default", "POST", nil, 0, 0, map[int]int{201: 10}, map[string]int{"Created": 10}},
+		{"Code", "POST", []string{"201"}, 0, 10, map[int]int{201: 10}, map[string]int{}},
+		{"Class", "POST", []string{"2xx", "3xx"}, 0, 10, map[int]int{201: 10}, map[string]int{}},
+		{"Range", "POST", []string{"202-204"}, 0, 0, map[int]int{201: 10}, map[string]int{"Created": 10}},
+		{"Abort", "POST", []string{"201"}, 1, 10, map[int]int{201: 10}, map[string]int{}},
+		{"Not found", "GET", []string{"2xx"}, 0, 0, map[int]int{404: 10}, map[string]int{"Not Found": 10}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &BenchmarkParameters{
+				URL:             server.URL,
+				Method:          tc.method,
+				ConcurrentConns: 1,
+				ReqCount:        10,
+				AbortAfter:      tc.abort,
+				ExpectedStatus:  tc.expected,
+			}
+
+			benchmark, err := NewBenchmark(req)
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != tc.success {
+				t.Errorf("Expected %d successful requests got %d", tc.success, summary.SuccessReq)
+			}
+
+			if diff := cmp.Diff(tc.codes, summary.StatusCodes); diff != "" {
+				t.Errorf("Status codes mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.errors, summary.Errors); diff != "" {
+				t.Errorf("Errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := NewBenchmark(&BenchmarkParameters{URL: server.URL, ReqCount: 1, ExpectedStatus: []string{"6xx"}}); err == nil {
+		t.Errorf("Benchmark with invalid expected status should not be created")
+	}
+}
+
 func PrepareInmemoryListenerBenchmark(reqCount int, connections int) (*Benchmark, *fasthttp.Server, error) {
 	ln := fasthttputil.NewInmemoryListener()
 	s := &fasthttp.Server{
@@ -350,6 +724,7 @@ func PrepareInmemoryListenerBenchmark(reqCount int, connections int) (*Benchmark
 	go s.Serve(ln)
 
 	req := &BenchmarkParameters{
+		URL:             "http://katyusha.test",
 		ConcurrentConns: connections,
 		ReqCount:        reqCount,
 	}
@@ -359,10 +734,8 @@ func PrepareInmemoryListenerBenchmark(reqCount int, connections int) (*Benchmark
 		return nil, nil, err
 	}
 
-	benchmark.client = &fasthttp.Client{
-		Dial: func(addr string) (net.Conn, error) {
-			return ln.Dial()
-		},
+	benchmark.dial = func(addr string) (net.Conn, error) {
+		return ln.Dial()
 	}
 
 	return benchmark, s, nil
@@ -398,3 +771,25 @@ func BenchmarkKatyusha(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkCollectorMemory shows that memory used to aggregate results does not depend on requests count
+func BenchmarkCollectorMemory(b *testing.B) {
+	for _, reqCount := range []int{1000, 100000, 1000000} {
+		b.Run(fmt.Sprintf("%d requests", reqCount), func(b *testing.B) {
+			benchmark := &Benchmark{}
+			stat := &RequestStat{RetCode: 200, Success: true}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c := newCollector(benchmark)
+				start := time.Now()
+				for j := 0; j < reqCount; j++ {
+					stat.Duration = time.Duration(j%100000) * time.Microsecond
+					stat.Latency = stat.Duration
+					c.add(stat)
+				}
+				c.summary(start, time.Now())
+			}
+		})
+	}
+}
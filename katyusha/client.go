@@ -0,0 +1,139 @@
+package katyusha
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Protocols of the benchmark client
+const (
+	ProtocolHTTP1 = "http1" // HTTP/1.1 with fasthttp client (default)
+	ProtocolHTTP2 = "http2" // HTTP/2 over TLS for https and h2c with prior knowledge for http URLs
+	ProtocolHTTP3 = "http3" // HTTP/3 over QUIC, https URLs only
+)
+
+// client sends requests of one worker.
+// Requests and responses are fasthttp types for every protocol so templates, assertions and extracts work the same.
+type client interface {
+	do(req *fasthttp.Request, resp *fasthttp.Response) error
+
+	// setPhases sets phases of the last request in stat
+	setPhases(stat *RequestStat)
+
+	closeIdleConnections()
+}
+
+// validateProtocol returns error for unknown protocol
+func validateProtocol(protocol string) error {
+	switch protocol {
+	case "", ProtocolHTTP1, ProtocolHTTP2, ProtocolHTTP3:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown protocol %s, use http1, http2 or http3", protocol)
+}
+
+// newClient returns client of one worker for the benchmark protocol
+func (b *Benchmark) newClient() client {
+	switch b.Protocol {
+	case ProtocolHTTP2:
+		return newHTTP2Client(b.h2)
+	case ProtocolHTTP3:
+		return newHTTP3Client(b)
+	}
+
+	return newFasthttpClient(b)
+}
+
+// fasthttpClient is HTTP/1.1 client of one worker.
+// Worker has its own connections so phases of its requests can be measured.
+type fasthttpClient struct {
+	*fasthttp.Client
+	timer *phaseTimer
+}
+
+func newFasthttpClient(b *Benchmark) *fasthttpClient {
+	timer := newPhaseTimer(b.tlsConfig, b.dial, b.WriteTimeout)
+
+	return &fasthttpClient{
+		Client: &fasthttp.Client{
+			Name:                KatyushaName,
+			MaxConnsPerHost:     1,
+			ReadTimeout:         b.ReadTimeout,
+			WriteTimeout:        b.WriteTimeout,
+			MaxIdleConnDuration: b.KeepAlive,
+			TLSConfig:           b.tlsConfig,
+			Dial:                timer.Dial,
+		},
+		timer: timer,
+	}
+}
+
+func (c *fasthttpClient) do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	c.timer.start(time.Now(), string(req.URI().Scheme()) == "https")
+	return c.Do(req, resp)
+}
+
+func (c *fasthttpClient) setPhases(stat *RequestStat) {
+	c.timer.setPhases(stat)
+}
+
+func (c *fasthttpClient) closeIdleConnections() {
+	c.CloseIdleConnections()
+}
+
+// newHTTPRequest converts request to net/http request for clients of HTTP/2 and HTTP/3
+func newHTTPRequest(ctx context.Context, req *fasthttp.Request) (*http.Request, error) {
+	body := req.Body()
+	if len(body) == 0 && req.PostArgs().Len() > 0 {
+		body = req.PostArgs().QueryString()
+	}
+
+	// Body can be sent after the request was released
+	var reqBody io.Reader
+	if len(body) > 0 {
+		reqBody = bytes.NewReader(append([]byte(nil), body...))
+	}
+
+	r, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), string(req.URI().FullURI()), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.VisitAll(func(key, value []byte) {
+		switch string(key) {
+		case fasthttp.HeaderHost, fasthttp.HeaderContentLength, fasthttp.HeaderConnection, fasthttp.HeaderTransferEncoding:
+		default:
+			r.Header.Add(string(key), string(value))
+		}
+	})
+
+	if r.Header.Get(fasthttp.HeaderUserAgent) == "" {
+		r.Header.Set(fasthttp.HeaderUserAgent, KatyushaName)
+	}
+
+	return r, nil
+}
+
+// readHTTPResponse reads net/http response into resp
+func readHTTPResponse(res *http.Response, resp *fasthttp.Response) error {
+	resp.SetStatusCode(res.StatusCode)
+	for key, values := range res.Header {
+		if key == fasthttp.HeaderContentLength {
+			continue
+		}
+
+		for _, value := range values {
+			resp.Header.Add(key, value)
+		}
+	}
+
+	_, err := io.Copy(resp.BodyWriter(), res.Body)
+	return err
+}
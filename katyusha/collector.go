@@ -0,0 +1,187 @@
+package katyusha
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// collector aggregates request stats into Summary
+type collector struct {
+	url          string
+	rate         int
+	requestDelay time.Duration
+
+	success        int
+	fail           int
+	dataTransfered int
+	errors         map[string]int
+	statusCodes    map[int]int
+
+	requestTimes *histogram
+	latencies    *histogram
+	phases       *phasesCollector
+}
+
+func newCollector(b *Benchmark) *collector {
+	return &collector{
+		url:          b.URL,
+		rate:         b.Rate,
+		requestDelay: b.RequestDelay,
+		errors:       make(map[string]int),
+		statusCodes:  make(map[int]int),
+		requestTimes: newHistogram(),
+		latencies:    newHistogram(),
+		phases:       newPhasesCollector(),
+	}
+}
+
+// add records one request stat
+func (c *collector) add(stat *RequestStat) {
+	c.requestTimes.record(stat.Duration)
+	c.phases.add(stat)
+
+	// With RequestDelay pacing a worker should send request every RequestDelay.
+	// When the request takes longer the requests which were not sent in the meantime
+	// are recorded with the latency they would have had.
+	if c.rate == 0 {
+		c.latencies.recordCorrected(stat.Latency, c.requestDelay)
+	} else {
+		c.latencies.record(stat.Latency)
+	}
+
+	if stat.RetCode != 0 {
+		c.statusCodes[stat.RetCode]++
+	}
+
+	if stat.Success {
+		c.success++
+		c.dataTransfered += stat.BodySize
+		return
+	}
+
+	c.fail++
+	var errString string
+	if stat.Error != nil {
+		errString = stat.Error.Error()
+	} else {
+		errString = fasthttp.StatusMessage(stat.RetCode)
+	}
+
+	c.errors[errString]++
+}
+
+// summary returns results of requests collected between start and end
+func (c *collector) summary(start, end time.Time) *Summary {
+	var reqPerSecond float64
+
+	totalTime := end.Sub(start)
+	if totalTime > time.Duration(time.Second) {
+		reqPerSecond = float64(c.success) / float64(totalTime/time.Second)
+	} else {
+		reqPerSecond = float64(c.success)
+	}
+
+	reqStat := c.requestTimes.stat()
+	latencyStat := c.latencies.stat()
+
+	return &Summary{
+		URL:            c.url,
+		Start:          start,
+		End:            end,
+		TotalTime:      totalTime,
+		DataTransfered: c.dataTransfered,
+		ReqPerSec:      reqPerSecond,
+		ReqCount:       c.success + c.fail,
+		SuccessReq:     c.success,
+		FailReq:        c.fail,
+		AvgReqTime:     reqStat.avg,
+		MinReqTime:     reqStat.min,
+		MaxReqTime:     reqStat.max,
+		P50ReqTime:     reqStat.p50,
+		P75ReqTime:     reqStat.p75,
+		P90ReqTime:     reqStat.p90,
+		P99ReqTime:     reqStat.p99,
+		StdDeviation:   reqStat.stdDeviation,
+		AvgLatency:     latencyStat.avg,
+		MaxLatency:     latencyStat.max,
+		P50Latency:     latencyStat.p50,
+		P75Latency:     latencyStat.p75,
+		P90Latency:     latencyStat.p90,
+		P99Latency:     latencyStat.p99,
+		Errors:         c.errors,
+		StatusCodes:    c.statusCodes,
+		Phases:         c.phases.phases(),
+	}
+}
+
+// collectorSnapshot is collector sent by agent to the coordinator
+type collectorSnapshot struct {
+	Success        int                          `json:"success"`
+	Fail           int                          `json:"fail"`
+	DataTransfered int                          `json:"data_transfered"`
+	Errors         map[string]int               `json:"errors,omitempty"`
+	StatusCodes    map[int]int                  `json:"status_codes,omitempty"`
+	RequestTimes   histogramSnapshot            `json:"request_times"`
+	Latencies      histogramSnapshot            `json:"latencies"`
+	Phases         map[string]histogramSnapshot `json:"phases,omitempty"`
+}
+
+// snapshot returns collected results
+func (c *collector) snapshot() collectorSnapshot {
+	s := collectorSnapshot{
+		Success:        c.success,
+		Fail:           c.fail,
+		DataTransfered: c.dataTransfered,
+		Errors:         c.errors,
+		StatusCodes:    c.statusCodes,
+		RequestTimes:   c.requestTimes.snapshot(),
+		Latencies:      c.latencies.snapshot(),
+		Phases:         make(map[string]histogramSnapshot),
+	}
+
+	for name, h := range c.phases.histograms {
+		if h.count > 0 {
+			s.Phases[name] = h.snapshot()
+		}
+	}
+
+	return s
+}
+
+// merge adds results collected by an agent
+func (c *collector) merge(s collectorSnapshot) error {
+	c.success += s.Success
+	c.fail += s.Fail
+	c.dataTransfered += s.DataTransfered
+
+	for name, count := range s.Errors {
+		c.errors[name] += count
+	}
+
+	for code, count := range s.StatusCodes {
+		c.statusCodes[code] += count
+	}
+
+	if err := c.requestTimes.mergeSnapshot(s.RequestTimes); err != nil {
+		return err
+	}
+
+	if err := c.latencies.mergeSnapshot(s.Latencies); err != nil {
+		return err
+	}
+
+	for name, p := range s.Phases {
+		h, ok := c.phases.histograms[name]
+		if !ok {
+			return fmt.Errorf("Unknown phase %s", name)
+		}
+
+		if err := h.mergeSnapshot(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package katyusha
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// DefaultTolerance is the percent change of a metric which is not reported as regression
+const DefaultTolerance = 5.0
+
+// comparedMetrics are metrics compared between summaries, higherIsBetter tells which direction is a regression
+var comparedMetrics = []struct {
+	name           string
+	higherIsBetter bool
+}{
+	{"req_per_sec", true},
+	{"success_rate", true},
+	{"error_rate", false},
+	{"drop_rate", false},
+	{"avg", false},
+	{"min", false},
+	{"max", false},
+	{"p50", false},
+	{"p75", false},
+	{"p90", false},
+	{"p99", false},
+	{"std_deviation", false},
+	{"avg_latency", false},
+	{"max_latency", false},
+	{"p50_latency", false},
+	{"p75_latency", false},
+	{"p90_latency", false},
+	{"p99_latency", false},
+}
+
+// MetricDelta is the difference of one metric between two summaries.
+// Values use the same units as machine-readable Summary output, durations are in nanoseconds and rates are fractions.
+type MetricDelta struct {
+	Metric     string  `json:"metric"`
+	Base       float64 `json:"base"`
+	Current    float64 `json:"current"`
+	Delta      float64 `json:"delta"`
+	Change     float64 `json:"change"` // Percent change, zero when base value is zero
+	Regression bool    `json:"regression"`
+
+	kind metricKind
+}
+
+// Comparison is the result of comparing current summary against base summary
+type Comparison struct {
+	BaseID    int64         `json:"base_id"`
+	CurrentID int64         `json:"current_id"`
+	Tolerance float64       `json:"tolerance"`
+	Metrics   []MetricDelta `json:"metrics"`
+}
+
+// CompareSummaries returns per metric deltas between base and current summary.
+// Metric is a regression when it changed in the worse direction by more than tolerance percent,
+// or when it became worse and base value is zero.
+func CompareSummaries(base, current *BenchmarkSummary, tolerance float64) *Comparison {
+	c := &Comparison{
+		BaseID:    base.ID,
+		CurrentID: current.ID,
+		Tolerance: tolerance,
+		Metrics:   make([]MetricDelta, 0, len(comparedMetrics)),
+	}
+
+	for _, cm := range comparedMetrics {
+		m := metrics[cm.name]
+		d := MetricDelta{
+			Metric:  cm.name,
+			Base:    m.value(&base.Summary),
+			Current: m.value(&current.Summary),
+			kind:    m.kind,
+		}
+
+		d.Delta = d.Current - d.Base
+		if d.Base != 0 {
+			d.Change = d.Delta / d.Base * 100
+		}
+
+		worse := d.Delta > 0
+		if cm.higherIsBetter {
+			worse = d.Delta < 0
+		}
+
+		if worse {
+			if d.Base == 0 {
+				d.Regression = true
+			} else {
+				change := d.Change
+				if change < 0 {
+					change = -change
+				}
+				d.Regression = change > tolerance
+			}
+		}
+
+		c.Metrics = append(c.Metrics, d)
+	}
+
+	return c
+}
+
+// Regressions returns metrics which changed in the worse direction beyond tolerance
+func (c *Comparison) Regressions() []MetricDelta {
+	var regressions []MetricDelta
+	for _, d := range c.Metrics {
+		if d.Regression {
+			regressions = append(regressions, d)
+		}
+	}
+
+	return regressions
+}
+
+func (c Comparison) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Comparison of summary %d (current) against summary %d (base), tolerance %.4g%%\n", c.CurrentID, c.BaseID, c.Tolerance)
+
+	w := tabwriter.NewWriter(&sb, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Metric\tBase\tCurrent\tDelta\tChange\t")
+	for _, d := range c.Metrics {
+		change := fmt.Sprintf("%+.2f%%", d.Change)
+		if d.Base == 0 && d.Delta != 0 {
+			change = "n/a"
+		}
+
+		delta := formatMetricValue(d.kind, d.Delta)
+		if d.Delta >= 0 {
+			delta = "+" + delta
+		}
+
+		flag := ""
+		if d.Regression {
+			flag = "REGRESSION"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Metric, formatMetricValue(d.kind, d.Base),
+			formatMetricValue(d.kind, d.Current), delta, change, flag)
+	}
+	w.Flush()
+
+	fmt.Fprintf(&sb, "Regressions: %d\n", len(c.Regressions()))
+
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package katyusha
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareSummaries(t *testing.T) {
+	base := &BenchmarkSummary{
+		ID: 1,
+		Summary: Summary{
+			ReqCount:   1000,
+			SuccessReq: 1000,
+			ReqPerSec:  1000,
+			AvgReqTime: 10 * time.Millisecond,
+			P50ReqTime: 10 * time.Millisecond,
+			P99ReqTime: 100 * time.Millisecond,
+		},
+	}
+
+	current := &BenchmarkSummary{
+		ID: 2,
+		Summary: Summary{
+			ReqCount:   1000,
+			SuccessReq: 990,
+			FailReq:    10,
+			ReqPerSec:  970,
+			AvgReqTime: 9 * time.Millisecond,
+			P50ReqTime: 10400 * time.Microsecond,
+			P99ReqTime: 150 * time.Millisecond,
+		},
+	}
+
+	c := CompareSummaries(base, current, DefaultTolerance)
+
+	tests := []struct {
+		metric     string
+		delta      float64
+		change     float64
+		regression bool
+	}{
+		{"req_per_sec", -30, -3, false},
+		{"avg", float64(-time.Millisecond), -10, false},
+		{"p50", float64(400 * time.Microsecond), 4, false},
+		{"p99", float64(50 * time.Millisecond), 50, true},
+		{"error_rate", 0.01, 0, true},
+		{"success_rate", -0.01, -1, false},
+		{"max", 0, 0, false},
+	}
+
+	found := make(map[string]MetricDelta)
+	for _, d := range c.Metrics {
+		found[d.Metric] = d
+	}
+
+	for _, test := range tests {
+		d, ok := found[test.metric]
+		if !ok {
+			t.Errorf("Metric %s not compared", test.metric)
+			continue
+		}
+
+		if !approxEqual(d.Delta, test.delta) || !approxEqual(d.Change, test.change) || d.Regression != test.regression {
+			t.Errorf("Metric %s expected delta %v change %v regression %t got %v %v %t", test.metric,
+				test.delta, test.change, test.regression, d.Delta, d.Change, d.Regression)
+		}
+	}
+
+	if len(c.Regressions()) != 2 {
+		t.Errorf("Expected 2 regressions got %d", len(c.Regressions()))
+	}
+
+	if !strings.Contains(c.String(), "REGRESSION") {
+		t.Errorf("Regressions are not flagged in comparison table:\n%s", c)
+	}
+
+	c = CompareSummaries(base, current, 60)
+	if len(c.Regressions()) != 1 || c.Regressions()[0].Metric != "error_rate" {
+		t.Errorf("Expected only error_rate regression with 60%% tolerance got %v", c.Regressions())
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
@@ -0,0 +1,22 @@
+package katyusha
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardFiles are static files of the web dashboard, it uses only the Server API and no external resources
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// newDashboard returns handler serving the dashboard files
+func newDashboard() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,537 @@
+// Katyusha dashboard, a single page using the API of kt serve without external libraries
+"use strict";
+
+const main = document.getElementById("main");
+const colors = ["#0969da", "#1f883d", "#cf222e", "#9a6700", "#8250df"];
+
+// el creates HTML element, text children are escaped by the DOM
+function el(tag, attrs, ...children) {
+  return build(document.createElement(tag), attrs, children);
+}
+
+function svg(tag, attrs, ...children) {
+  return build(document.createElementNS("http://www.w3.org/2000/svg", tag), attrs, children);
+}
+
+function build(e, attrs, children) {
+  for (const [name, value] of Object.entries(attrs || {})) {
+    if (name.startsWith("on")) {
+      e.addEventListener(name.slice(2), value);
+    } else if (value !== undefined && value !== null && value !== false) {
+      e.setAttribute(name, value === true ? "" : value);
+    }
+  }
+
+  for (const child of children.flat()) {
+    if (child !== undefined && child !== null && child !== false) {
+      e.append(child instanceof Node ? child : String(child));
+    }
+  }
+
+  return e;
+}
+
+// api calls the server API and returns the decoded response, API errors are thrown
+async function api(method, path, body) {
+  const res = await fetch("/api" + path, {
+    method: method,
+    headers: body ? {"Content-Type": "application/json"} : {},
+    body: body ? JSON.stringify(body) : undefined,
+  });
+
+  const text = await res.text();
+  const data = text ? JSON.parse(text) : null;
+  if (!res.ok) {
+    throw new Error(data && data.error ? data.error : res.statusText);
+  }
+
+  return data;
+}
+
+// Durations are in nanoseconds like in machine-readable output of kt
+function fmtDuration(ns) {
+  if (ns < 0) {
+    return "-" + fmtDuration(-ns);
+  }
+
+  for (const [size, unit] of [[6e10, "m"], [1e9, "s"], [1e6, "ms"], [1e3, "µs"]]) {
+    if (ns >= size) {
+      return (ns / size).toFixed(2).replace(/\.?0+$/, "") + unit;
+    }
+  }
+
+  return Math.round(ns) + "ns";
+}
+
+function fmtNumber(v) {
+  return Number(v).toFixed(2);
+}
+
+function fmtPercent(fraction) {
+  return (fraction * 100).toFixed(2) + "%";
+}
+
+function fmtBytes(bytes) {
+  for (const [size, unit] of [[1 << 30, "G"], [1 << 20, "M"], [1 << 10, "K"]]) {
+    if (bytes >= size) {
+      return (bytes / size).toFixed(1) + unit;
+    }
+  }
+
+  return bytes + "B";
+}
+
+function fmtTime(time) {
+  return new Date(time).toLocaleString();
+}
+
+function fmtShortTime(ms) {
+  return new Date(ms).toLocaleString([], {month: "short", day: "numeric", hour: "2-digit", minute: "2-digit"});
+}
+
+function fmtSeconds(s) {
+  return s.toFixed(1).replace(/\.0$/, "") + "s";
+}
+
+// fmtMetric formats value of compared metric, rates are fractions and latencies are durations
+function fmtMetric(metric, value) {
+  if (metric === "req_per_sec") {
+    return fmtNumber(value);
+  }
+
+  if (metric.endsWith("_rate")) {
+    return fmtPercent(value);
+  }
+
+  return fmtDuration(value);
+}
+
+function errorRate(s) {
+  return s.requests_count ? s.fail_req / s.requests_count : 0;
+}
+
+function stat(label, value) {
+  return el("div", {class: "stat"}, el("div", {class: "label"}, label), el("div", {class: "value"}, value));
+}
+
+function table(headers, rows) {
+  return el("table", {},
+    el("thead", {}, el("tr", {}, headers.map(h => el("th", {}, h)))),
+    el("tbody", {}, rows));
+}
+
+function status(run) {
+  return el("span", {class: "status " + run.status}, run.status);
+}
+
+// lineChart draws series of [x, y] points, formatX and formatY format axis labels.
+// X axis starts at xStart when it is set, e.g. 0 for the time since the benchmark start.
+function lineChart(title, series, formatX, formatY, xStart) {
+  const W = 560, H = 220, L = 70, R = 16, T = 10, B = 24;
+  const box = el("div", {class: "chart"}, el("h3", {}, title));
+
+  const points = series.flatMap(s => s.points);
+  if (points.length === 0) {
+    box.append(el("div", {class: "empty"}, "No data"));
+    return box;
+  }
+
+  let xMin = xStart !== undefined ? xStart : Math.min(...points.map(p => p[0]));
+  let xMax = Math.max(...points.map(p => p[0]));
+  if (xMin >= xMax) {
+    xMax = xMin + 1;
+  }
+  const yMax = Math.max(...points.map(p => p[1])) * 1.1 || 1;
+
+  const sx = v => L + (v - xMin) / (xMax - xMin) * (W - L - R);
+  const sy = v => H - B - v / yMax * (H - T - B);
+
+  const chart = svg("svg", {viewBox: `0 0 ${W} ${H}`});
+  for (let i = 0; i <= 4; i++) {
+    const v = yMax * i / 4;
+    chart.append(
+      svg("line", {class: "grid", x1: L, x2: W - R, y1: sy(v), y2: sy(v)}),
+      svg("text", {class: "axis", x: L - 6, y: sy(v) + 3, "text-anchor": "end"}, formatY(v)));
+  }
+
+  for (let i = 0; i <= 3; i++) {
+    const v = xMin + (xMax - xMin) * i / 3;
+    const anchor = i === 0 ? "start" : i === 3 ? "end" : "middle";
+    chart.append(svg("text", {class: "axis", x: sx(v), y: H - 6, "text-anchor": anchor}, formatX(v)));
+  }
+
+  const legend = el("div", {class: "legend"});
+  series.forEach((s, i) => {
+    const color = colors[i % colors.length];
+    chart.append(svg("polyline", {
+      fill: "none",
+      stroke: color,
+      "stroke-width": 2,
+      points: s.points.map(p => `${sx(p[0])},${sy(p[1])}`).join(" "),
+    }));
+
+    // Points have tooltips unless there are too many of them
+    if (s.points.length <= 100) {
+      for (const p of s.points) {
+        chart.append(svg("circle", {cx: sx(p[0]), cy: sy(p[1]), r: 3, fill: color},
+          svg("title", {}, `${s.name}: ${formatY(p[1])} at ${formatX(p[0])}`)));
+      }
+    }
+
+    legend.append(el("span", {}, el("i", {style: "background: " + color}), s.name));
+  });
+
+  box.append(chart, legend);
+  return box;
+}
+
+// runButton starts run of the benchmark configuration and shows it
+function runButton(id) {
+  return el("button", {
+    class: "primary",
+    onclick: async () => {
+      try {
+        const run = await api("POST", `/benchmarks/${id}/runs`);
+        location.hash = `#/runs/${run.id}`;
+      } catch (e) {
+        alert(e.message);
+      }
+    },
+  }, "Run");
+}
+
+function describeLoad(b) {
+  let load = `${b.concurrent_conns} connections`;
+  if (b.rate) {
+    load += `, ${b.rate} req/s`;
+  }
+
+  return load;
+}
+
+function describeLength(b) {
+  if (b.stages && b.stages.length) {
+    return `${b.stages.length} stages`;
+  }
+
+  if (b.duration) {
+    return fmtDuration(b.duration);
+  }
+
+  return `${b.requests_count} requests`;
+}
+
+async function showBenchmarks() {
+  const [benchmarks, runs] = await Promise.all([api("GET", "/benchmarks"), api("GET", "/runs")]);
+
+  const nodes = [el("h1", {}, "Benchmark configurations")];
+
+  const running = runs.find(r => r.status === "running");
+  if (running) {
+    nodes.push(el("p", {}, "Benchmark ", el("a", {href: `#/benchmarks/${running.configuration}`}, running.configuration),
+      " is running: ", el("a", {href: `#/runs/${running.id}`}, `run ${running.id}`)));
+  }
+
+  if (benchmarks.length === 0) {
+    nodes.push(el("p", {}, "There are no benchmark configurations, save one with kt benchmark --save or the API."));
+    return nodes;
+  }
+
+  nodes.push(table(["ID", "Description", "URL", "Method", "Load", "Length", ""],
+    benchmarks.map(b => el("tr", {},
+      el("td", {}, el("a", {href: `#/benchmarks/${b.id}`}, b.id)),
+      el("td", {class: "wrap"}, el("a", {href: `#/benchmarks/${b.id}`}, b.description)),
+      el("td", {class: "wrap"}, b.url),
+      el("td", {}, b.method),
+      el("td", {}, describeLoad(b)),
+      el("td", {}, describeLength(b)),
+      el("td", {}, runButton(b.id))))));
+
+  return nodes;
+}
+
+async function showBenchmark(onLeave, id) {
+  const [b, summaries] = await Promise.all([api("GET", `/benchmarks/${id}`), api("GET", `/benchmarks/${id}/summaries`)]);
+  summaries.sort((x, y) => new Date(x.start) - new Date(y.start));
+
+  const nodes = [
+    el("h1", {}, `Benchmark ${b.id}: ${b.description}`),
+    el("div", {class: "stats"},
+      stat("URL", b.url),
+      stat("Method", b.method || "GET"),
+      stat("Protocol", b.protocol || "http1"),
+      stat("Load", describeLoad(b)),
+      stat("Length", describeLength(b))),
+    el("div", {class: "toolbar"}, runButton(b.id)),
+    el("h2", {}, "Trends"),
+  ];
+
+  const at = s => new Date(s.start).getTime();
+  nodes.push(el("div", {class: "charts"},
+    lineChart("Throughput", [{name: "req/s", points: summaries.map(s => [at(s), s.req_per_sec])}], fmtShortTime, fmtNumber),
+    lineChart("Request time", ["p50", "p90", "p99"].map(p => ({
+      name: p,
+      points: summaries.map(s => [at(s), s[p + "_req_time"]]),
+    })), fmtShortTime, fmtDuration),
+    lineChart("Error rate", [{name: "errors", points: summaries.map(s => [at(s), errorRate(s)])}], fmtShortTime, fmtPercent)));
+
+  nodes.push(el("h2", {}, "Summaries"));
+  if (summaries.length === 0) {
+    nodes.push(el("p", {}, "The benchmark has not been run yet."));
+    return nodes;
+  }
+
+  // Two selected summaries are compared, the older one is the base
+  const selected = new Set();
+  const compare = el("button", {
+    disabled: true,
+    onclick: () => {
+      const [base, current] = [...selected].sort((x, y) => x - y);
+      location.hash = `#/compare/${base}/${current}`;
+    },
+  }, "Compare selected");
+
+  const select = (id, checked) => {
+    if (checked) {
+      selected.add(id);
+    } else {
+      selected.delete(id);
+    }
+    compare.disabled = selected.size !== 2;
+  };
+
+  nodes.push(el("div", {class: "toolbar"}, compare, "Select two summaries to compare them side by side."));
+  nodes.push(table(["", "ID", "Start", "Duration", "Requests", "Req/s", "P50", "P99", "Error rate"],
+    summaries.slice().reverse().map(s => el("tr", {},
+      el("td", {}, el("input", {type: "checkbox", onchange: e => select(s.id, e.target.checked)})),
+      el("td", {}, el("a", {href: `#/summaries/${s.id}`}, s.id)),
+      el("td", {}, fmtTime(s.start)),
+      el("td", {}, fmtDuration(s.duration)),
+      el("td", {class: "number"}, s.requests_count),
+      el("td", {class: "number"}, fmtNumber(s.req_per_sec)),
+      el("td", {class: "number"}, fmtDuration(s.p50_req_time)),
+      el("td", {class: "number"}, fmtDuration(s.p99_req_time)),
+      el("td", {class: "number"}, fmtPercent(errorRate(s)))))));
+
+  return nodes;
+}
+
+function summaryStats(s) {
+  return el("div", {class: "stats"},
+    stat("Requests", s.requests_count),
+    stat("Successful", s.success_req),
+    stat("Failed", s.fail_req),
+    stat("Req/s", fmtNumber(s.req_per_sec)),
+    stat("Average", fmtDuration(s.avg_req_time)),
+    stat("P50", fmtDuration(s.p50_req_time)),
+    stat("P90", fmtDuration(s.p90_req_time)),
+    stat("P99", fmtDuration(s.p99_req_time)),
+    stat("Max", fmtDuration(s.max_req_time)),
+    stat("Data", fmtBytes(s.data_transfered)));
+}
+
+async function showSummary(onLeave, id) {
+  const s = await api("GET", `/summaries/${id}`);
+  const intervals = s.intervals || [];
+  const at = i => (i.offset + i.duration) / 1e9;
+
+  const nodes = [
+    el("h1", {}, `Summary ${s.id}`),
+    el("p", {}, `Started ${fmtTime(s.start)} and took ${fmtDuration(s.duration)}`),
+    summaryStats(s),
+    el("div", {class: "charts"},
+      lineChart("Throughput", [{name: "req/s", points: intervals.map(i => [at(i), i.duration ? i.requests_count / (i.duration / 1e9) : 0])}], fmtSeconds, fmtNumber, 0),
+      lineChart("Request time", ["p50", "p90", "p99"].map(p => ({
+        name: p,
+        points: intervals.map(i => [at(i), i[p + "_req_time"]]),
+      })), fmtSeconds, fmtDuration, 0)),
+  ];
+
+  const codes = Object.entries(s.status_codes || {});
+  const errors = Object.entries(s.errors || {});
+  if (codes.length || errors.length) {
+    nodes.push(el("h2", {}, "Responses"));
+    nodes.push(table(["Status code or error", "Count"], [
+      ...codes.map(([code, count]) => el("tr", {}, el("td", {}, code), el("td", {class: "number"}, count))),
+      ...errors.map(([error, count]) => el("tr", {}, el("td", {class: "wrap"}, error), el("td", {class: "number"}, count))),
+    ]));
+  }
+
+  return nodes;
+}
+
+async function showCompare(onLeave, baseID, currentID) {
+  const [comparison, base, current] = await Promise.all([
+    api("GET", `/compare?base=${baseID}&current=${currentID}`),
+    api("GET", `/summaries/${baseID}`),
+    api("GET", `/summaries/${currentID}`),
+  ]);
+
+  const side = (label, f) => el("tr", {}, el("td", {}, label), el("td", {}, f(base)), el("td", {}, f(current)));
+  const regressions = comparison.metrics.filter(m => m.regression).length;
+
+  return [
+    el("h1", {}, `Summary ${current.id} compared to ${base.id}`),
+    table(["", el("a", {href: `#/summaries/${base.id}`}, `Base ${base.id}`), el("a", {href: `#/summaries/${current.id}`}, `Current ${current.id}`)], [
+      side("Start", s => fmtTime(s.start)),
+      side("Duration", s => fmtDuration(s.duration)),
+      side("Requests", s => s.requests_count),
+      side("Failed", s => s.fail_req),
+    ]),
+    el("h2", {}, `Metrics, ${regressions} regressions beyond ${comparison.tolerance}% tolerance`),
+    table(["Metric", "Base", "Current", "Delta", "Change"],
+      comparison.metrics.map(m => el("tr", {class: m.regression ? "regression" : null},
+        el("td", {}, m.metric),
+        el("td", {class: "number"}, fmtMetric(m.metric, m.base)),
+        el("td", {class: "number"}, fmtMetric(m.metric, m.current)),
+        el("td", {class: "number"}, (m.delta > 0 ? "+" : "") + fmtMetric(m.metric, m.delta)),
+        el("td", {class: "number"}, (m.change > 0 ? "+" : "") + m.change.toFixed(2) + "%")))),
+  ];
+}
+
+async function showRuns() {
+  const runs = await api("GET", "/runs");
+  if (runs.length === 0) {
+    return [el("h1", {}, "Runs"), el("p", {}, "No benchmark was run since the server started.")];
+  }
+
+  return [
+    el("h1", {}, "Runs"),
+    table(["ID", "Benchmark", "Status", "Start", "End", "Summary"],
+      runs.reverse().map(r => el("tr", {},
+        el("td", {}, el("a", {href: `#/runs/${r.id}`}, r.id)),
+        el("td", {}, el("a", {href: `#/benchmarks/${r.configuration}`}, r.configuration)),
+        el("td", {}, status(r)),
+        el("td", {}, fmtTime(r.start)),
+        el("td", {}, r.end ? fmtTime(r.end) : ""),
+        el("td", {}, r.summary ? el("a", {href: `#/summaries/${r.summary}`}, r.summary) : "")))),
+  ];
+}
+
+// showRun follows progress of the run and draws its live charts
+async function showRun(onLeave, id) {
+  const run = await api("GET", `/runs/${id}`);
+  const live = el("div");
+  const throughput = [];
+  const p99 = [];
+
+  const cancel = el("button", {
+    class: "danger",
+    onclick: async () => {
+      try {
+        cancel.disabled = true;
+        await api("POST", `/runs/${id}/cancel`);
+      } catch (e) {
+        alert(e.message);
+      }
+    },
+  }, "Cancel");
+
+  const render = run => {
+    const p = run.progress;
+    if (p && (throughput.length === 0 || throughput[throughput.length - 1][0] < p.elapsed / 1e9)) {
+      throughput.push([p.elapsed / 1e9, p.req_per_sec]);
+      p99.push([p.elapsed / 1e9, p.p99_req_time]);
+    }
+
+    const nodes = [el("p", {}, "Status ", status(run), ` since ${fmtTime(run.start)}`)];
+    if (p) {
+      nodes.push(el("div", {class: "stats"},
+        stat("Elapsed", fmtDuration(p.elapsed)),
+        stat("Remaining", p.remaining ? fmtDuration(p.remaining) : "unknown"),
+        p.stage ? stat("Stage", p.stage) : null,
+        stat("Requests", p.requests_count),
+        stat("Failed", p.fail_req),
+        stat("Req/s", fmtNumber(p.req_per_sec)),
+        stat("P99", fmtDuration(p.p99_req_time))));
+    }
+
+    if (run.status === "running") {
+      nodes.push(el("div", {class: "toolbar"}, cancel));
+    }
+
+    if (run.error) {
+      nodes.push(el("div", {class: "error"}, run.error));
+    }
+
+    if (run.summary) {
+      nodes.push(el("p", {}, el("a", {href: `#/summaries/${run.summary}`}, `Summary ${run.summary}`), " was saved."));
+    }
+
+    if (run.thresholds) {
+      nodes.push(table(["Threshold", "Actual", "Result"], run.thresholds.map(t => el("tr", {class: t.pass ? null : "regression"},
+        el("td", {}, t.threshold), el("td", {}, t.actual), el("td", {}, t.pass ? "PASS" : "FAIL")))));
+    }
+
+    nodes.push(el("div", {class: "charts"},
+      lineChart("Throughput", [{name: "req/s", points: throughput}], fmtSeconds, fmtNumber, 0),
+      lineChart("P99 request time", [{name: "p99", points: p99}], fmtSeconds, fmtDuration, 0)));
+
+    live.replaceChildren(...nodes);
+  };
+
+  render(run);
+
+  if (run.status === "running") {
+    const events = new EventSource(`/api/runs/${id}/progress`);
+    events.onmessage = e => {
+      const run = JSON.parse(e.data);
+      render(run);
+      if (run.status !== "running") {
+        events.close();
+      }
+    };
+    onLeave(() => events.close());
+  }
+
+  return [el("h1", {}, "Run ", run.id, " of benchmark ", el("a", {href: `#/benchmarks/${run.configuration}`}, run.configuration)), live];
+}
+
+const routes = [
+  [/^#\/?$/, showBenchmarks],
+  [/^#\/benchmarks\/(\d+)$/, showBenchmark],
+  [/^#\/summaries\/(\d+)$/, showSummary],
+  [/^#\/compare\/(\d+)\/(\d+)$/, showCompare],
+  [/^#\/runs$/, showRuns],
+  [/^#\/runs\/(\d+)$/, showRun],
+];
+
+// leave releases resources of the current page, e.g. the progress stream of a run
+let leave = () => {};
+
+async function route() {
+  leave();
+
+  let left = false;
+  const cleanups = [];
+  leave = () => {
+    left = true;
+    cleanups.forEach(f => f());
+  };
+  const onLeave = f => left ? f() : cleanups.push(f);
+
+  const hash = location.hash || "#/";
+  for (const [pattern, view] of routes) {
+    const match = hash.match(pattern);
+    if (!match) {
+      continue;
+    }
+
+    try {
+      const nodes = await view(onLeave, ...match.slice(1));
+      if (!left) {
+        main.replaceChildren(...nodes);
+      }
+    } catch (e) {
+      if (!left) {
+        main.replaceChildren(el("div", {class: "error"}, e.message));
+      }
+    }
+    return;
+  }
+
+  main.replaceChildren(el("div", {class: "error"}, "Page not found"));
+}
+
+window.addEventListener("hashchange", route);
+route();
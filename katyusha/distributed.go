@@ -0,0 +1,343 @@
+package katyusha
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AgentStartDelay is the time given to agents to prepare the benchmark, all agents start it together after the delay
+const AgentStartDelay = time.Second
+
+// AgentStopTimeout is the time to wait for results of agents after they were asked to stop
+const AgentStopTimeout = 10 * time.Second
+
+// agentUpdate is a message of one agent read by the coordinator
+type agentUpdate struct {
+	agent    int
+	progress *Progress
+	results  *resultsSnapshot
+	err      error
+}
+
+// StartDistributed runs the benchmark on agents started with Agent, agents are host:port addresses or URLs.
+// Connections, requests, rate and abort after are split between agents and all agents start at the same time.
+// Progress of agents is merged and passed to OnProgress, results are merged into one Summary.
+// When ctx is cancelled agents are stopped and the summary has results collected so far.
+func (b *Benchmark) StartDistributed(ctx context.Context, agents []string) (*Summary, error) {
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("Distributed benchmark needs at least one agent")
+	}
+
+	parts, err := b.BenchmarkParameters.split(len(agents))
+	if err != nil {
+		return nil, err
+	}
+
+	if b.feeder != nil && b.feeder.strategy == FeederUnique && len(b.feeder.rows) < len(agents) {
+		return nil, fmt.Errorf("%d rows of unique feeder can't be split between %d agents", len(b.feeder.rows), len(agents))
+	}
+
+	id, err := newRunID()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, len(agents))
+	for i, agent := range agents {
+		urls[i] = agentURL(agent)
+	}
+
+	// Requests of agents are not cancelled with ctx, agents are stopped so they can send their results
+	agentCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+
+	start := time.Now().Add(AgentStartDelay)
+	updates := make(chan agentUpdate)
+
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		run := agentRun{
+			ID:               id,
+			Parameters:       parts[i],
+			Delay:            time.Until(start),
+			ProgressInterval: b.ProgressInterval,
+		}
+
+		go func(i int) {
+			defer wg.Done()
+			runAgent(agentCtx, i, urls[i], &run, updates)
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	var progressTick <-chan time.Time
+	if b.OnProgress != nil {
+		interval := b.ProgressInterval
+		if interval <= 0 {
+			interval = DefaultProgressInterval
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		progressTick = ticker.C
+	}
+
+	// Agents which don't send results after they were stopped are aborted
+	var stopTimer *time.Timer
+	var stopTimeout <-chan time.Time
+	stop := func() {
+		if stopTimer != nil {
+			return
+		}
+
+		stopAgents(urls, id)
+		stopTimer = time.NewTimer(AgentStopTimeout)
+		stopTimeout = stopTimer.C
+	}
+	defer func() {
+		if stopTimer != nil {
+			stopTimer.Stop()
+		}
+	}()
+
+	snapshots := make([]*resultsSnapshot, len(agents))
+	progress := make([]Progress, len(agents))
+	done := ctx.Done()
+
+	var firstErr error
+MAIN:
+	for {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				break MAIN
+			}
+
+			switch {
+			case u.err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("Agent %s: %w", agents[u.agent], u.err)
+				}
+				stop()
+			case u.progress != nil:
+				progress[u.agent] = *u.progress
+			case u.results != nil:
+				snapshots[u.agent] = u.results
+			}
+		case <-progressTick:
+			b.OnProgress(mergeProgress(progress))
+		case <-done:
+			done = nil
+			stop()
+		case <-stopTimeout:
+			abort()
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	r := newResults(b, start)
+	r.end = time.Now()
+
+	intervals := make([][]intervalSnapshot, 0, len(snapshots))
+	for i, s := range snapshots {
+		if err := r.merge(s); err != nil {
+			return nil, fmt.Errorf("Agent %s: %w", agents[i], err)
+		}
+
+		intervals = append(intervals, s.Intervals)
+	}
+
+	if err := r.timeline.merge(intervals); err != nil {
+		return nil, err
+	}
+	r.intervals = r.timeline.intervals(r.end)
+
+	return r.summary(), nil
+}
+
+// runAgent starts benchmark on the agent and sends its progress and results to updates
+func runAgent(ctx context.Context, agent int, url string, run *agentRun, updates chan<- agentUpdate) {
+	update := func(u agentUpdate) {
+		u.agent = agent
+		updates <- u
+	}
+
+	body, err := json.Marshal(run)
+	if err != nil {
+		update(agentUpdate{err: err})
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/run", bytes.NewReader(body))
+	if err != nil {
+		update(agentUpdate{err: err})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		update(agentUpdate{err: err})
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		update(agentUpdate{err: readAgentError(res)})
+		return
+	}
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var msg agentMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			update(agentUpdate{err: errors.New("Agent closed connection without results")})
+			return
+		} else if err != nil {
+			update(agentUpdate{err: err})
+			return
+		}
+
+		switch {
+		case msg.Error != "":
+			update(agentUpdate{err: errors.New(msg.Error)})
+			return
+		case msg.Results != nil:
+			update(agentUpdate{results: msg.Results})
+			return
+		case msg.Progress != nil:
+			update(agentUpdate{progress: msg.Progress})
+		}
+	}
+}
+
+// stopAgents asks agents to stop the benchmark, agents which already finished are ignored
+func stopAgents(urls []string, id string) {
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), AgentStopTimeout)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/stop?id="+id, nil)
+			if err != nil {
+				return
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err == nil {
+				res.Body.Close()
+			}
+		}(url)
+	}
+
+	wg.Wait()
+}
+
+// readAgentError returns error sent by agent which did not start the benchmark
+func readAgentError(res *http.Response) error {
+	var msg agentMessage
+	if err := json.NewDecoder(res.Body).Decode(&msg); err != nil || msg.Error == "" {
+		return fmt.Errorf("Agent returned %s", res.Status)
+	}
+
+	return errors.New(msg.Error)
+}
+
+// agentURL returns base URL of the agent address
+func agentURL(agent string) string {
+	if !strings.Contains(agent, "://") {
+		agent = "http://" + agent
+	}
+
+	return strings.TrimSuffix(agent, "/")
+}
+
+// newRunID returns random ID of distributed benchmark run
+func newRunID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// split returns parameters of each of n agents.
+// Connections, requests, rate and abort after are split, the first agents get the remainder.
+func (b *BenchmarkParameters) split(n int) ([]BenchmarkParameters, error) {
+	if len(b.Stages) == 0 && b.ConcurrentConns < n {
+		return nil, fmt.Errorf("%d connections can't be split between %d agents", b.ConcurrentConns, n)
+	}
+
+	if b.Rate > 0 && b.Rate < n {
+		return nil, fmt.Errorf("Rate %d can't be split between %d agents", b.Rate, n)
+	}
+
+	parts := make([]BenchmarkParameters, n)
+	for i := range parts {
+		part := *b
+		part.ConcurrentConns = share(b.ConcurrentConns, i, n)
+		part.ReqCount = share(b.ReqCount, i, n)
+		part.Rate = share(b.Rate, i, n)
+
+		// Every agent aborts after at least one failed request
+		if b.AbortAfter > 0 {
+			part.AbortAfter = share(b.AbortAfter, i, n)
+			if part.AbortAfter == 0 {
+				part.AbortAfter = 1
+			}
+		}
+
+		// Every agent reads the feeder file so rows of unique feeder are split to stay unique across agents
+		if b.Feeder != nil && b.Feeder.Strategy == FeederUnique {
+			feeder := *b.Feeder
+			feeder.Part, feeder.Parts = i, n
+			part.Feeder = &feeder
+		}
+
+		if len(b.Stages) > 0 {
+			part.Stages = make([]Stage, len(b.Stages))
+			for j, stage := range b.Stages {
+				stage.Connections = share(stage.Connections, i, n)
+				stage.Rate = share(stage.Rate, i, n)
+				part.Stages[j] = stage
+			}
+		}
+
+		parts[i] = part
+	}
+
+	return parts, nil
+}
+
+// share returns part of v of the i-th of n agents
+func share(v, i, n int) int {
+	s := v / n
+	if i < v%n {
+		s++
+	}
+
+	return s
+}
@@ -0,0 +1,364 @@
+package katyusha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startAgents starts n agents and returns their addresses
+func startAgents(t *testing.T, n int) []string {
+	var agents []string
+	for i := 0; i < n; i++ {
+		server := httptest.NewServer(NewAgent())
+		t.Cleanup(server.Close)
+
+		agents = append(agents, strings.TrimPrefix(server.URL, "http://"))
+	}
+
+	return agents
+}
+
+func TestDistributed(t *testing.T) {
+	var count int64
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+
+		if atomic.AddInt64(&count, 1)%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL,
+		Method:          "GET",
+		ConcurrentConns: 6,
+		ReqCount:        1000,
+		ReportInterval:  100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	var reports []Progress
+	benchmark.ProgressInterval = 50 * time.Millisecond
+	benchmark.OnProgress = func(p Progress) {
+		reports = append(reports, p)
+	}
+
+	summary, err := benchmark.StartDistributed(context.Background(), startAgents(t, 3))
+	if err != nil {
+		t.Fatalf("Distributed benchmark failed: %v", err)
+	}
+
+	if summary.ReqCount != 1000 || summary.SuccessReq != 900 || summary.FailReq != 100 {
+		t.Errorf("Expected 1000 requests with 100 failed, got %d with %d failed", summary.ReqCount, summary.FailReq)
+	}
+
+	if summary.StatusCodes[500] != 100 || summary.StatusCodes[200] != 900 {
+		t.Errorf("Status codes of agents should be merged: %v", summary.StatusCodes)
+	}
+
+	if summary.MinReqTime < time.Millisecond || summary.P99ReqTime < summary.P50ReqTime || summary.ReqPerSec == 0 {
+		t.Errorf("Request times of agents should be merged: %+v", summary)
+	}
+
+	if summary.Phases.TTFB.Count != 1000 || summary.Phases.Connect.Count < 6 {
+		t.Errorf("Phases of agents should be merged: %+v", summary.Phases)
+	}
+
+	var reqCount int
+	for _, interval := range summary.Intervals {
+		reqCount += interval.ReqCount
+	}
+
+	if len(summary.Intervals) == 0 || reqCount != summary.ReqCount {
+		t.Errorf("Intervals requests should sum up to %d but it is %d", summary.ReqCount, reqCount)
+	}
+
+	if len(reports) == 0 {
+		t.Fatalf("Progress of agents should be reported")
+	}
+
+	last := reports[len(reports)-1]
+	if last.ReqCount == 0 || last.ReqCount > summary.ReqCount {
+		t.Errorf("Progress should report requests of all agents: %+v", last)
+	}
+}
+
+func TestDistributedRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Test")
+	}))
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL,
+		Method:          "GET",
+		ConcurrentConns: 4,
+		Rate:            101,
+		Duration:        500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary, err := benchmark.StartDistributed(context.Background(), startAgents(t, 2))
+	if err != nil {
+		t.Fatalf("Distributed benchmark failed: %v", err)
+	}
+
+	if summary.ScheduledReq < 45 || summary.ScheduledReq > 55 {
+		t.Errorf("About 50 requests should be scheduled but it is %d", summary.ScheduledReq)
+	}
+
+	if summary.ReqCount != summary.ScheduledReq-summary.DroppedReq {
+		t.Errorf("All scheduled requests should be sent: %d of %d", summary.ReqCount, summary.ScheduledReq)
+	}
+}
+
+func TestDistributedStagesScenario(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}))
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL,
+		Method:          "GET",
+		ConcurrentConns: 1,
+		Stages: []Stage{
+			{Duration: 300 * time.Millisecond, Connections: 4},
+			{Duration: 300 * time.Millisecond, Connections: 2},
+		},
+		Scenario: []Endpoint{
+			{Name: "items", URL: "/items", Weight: 3},
+			{Name: "orders", URL: "/orders"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary, err := benchmark.StartDistributed(context.Background(), startAgents(t, 2))
+	if err != nil {
+		t.Fatalf("Distributed benchmark failed: %v", err)
+	}
+
+	if len(summary.Stages) != 2 || len(summary.Endpoints) != 2 {
+		t.Fatalf("Summary should have 2 stages and 2 endpoints: %d %d", len(summary.Stages), len(summary.Endpoints))
+	}
+
+	var stages, endpoints int
+	for i := range summary.Stages {
+		stages += summary.Stages[i].ReqCount
+		endpoints += summary.Endpoints[i].ReqCount
+	}
+
+	if stages != summary.ReqCount || endpoints != summary.ReqCount {
+		t.Errorf("Stages (%d) and endpoints (%d) requests should sum up to %d", stages, endpoints, summary.ReqCount)
+	}
+
+	if summary.Endpoints[0].Endpoint != "items" || summary.Endpoints[0].ReqCount <= summary.Endpoints[1].ReqCount {
+		t.Errorf("Endpoint items should have more requests: %+v", summary.Endpoints[0])
+	}
+}
+
+func TestDistributedCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}))
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL,
+		Method:          "GET",
+		ConcurrentConns: 2,
+		Duration:        time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), AgentStartDelay+500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	summary, err := benchmark.StartDistributed(ctx, startAgents(t, 2))
+	if err != nil {
+		t.Fatalf("Distributed benchmark failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > AgentStartDelay+5*time.Second {
+		t.Errorf("Agents should stop when the benchmark is cancelled, it took %v", elapsed)
+	}
+
+	if summary.ReqCount == 0 || summary.SuccessReq != summary.ReqCount {
+		t.Errorf("Summary should have requests sent before the benchmark was cancelled: %d of %d", summary.SuccessReq, summary.ReqCount)
+	}
+}
+
+func TestDistributedUniqueFeeder(t *testing.T) {
+	var mu sync.Mutex
+	users := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		users[strings.TrimPrefix(r.URL.Path, "/users/")]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var content strings.Builder
+	content.WriteString("user\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&content, "%d\n", i)
+	}
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL + "/users/${user}",
+		Method:          "GET",
+		ConcurrentConns: 3,
+		ReqCount:        100,
+		Feeder: &Feeder{
+			File:      writeFeederFile(t, dir, "users.csv", content.String()),
+			Strategy:  FeederUnique,
+			Exhausted: FeederStop,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary, err := benchmark.StartDistributed(context.Background(), startAgents(t, 3))
+	if err != nil {
+		t.Fatalf("Distributed benchmark failed: %v", err)
+	}
+
+	// Rows are split between agents so every row is used once
+	if summary.ReqCount != 50 || len(users) != 50 {
+		t.Errorf("Every row should be requested once, got %d requests of %d rows", summary.ReqCount, len(users))
+	}
+
+	for user, n := range users {
+		if n != 1 {
+			t.Errorf("Row %s requested %d times", user, n)
+		}
+	}
+
+	// Every agent needs at least one row
+	benchmark, err = NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL + "/users/${user}",
+		Method:          "GET",
+		ConcurrentConns: 3,
+		ReqCount:        100,
+		Feeder:          &Feeder{File: writeFeederFile(t, dir, "few.csv", "user\n1\n2\n"), Strategy: FeederUnique},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	_, err = benchmark.StartDistributed(context.Background(), startAgents(t, 3))
+	if err == nil || !strings.Contains(err.Error(), "rows of unique feeder can't be split") {
+		t.Errorf("Expected error splitting 2 rows between 3 agents, got %v", err)
+	}
+}
+
+func TestDistributedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Test")
+	}))
+	defer server.Close()
+
+	// Busy agent may still be finishing its run so the last case has another agent
+	agents := startAgents(t, 2)
+	agent := agents[0]
+
+	closed := httptest.NewServer(NewAgent())
+	closed.Close()
+
+	tt := []struct {
+		name   string
+		conns  int
+		agents []string
+		err    string
+	}{
+		{
+			name:   "No agents",
+			conns:  2,
+			agents: nil,
+			err:    "at least one agent",
+		},
+		{
+			name:   "Connections split",
+			conns:  1,
+			agents: []string{agent, agent},
+			err:    "can't be split",
+		},
+		{
+			name:   "Busy agent",
+			conns:  2,
+			agents: []string{agent, agent},
+			err:    "Agent is running benchmark",
+		},
+		{
+			name:   "Agent is down",
+			conns:  2,
+			agents: []string{agents[1], closed.URL},
+			err:    closed.URL,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			benchmark, err := NewBenchmark(&BenchmarkParameters{
+				URL:             server.URL,
+				Method:          "GET",
+				ConcurrentConns: tc.conns,
+				Duration:        time.Minute,
+			})
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			_, err = benchmark.StartDistributed(context.Background(), tc.agents)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error with %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestMergeProgress(t *testing.T) {
+	agents := []Progress{
+		{Elapsed: time.Second, Remaining: 2 * time.Second, Stage: 1, ReqCount: 10, SuccessReq: 9, FailReq: 1, ReqPerSec: 10, P99ReqTime: 5 * time.Millisecond},
+		{Elapsed: 2 * time.Second, Remaining: time.Second, Stage: 2, ReqCount: 20, SuccessReq: 20, ReqPerSec: 15.5, P99ReqTime: 3 * time.Millisecond},
+	}
+
+	expected := Progress{Elapsed: 2 * time.Second, Remaining: 2 * time.Second, Stage: 2, ReqCount: 30, SuccessReq: 29, FailReq: 1, ReqPerSec: 25.5, P99ReqTime: 5 * time.Millisecond}
+	if p := mergeProgress(agents); p != expected {
+		t.Errorf("Merged progress should be %+v but it is %+v", expected, p)
+	}
+}
@@ -0,0 +1,172 @@
+package katyusha
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Extract stores a value from the response of a step in a variable used by the next steps.
+// Exactly one of JSON, Regex, Header and Cookie has to be set.
+type Extract struct {
+	Variable string `mapstructure:"var" json:"var"`
+
+	JSON   string `mapstructure:"json" json:"json,omitempty"`     // Dot separated path, array elements are selected by index e.g. data.items.0.id
+	Regex  string `mapstructure:"regex" json:"regex,omitempty"`   // Value of the first group or the whole match when regex has no groups
+	Header string `mapstructure:"header" json:"header,omitempty"` // Response header name
+	Cookie string `mapstructure:"cookie" json:"cookie,omitempty"` // Response cookie name
+}
+
+func (e Extract) String() string {
+	switch {
+	case e.JSON != "":
+		return fmt.Sprintf("%s from json %s", e.Variable, e.JSON)
+	case e.Regex != "":
+		return fmt.Sprintf("%s from regex %s", e.Variable, e.Regex)
+	case e.Header != "":
+		return fmt.Sprintf("%s from header %s", e.Variable, e.Header)
+	}
+
+	return fmt.Sprintf("%s from cookie %s", e.Variable, e.Cookie)
+}
+
+// extractor is compiled Extract
+type extractor struct {
+	variable string
+	jsonPath []string
+	regex    *regexp.Regexp
+	header   string
+	cookie   string
+}
+
+func newExtractor(e Extract) (*extractor, error) {
+	if e.Variable == "" {
+		return nil, fmt.Errorf("Extract has no variable name")
+	}
+
+	// Generator would be used instead of the variable in templates
+	if _, ok := generators[e.Variable]; ok {
+		return nil, fmt.Errorf("Extract variable %s has name of template function, rename the variable", e.Variable)
+	}
+
+	var set int
+	for _, source := range []string{e.JSON, e.Regex, e.Header, e.Cookie} {
+		if source != "" {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("Extract of %s needs exactly one of json, regex, header or cookie", e.Variable)
+	}
+
+	ex := &extractor{
+		variable: e.Variable,
+		header:   e.Header,
+		cookie:   e.Cookie,
+	}
+
+	if e.JSON != "" {
+		ex.jsonPath = strings.Split(e.JSON, ".")
+	}
+
+	if e.Regex != "" {
+		r, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("Can't compile regex of %s: %w", e.Variable, err)
+		}
+
+		ex.regex = r
+	}
+
+	return ex, nil
+}
+
+// extractValues sets variables from the response.
+// Response body is decoded from JSON only once for all JSON extractors.
+func extractValues(extractors []*extractor, resp *fasthttp.Response, vars map[string]string) error {
+	var doc interface{}
+	var decoded bool
+
+	for _, ex := range extractors {
+		var value string
+		var ok bool
+
+		switch {
+		case ex.jsonPath != nil:
+			if !decoded {
+				decoded = true
+				if err := decodeJSON(resp.Body(), &doc); err != nil {
+					doc = nil
+				}
+			}
+
+			value, ok = jsonValue(doc, ex.jsonPath)
+		case ex.regex != nil:
+			matches := ex.regex.FindSubmatch(resp.Body())
+			if len(matches) > 1 {
+				value, ok = string(matches[1]), true
+			} else if len(matches) == 1 {
+				value, ok = string(matches[0]), true
+			}
+		case ex.header != "":
+			h := resp.Header.Peek(ex.header)
+			value, ok = string(h), len(h) > 0
+		default:
+			c := fasthttp.AcquireCookie()
+			c.SetKey(ex.cookie)
+			if resp.Header.Cookie(c) {
+				value, ok = string(c.Value()), true
+			}
+			fasthttp.ReleaseCookie(c)
+		}
+
+		if !ok {
+			return fmt.Errorf("Can't extract %s", ex.variable)
+		}
+
+		vars[ex.variable] = value
+	}
+
+	return nil
+}
+
+// jsonValue returns value at path in JSON document decoded by decodeJSON.
+// Numbers are returned as written in the document, null is not a value.
+func jsonValue(doc interface{}, path []string) (string, bool) {
+	value, ok := jsonLookup(doc, path)
+	if !ok || value == nil {
+		return "", false
+	}
+
+	// Objects and arrays are used as JSON
+	return jsonString(value), true
+}
+
+// jsonLookup returns value at path in decoded JSON document, it is nil for null.
+// It returns false when the path does not exist.
+func jsonLookup(doc interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			value, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			doc = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
@@ -0,0 +1,41 @@
+package katyusha
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONValue(t *testing.T) {
+	var doc interface{}
+	body := `{"data": {"id": 12, "order": 9007199254740993, "price": 1.50, "big": 1e400, "ids": [9007199254740993], "active": true, "items": [{"name": "a"}, {"name": "b"}], "tags": ["x"], "none": null}}`
+	if err := decodeJSON([]byte(body), &doc); err != nil {
+		t.Fatalf("Can't decode JSON: %v", err)
+	}
+
+	tt := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"data.id", "12", true},
+		{"data.order", "9007199254740993", true},
+		{"data.price", "1.50", true},
+		{"data.big", "1e400", true},
+		{"data.ids", "[9007199254740993]", true},
+		{"data.active", "true", true},
+		{"data.items.1.name", "b", true},
+		{"data.tags", `["x"]`, true},
+		{"data.items.2.name", "", false},
+		{"data.items.name", "", false},
+		{"data.none", "", false},
+		{"data.missing", "", false},
+		{"data.id.value", "", false},
+	}
+
+	for _, tc := range tt {
+		value, ok := jsonValue(doc, strings.Split(tc.path, "."))
+		if ok != tc.ok || value != tc.expected {
+			t.Errorf("Path %s should return %q %t but it returned %q %t", tc.path, tc.expected, tc.ok, value, ok)
+		}
+	}
+}
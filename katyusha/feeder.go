@@ -0,0 +1,296 @@
+package katyusha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feeder strategies
+const (
+	FeederSequential = "sequential" // Rows in file order
+	FeederRandom     = "random"     // Random row for every request, it is never exhausted
+	FeederUnique     = "unique"     // Every row is used by one request only, in random order
+)
+
+// Feeder behavior when all rows were used
+const (
+	FeederWrap = "wrap" // Start from the first row again
+	FeederStop = "stop" // Stop the benchmark
+)
+
+// Feeder reads request variables from CSV or JSONL file.
+// Columns of CSV header or keys of JSON objects are available in templates as ${column}.
+type Feeder struct {
+	File      string `mapstructure:"file" json:"file"`
+	Format    string `mapstructure:"format" json:"format"`       // csv or jsonl, taken from the file extension when not set
+	Strategy  string `mapstructure:"strategy" json:"strategy"`   // sequential (default), random or unique
+	Exhausted string `mapstructure:"exhausted" json:"exhausted"` // wrap (default) or stop
+
+	// Rows of unique feeder are split between agents of distributed benchmark.
+	// Agent uses every Parts-th row starting with row Part.
+	Part  int `mapstructure:"-" json:"part,omitempty"`
+	Parts int `mapstructure:"-" json:"parts,omitempty"`
+}
+
+func (f Feeder) String() string {
+	strategy, exhausted := f.Strategy, f.Exhausted
+	if strategy == "" {
+		strategy = FeederSequential
+	}
+
+	if exhausted == "" {
+		exhausted = FeederWrap
+	}
+
+	return fmt.Sprintf("%s (%s, exhausted %s)", f.File, strategy, exhausted)
+}
+
+// feeder gives rows to requests.
+// It is used only by the goroutine dispatching requests so it does not need locking.
+type feeder struct {
+	columns []string
+	rows    [][]string
+
+	strategy string
+	stop     bool
+
+	order []int // Order of rows in the current pass
+	pos   int
+}
+
+// newFeeder loads all rows of the feeder file
+func newFeeder(f *Feeder) (*feeder, error) {
+	strategy := f.Strategy
+	if strategy == "" {
+		strategy = FeederSequential
+	}
+
+	switch strategy {
+	case FeederSequential, FeederRandom, FeederUnique:
+	default:
+		return nil, fmt.Errorf("Unknown feeder strategy %s, use sequential, random or unique", f.Strategy)
+	}
+
+	switch f.Exhausted {
+	case "", FeederWrap, FeederStop:
+	default:
+		return nil, fmt.Errorf("Unknown feeder exhausted option %s, use wrap or stop", f.Exhausted)
+	}
+
+	format := f.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(f.File)), ".")
+	}
+
+	file, err := os.Open(f.File)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open feeder file: %w", err)
+	}
+	defer file.Close()
+
+	var columns []string
+	var rows [][]string
+
+	switch format {
+	case "csv":
+		columns, rows, err = readCSV(file)
+	case "jsonl":
+		columns, rows, err = readJSONL(file)
+	default:
+		return nil, fmt.Errorf("Unknown feeder format %s, use csv or jsonl", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read feeder file %s: %w", f.File, err)
+	}
+
+	// Generator would be used instead of the column in templates
+	for _, column := range columns {
+		if _, ok := generators[column]; ok {
+			return nil, fmt.Errorf("Feeder file %s column %s has name of template function, rename the column", f.File, column)
+		}
+	}
+
+	if strategy == FeederUnique && f.Parts > 1 {
+		rows = partRows(rows, f.Part, f.Parts)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Feeder file %s has no rows", f.File)
+	}
+
+	fd := &feeder{
+		columns:  columns,
+		rows:     rows,
+		strategy: strategy,
+		stop:     f.Exhausted == FeederStop,
+	}
+	fd.newPass()
+
+	return fd, nil
+}
+
+// partRows returns every n-th row starting with row part
+func partRows(rows [][]string, part, n int) [][]string {
+	var result [][]string
+	for i := part; i < len(rows); i += n {
+		result = append(result, rows[i])
+	}
+
+	return result
+}
+
+// newPass prepares order of rows
+func (f *feeder) newPass() {
+	f.pos = 0
+	if f.order == nil {
+		f.order = make([]int, len(f.rows))
+		for i := range f.order {
+			f.order[i] = i
+		}
+	}
+
+	if f.strategy == FeederUnique {
+		rand.Shuffle(len(f.order), func(i, j int) {
+			f.order[i], f.order[j] = f.order[j], f.order[i]
+		})
+	}
+}
+
+// next returns values of the next row, it returns false when rows are exhausted and feeder should stop
+func (f *feeder) next() ([]string, bool) {
+	if f.strategy == FeederRandom {
+		return f.rows[rand.Intn(len(f.rows))], true
+	}
+
+	if f.pos == len(f.order) {
+		if f.stop {
+			return nil, false
+		}
+
+		f.newPass()
+	}
+
+	row := f.rows[f.order[f.pos]]
+	f.pos++
+
+	return row, true
+}
+
+// set adds row values to variables
+func (f *feeder) set(row []string, vars map[string]string) {
+	for i, column := range f.columns {
+		vars[column] = row[i]
+	}
+}
+
+// readCSV reads CSV file with header
+func readCSV(r io.Reader) ([]string, [][]string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("No header")
+	}
+
+	columns := records[0]
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	return columns, records[1:], nil
+}
+
+// readJSONL reads file with JSON object in every line.
+// Columns are keys of all objects, missing values are empty.
+func readJSONL(r io.Reader) ([]string, [][]string, error) {
+	var objects []map[string]interface{}
+	keys := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := decodeJSON([]byte(text), &object); err != nil {
+			return nil, nil, fmt.Errorf("Line %d: %w", line, err)
+		}
+
+		for key := range object {
+			keys[key] = true
+		}
+
+		objects = append(objects, object)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	columns := make([]string, 0, len(keys))
+	for key := range keys {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+
+	rows := make([][]string, len(objects))
+	for i, object := range objects {
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			rows[i][j] = jsonString(object[column])
+		}
+	}
+
+	return columns, rows, nil
+}
+
+// decodeJSON decodes data like json.Unmarshal but numbers are kept as json.Number,
+// so IDs above 2^53 are not rounded to float64
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("Invalid data after JSON value")
+	}
+
+	return nil
+}
+
+// jsonString returns JSON value as string, strings are not quoted and numbers are kept as written
+func jsonString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,249 @@
+package katyusha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeFeederFile writes content to the file in dir
+func writeFeederFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Can't write feeder file: %v", err)
+	}
+
+	return path
+}
+
+func TestFeederFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tt := []struct {
+		name    string
+		feeder  Feeder
+		columns []string
+		rows    [][]string
+	}{
+		{
+			name:    "csv",
+			feeder:  Feeder{File: writeFeederFile(t, dir, "users.csv", "user, term\n1,shoes\n2,\"red, hat\"\n")},
+			columns: []string{"user", "term"},
+			rows:    [][]string{{"1", "shoes"}, {"2", "red, hat"}},
+		},
+		{
+			name: "jsonl",
+			feeder: Feeder{File: writeFeederFile(t, dir, "users.jsonl",
+				"{\"user\": 1, \"term\": \"shoes\", \"vip\": true}\n\n{\"user\": 2.5, \"tags\": [\"a\"]}\n")},
+			columns: []string{"tags", "term", "user", "vip"},
+			rows:    [][]string{{"", "shoes", "1", "true"}, {`["a"]`, "", "2.5", ""}},
+		},
+		{
+			name: "jsonl numbers",
+			feeder: Feeder{File: writeFeederFile(t, dir, "ids.jsonl",
+				"{\"id\": 9007199254740993, \"price\": 1.50, \"big\": 1e400, \"nested\": {\"id\": 9007199254740993}}\n")},
+			columns: []string{"big", "id", "nested", "price"},
+			rows:    [][]string{{"1e400", "9007199254740993", `{"id":9007199254740993}`, "1.50"}},
+		},
+		{
+			name:    "format option",
+			feeder:  Feeder{File: writeFeederFile(t, dir, "users.txt", "user\n1\n"), Format: "csv"},
+			columns: []string{"user"},
+			rows:    [][]string{{"1"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newFeeder(&tc.feeder)
+			if err != nil {
+				t.Fatalf("Can't create feeder: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.columns, f.columns); diff != "" {
+				t.Errorf("Columns mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.rows, f.rows); diff != "" {
+				t.Errorf("Rows mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFeederStrategies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := writeFeederFile(t, dir, "users.csv", "user\n1\n2\n3\n")
+
+	// rows returns user column of n rows, it stops when feeder is exhausted
+	rows := func(f *feeder, n int) []string {
+		var users []string
+		for i := 0; i < n; i++ {
+			row, ok := f.next()
+			if !ok {
+				break
+			}
+
+			users = append(users, row[0])
+		}
+
+		return users
+	}
+
+	f, err := newFeeder(&Feeder{File: file})
+	if err != nil {
+		t.Fatalf("Can't create feeder: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"1", "2", "3", "1", "2"}, rows(f, 5)); diff != "" {
+		t.Errorf("Sequential feeder should wrap (-want +got):\n%s", diff)
+	}
+
+	f, err = newFeeder(&Feeder{File: file, Strategy: FeederSequential, Exhausted: FeederStop})
+	if err != nil {
+		t.Fatalf("Can't create feeder: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"1", "2", "3"}, rows(f, 5)); diff != "" {
+		t.Errorf("Sequential feeder should stop (-want +got):\n%s", diff)
+	}
+
+	f, err = newFeeder(&Feeder{File: file, Strategy: FeederUnique, Exhausted: FeederStop})
+	if err != nil {
+		t.Fatalf("Can't create feeder: %v", err)
+	}
+
+	unique := rows(f, 5)
+	if len(unique) != 3 || !cmp.Equal(map[string]bool{"1": true, "2": true, "3": true},
+		map[string]bool{unique[0]: true, unique[1]: true, unique[2]: true}) {
+		t.Errorf("Unique feeder should use every row once: %v", unique)
+	}
+
+	f, err = newFeeder(&Feeder{File: file, Strategy: FeederRandom, Exhausted: FeederStop})
+	if err != nil {
+		t.Fatalf("Can't create feeder: %v", err)
+	}
+
+	if random := rows(f, 100); len(random) != 100 {
+		t.Errorf("Random feeder should not be exhausted: %d rows", len(random))
+	}
+}
+
+func TestFeederRequests(t *testing.T) {
+	var mu sync.Mutex
+	users := make(map[string]int)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		users[strings.TrimPrefix(r.URL.Path, "/users/")+" "+r.Header.Get("X-Term")]++
+		mu.Unlock()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var content strings.Builder
+	content.WriteString("user,term\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&content, "%d,term%d\n", i, i)
+	}
+
+	req := &BenchmarkParameters{
+		URL:             server.URL + "/users/${user}",
+		ConcurrentConns: 4,
+		ReqCount:        100,
+		Headers:         headers{"X-Term": "${term}"},
+		Feeder: &Feeder{
+			File:      writeFeederFile(t, dir, "users.csv", content.String()),
+			Strategy:  FeederUnique,
+			Exhausted: FeederStop,
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.ReqCount != 50 {
+		t.Errorf("Benchmark should stop after 50 rows but it sent %d requests", summary.ReqCount)
+	}
+
+	if len(users) != 50 {
+		t.Errorf("Every row should be requested once: %v", users)
+	}
+
+	for i := 0; i < 50; i++ {
+		if key := fmt.Sprintf("%d term%d", i, i); users[key] != 1 {
+			t.Errorf("Row %s requested %d times", key, users[key])
+		}
+	}
+}
+
+func TestInvalidFeeder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := writeFeederFile(t, dir, "users.csv", "user\n1\n")
+
+	tt := []struct {
+		name   string
+		url    string
+		feeder *Feeder
+	}{
+		{"Missing file", "http://katyusha.test", &Feeder{File: filepath.Join(dir, "missing.csv")}},
+		{"Unknown format", "http://katyusha.test", &Feeder{File: file, Format: "xml"}},
+		{"Unknown strategy", "http://katyusha.test", &Feeder{File: file, Strategy: "shuffle"}},
+		{"Unknown exhausted", "http://katyusha.test", &Feeder{File: file, Exhausted: "restart"}},
+		{"No rows", "http://katyusha.test", &Feeder{File: writeFeederFile(t, dir, "empty.csv", "user\n")}},
+		{"Invalid JSON", "http://katyusha.test", &Feeder{File: writeFeederFile(t, dir, "users.jsonl", "{\"user\": 1\n")}},
+		{"Trailing JSON", "http://katyusha.test", &Feeder{File: writeFeederFile(t, dir, "trailing.jsonl", "{\"user\": 1} 2\n")}},
+		{"Unknown column", "http://katyusha.test/${term}", &Feeder{File: file}},
+		{"Generator column", "http://katyusha.test/${uuid}", &Feeder{File: writeFeederFile(t, dir, "ids.csv", "user,uuid\n1,2\n")}},
+		{"Generator key", "http://katyusha.test/${timestamp}", &Feeder{File: writeFeederFile(t, dir, "times.jsonl", "{\"timestamp\": 1}\n")}},
+		{"Column without feeder", "http://katyusha.test/${user}", nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &BenchmarkParameters{
+				URL:      tc.url,
+				ReqCount: 1,
+				Feeder:   tc.feeder,
+			}
+
+			if _, err := NewBenchmark(req); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
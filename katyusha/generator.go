@@ -0,0 +1,214 @@
+package katyusha
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// generator appends generated value to dst
+type generator func(dst []byte) []byte
+
+const (
+	randStringLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	hexDigits         = "0123456789abcdef"
+)
+
+// uuidGroups is the number of hex digits in each group of UUID
+var uuidGroups = [...]int{8, 4, 4, 4, 12}
+
+// generators create generator from arguments of template function.
+// Functions without arguments can be used without parentheses e.g. ${uuid}.
+var generators = map[string]func(args []string) (generator, error){
+	"randInt":    newRandIntGenerator,
+	"uuid":       newUUIDGenerator,
+	"timestamp":  newTimestampGenerator,
+	"seq":        newSeqGenerator,
+	"randString": newRandStringGenerator,
+	"pick":       newPickGenerator,
+}
+
+// parseGenerator returns generator for expression like randInt(1, 100).
+// It returns nil generator when expression is a variable name.
+func parseGenerator(expression string) (generator, error) {
+	name, args := expression, []string(nil)
+
+	if open := strings.Index(expression, "("); open >= 0 {
+		if !strings.HasSuffix(expression, ")") {
+			return nil, fmt.Errorf("Missing ) in %s", expression)
+		}
+
+		name = strings.TrimSpace(expression[:open])
+		if inner := strings.TrimSpace(expression[open+1 : len(expression)-1]); inner != "" {
+			for _, arg := range strings.Split(inner, ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		}
+
+		if _, ok := generators[name]; !ok {
+			return nil, fmt.Errorf("Unknown function %s", name)
+		}
+	}
+
+	newGenerator, ok := generators[name]
+	if !ok {
+		return nil, nil
+	}
+
+	g, err := newGenerator(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return g, nil
+}
+
+// newRandIntGenerator returns random integers from min to max inclusive, randInt(min, max)
+func newRandIntGenerator(args []string) (generator, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("Needs min and max arguments")
+	}
+
+	min, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if max < min {
+		return nil, fmt.Errorf("Max %d is lower than min %d", max, min)
+	}
+
+	// Span of wide ranges like randInt(0, 9223372036854775807) overflows int64,
+	// it is 0 for the full int64 range
+	span := uint64(max) - uint64(min) + 1
+	if span > 0 && span <= math.MaxInt64 {
+		return func(dst []byte) []byte {
+			return strconv.AppendInt(dst, min+rand.Int63n(int64(span)), 10)
+		}, nil
+	}
+
+	return func(dst []byte) []byte {
+		v := rand.Uint64()
+		// Values out of span are drawn again, at least half of values are in span
+		for span > 0 && v >= span {
+			v = rand.Uint64()
+		}
+
+		return strconv.AppendInt(dst, int64(uint64(min)+v), 10)
+	}, nil
+}
+
+// newUUIDGenerator returns random version 4 UUIDs
+func newUUIDGenerator(args []string) (generator, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("Takes no arguments")
+	}
+
+	return func(dst []byte) []byte {
+		hi, lo := rand.Uint64(), rand.Uint64()
+		hi = hi&^0xf000 | 0x4000     // Version 4
+		lo = lo&^(0xc<<60) | 0x8<<60 // Variant RFC 4122
+		for i, group := range uuidGroups {
+			if i > 0 {
+				dst = append(dst, '-')
+			}
+
+			for ; group > 0; group-- {
+				dst = append(dst, hexDigits[hi>>60])
+				hi = hi<<4 | lo>>60
+				lo <<= 4
+			}
+		}
+
+		return dst
+	}, nil
+}
+
+// newTimestampGenerator returns current time, timestamp(unit) where unit is s (default), ms, ns or rfc3339
+func newTimestampGenerator(args []string) (generator, error) {
+	unit := "s"
+	if len(args) > 1 {
+		return nil, fmt.Errorf("Takes one unit argument")
+	} else if len(args) == 1 {
+		unit = args[0]
+	}
+
+	switch unit {
+	case "s":
+		return func(dst []byte) []byte { return strconv.AppendInt(dst, time.Now().Unix(), 10) }, nil
+	case "ms":
+		return func(dst []byte) []byte {
+			return strconv.AppendInt(dst, time.Now().UnixNano()/int64(time.Millisecond), 10)
+		}, nil
+	case "ns":
+		return func(dst []byte) []byte { return strconv.AppendInt(dst, time.Now().UnixNano(), 10) }, nil
+	case "rfc3339":
+		return func(dst []byte) []byte { return time.Now().AppendFormat(dst, time.RFC3339) }, nil
+	}
+
+	return nil, fmt.Errorf("Unknown unit %s, use s, ms, ns or rfc3339", unit)
+}
+
+// newSeqGenerator returns numbers increasing with every request, seq(start) starts from 1 by default.
+// Each ${seq} reference has its own counter shared by all connections.
+func newSeqGenerator(args []string) (generator, error) {
+	start := int64(1)
+	if len(args) > 1 {
+		return nil, fmt.Errorf("Takes one start argument")
+	} else if len(args) == 1 {
+		var err error
+		start, err = strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counter := start - 1
+	return func(dst []byte) []byte {
+		return strconv.AppendInt(dst, atomic.AddInt64(&counter, 1), 10)
+	}, nil
+}
+
+// newRandStringGenerator returns random alphanumeric strings, randString(length)
+func newRandStringGenerator(args []string) (generator, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("Needs length argument")
+	}
+
+	length, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if length <= 0 {
+		return nil, fmt.Errorf("Length has to be positive")
+	}
+
+	return func(dst []byte) []byte {
+		for i := 0; i < length; i++ {
+			dst = append(dst, randStringLetters[rand.Intn(len(randStringLetters))])
+		}
+
+		return dst
+	}, nil
+}
+
+// newPickGenerator returns random value from the arguments, pick(a, b, c)
+func newPickGenerator(args []string) (generator, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Needs at least one value")
+	}
+
+	return func(dst []byte) []byte {
+		return append(dst, args[rand.Intn(len(args))]...)
+	}, nil
+}
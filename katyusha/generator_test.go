@@ -0,0 +1,139 @@
+package katyusha
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGenerators(t *testing.T) {
+	tt := []struct {
+		expression string
+		match      string
+	}{
+		{"randInt(5, 7)", `^[5-7]$`},
+		{"randInt(-3,-3)", `^-3$`},
+		{"uuid", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"uuid()", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"timestamp", `^\d{10}$`},
+		{"timestamp(ms)", `^\d{13}$`},
+		{"timestamp(ns)", `^\d{19}$`},
+		{"timestamp(rfc3339)", `^\d{4}-\d{2}-\d{2}T`},
+		{"randString(12)", `^[a-zA-Z0-9]{12}$`},
+		{"pick(GET, POST,PUT)", `^(GET|POST|PUT)$`},
+	}
+
+	for _, tc := range tt {
+		g, err := parseGenerator(tc.expression)
+		if err != nil || g == nil {
+			t.Errorf("Can't parse generator %s: %v", tc.expression, err)
+			continue
+		}
+
+		r := regexp.MustCompile(tc.match)
+		for i := 0; i < 100; i++ {
+			if value := string(g(nil)); !r.MatchString(value) {
+				t.Errorf("Generator %s value %q does not match %s", tc.expression, value, tc.match)
+				break
+			}
+		}
+	}
+}
+
+func TestRandIntBoundaries(t *testing.T) {
+	tt := []struct {
+		expression string
+		min, max   int64
+	}{
+		{"randInt(0, 9223372036854775807)", 0, math.MaxInt64},
+		{"randInt(-9223372036854775808, 9223372036854775807)", math.MinInt64, math.MaxInt64},
+		{"randInt(-9223372036854775808, 0)", math.MinInt64, 0},
+		{"randInt(-1, 9223372036854775807)", -1, math.MaxInt64},
+		{"randInt(9223372036854775807, 9223372036854775807)", math.MaxInt64, math.MaxInt64},
+		{"randInt(-9223372036854775808, -9223372036854775808)", math.MinInt64, math.MinInt64},
+	}
+
+	for _, tc := range tt {
+		g, err := parseGenerator(tc.expression)
+		if err != nil {
+			t.Errorf("Can't parse generator %s: %v", tc.expression, err)
+			continue
+		}
+
+		for i := 0; i < 1000; i++ {
+			value, err := strconv.ParseInt(string(g(nil)), 10, 64)
+			if err != nil || value < tc.min || value > tc.max {
+				t.Errorf("Generator %s value %d is out of range: %v", tc.expression, value, err)
+				break
+			}
+		}
+	}
+}
+
+func TestSeqGenerator(t *testing.T) {
+	g, err := parseGenerator("seq(10)")
+	if err != nil {
+		t.Fatalf("Can't parse generator: %v", err)
+	}
+
+	for i := 10; i < 15; i++ {
+		if value := string(g(nil)); value != strconv.Itoa(i) {
+			t.Errorf("Sequence value should be %d but it is %s", i, value)
+		}
+	}
+
+	// Each reference has its own counter
+	g, _ = parseGenerator("seq")
+	if value := string(g(nil)); value != "1" {
+		t.Errorf("Sequence should start from 1 but it started from %s", value)
+	}
+}
+
+func TestInvalidGenerators(t *testing.T) {
+	for _, expression := range []string{
+		"randInt(1)", "randInt(a, 2)", "randInt(5, 1)", "uuid(1)", "timestamp(h)", "seq(a)",
+		"randString", "randString(0)", "pick()", "unknown(1)", "randInt(1, 2",
+	} {
+		if _, err := parseGenerator(expression); err == nil {
+			t.Errorf("Generator %s should not be parsed", expression)
+		}
+	}
+
+	g, err := parseGenerator("token")
+	if err != nil || g != nil {
+		t.Errorf("Variable name should not be parsed as generator")
+	}
+}
+
+func TestTemplateAllocations(t *testing.T) {
+	tmpl, err := compileTemplate(`{"id": "${uuid}", "n": ${randInt(1, 1000)}, "seq": ${seq}, "at": ${timestamp(ms)}, "s": "${randString(8)}", "p": "${pick(a, b)}", "user": "${user}"}`)
+	if err != nil {
+		t.Fatalf("Can't compile template: %v", err)
+	}
+
+	vars := map[string]string{"user": "katyusha"}
+	buf := make([]byte, 0, 256)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		buf = tmpl.appendTo(buf[:0], vars)
+	})
+
+	if allocs != 0 {
+		t.Errorf("Rendering template should not allocate but it made %v allocations", allocs)
+	}
+
+	if _, err := time.Parse(time.RFC3339, string(mustGenerate(t, "timestamp(rfc3339)"))); err != nil {
+		t.Errorf("Timestamp should be in RFC3339 format: %v", err)
+	}
+}
+
+func mustGenerate(t *testing.T, expression string) []byte {
+	g, err := parseGenerator(expression)
+	if err != nil {
+		t.Fatalf("Can't parse generator %s: %v", expression, err)
+	}
+
+	return g(nil)
+}
@@ -0,0 +1,431 @@
+package katyusha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPC configures benchmark of gRPC method.
+// URL is grpc://host:port for plaintext or grpcs://host:port for TLS connection and Headers are sent as metadata.
+type GRPC struct {
+	Method        string `mapstructure:"method" json:"method"`                           // Fully qualified method, package.Service/Method
+	Payload       string `mapstructure:"payload" json:"payload,omitempty"`               // Request message in JSON, JSON array of messages for client streaming
+	DescriptorSet string `mapstructure:"descriptor_set" json:"descriptor_set,omitempty"` // FileDescriptorSet file, server reflection is used when not set
+}
+
+func (g GRPC) String() string {
+	if g.DescriptorSet == "" {
+		return fmt.Sprintf("%s %s (reflection)", g.Method, g.Payload)
+	}
+
+	return fmt.Sprintf("%s %s (%s)", g.Method, g.Payload, g.DescriptorSet)
+}
+
+// grpcMethod is compiled GRPC configuration shared by workers
+type grpcMethod struct {
+	target  string
+	tls     bool
+	name    protoreflect.FullName // Method name used to find its descriptor
+	path    string                // Method path used in calls, /package.Service/Method
+	payload *template
+	timeout time.Duration
+
+	// Method descriptor is resolved with server reflection when there is no descriptor set.
+	// Only the resolved descriptor is kept, calls retry reflection until the server answers.
+	mu   sync.Mutex
+	desc protoreflect.MethodDescriptor
+}
+
+// newGRPCMethod validates GRPC configuration, payload can use feeder columns
+func newGRPCMethod(b *BenchmarkParameters, columns []string) (*grpcMethod, error) {
+	u, err := url.Parse(b.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "grpc" && u.Scheme != "grpcs" {
+		return nil, fmt.Errorf("gRPC requires grpc or grpcs URL: %s", b.URL)
+	}
+
+	if u.Port() == "" {
+		return nil, fmt.Errorf("gRPC URL has no port: %s", b.URL)
+	}
+
+	if len(b.Scenario) > 0 {
+		return nil, fmt.Errorf("gRPC benchmark can't have scenario")
+	}
+
+	if b.Protocol != "" && b.Protocol != ProtocolHTTP1 {
+		return nil, fmt.Errorf("gRPC benchmark can't use protocol %s", b.Protocol)
+	}
+
+	if len(b.Assertions) > 0 || len(b.ExpectedStatus) > 0 {
+		return nil, fmt.Errorf("gRPC benchmark can't have assertions or expected status, calls are successful with OK status")
+	}
+
+	if b.WebSocket != nil {
+		return nil, fmt.Errorf("Benchmark can't use both gRPC and WebSocket")
+	}
+
+	service, method, err := splitGRPCMethod(b.GRPC.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := compileTemplate(b.GRPC.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC payload: %w", err)
+	}
+
+	if v := payload.unknownVariable(columns); v != "" {
+		return nil, fmt.Errorf("Unknown variable %s, gRPC payload variables are set by feeder columns", v)
+	}
+
+	g := &grpcMethod{
+		target:  u.Host,
+		tls:     u.Scheme == "grpcs",
+		name:    protoreflect.FullName(service + "." + method),
+		path:    "/" + service + "/" + method,
+		payload: payload,
+		timeout: b.ReadTimeout,
+	}
+
+	if b.GRPC.DescriptorSet != "" {
+		files, err := readDescriptorSet(b.GRPC.DescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.desc, err = findMethod(files, g.name); err != nil {
+			return nil, err
+		}
+
+		if payload.static() {
+			if _, err := g.messages([]byte(payload.text)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// splitGRPCMethod returns service and method of package.Service/Method
+func splitGRPCMethod(name string) (string, string, error) {
+	name = strings.TrimPrefix(name, "/")
+
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		i = strings.LastIndex(name, ".")
+	}
+
+	if i <= 0 || i == len(name)-1 {
+		return "", "", fmt.Errorf("gRPC method %s is not fully qualified, use package.Service/Method", name)
+	}
+
+	return name[:i], name[i+1:], nil
+}
+
+// readDescriptorSet reads FileDescriptorSet created by protoc --descriptor_set_out --include_imports
+func readDescriptorSet(file string) (*protoregistry.Files, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading descriptor set %s: %w", file, err)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Can't parse descriptor set %s: %w", file, err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("Can't load descriptor set %s: %w", file, err)
+	}
+
+	return files, nil
+}
+
+// findMethod returns descriptor of the method
+func findMethod(files *protoregistry.Files, name protoreflect.FullName) (protoreflect.MethodDescriptor, error) {
+	d, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC method %s not found: %w", name, err)
+	}
+
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not gRPC method", name)
+	}
+
+	return md, nil
+}
+
+// method returns the method descriptor, it is resolved with server reflection when it is not known
+func (g *grpcMethod) method(conn *grpc.ClientConn) (protoreflect.MethodDescriptor, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Method of descriptor set is found before workers start
+	if g.desc != nil {
+		return g.desc, nil
+	}
+
+	files, err := reflectFiles(conn, g.name.Parent())
+	if err != nil {
+		return nil, fmt.Errorf("Server reflection: %w", err)
+	}
+
+	md, err := findMethod(files, g.name)
+	if err != nil {
+		return nil, err
+	}
+
+	g.desc = md
+	return md, nil
+}
+
+// reflectFiles asks the server for file of the service and its dependencies
+func reflectFiles(conn *grpc.ClientConn, service protoreflect.FullName) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	set := &descriptorpb.FileDescriptorSet{}
+	known := make(map[string]bool)
+
+	requests := []*rpb.ServerReflectionRequest{
+		{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: string(service)}},
+	}
+
+	for len(requests) > 0 {
+		req := requests[0]
+		requests = requests[1:]
+
+		// Dependencies can be sent with the file which imports them
+		if known[req.GetFileByFilename()] {
+			continue
+		}
+
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, errors.New(e.ErrorMessage)
+		}
+
+		for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, file); err != nil {
+				return nil, err
+			}
+
+			if known[file.GetName()] {
+				continue
+			}
+			known[file.GetName()] = true
+			set.File = append(set.File, file)
+
+			for _, dep := range file.GetDependency() {
+				requests = append(requests, &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				})
+			}
+		}
+	}
+
+	return protodesc.NewFiles(set)
+}
+
+// messages parses request messages of one call from payload
+func (g *grpcMethod) messages(payload []byte) ([]proto.Message, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 {
+		return []proto.Message{dynamicpb.NewMessage(g.desc.Input())}, nil
+	}
+
+	raw := []json.RawMessage{payload}
+	if g.desc.IsStreamingClient() && payload[0] == '[' {
+		if err := json.Unmarshal(payload, &raw); err != nil {
+			return nil, fmt.Errorf("Invalid gRPC payload: %w", err)
+		}
+	}
+
+	messages := make([]proto.Message, len(raw))
+	for i, r := range raw {
+		m := dynamicpb.NewMessage(g.desc.Input())
+		if err := protojson.Unmarshal(r, m); err != nil {
+			return nil, fmt.Errorf("Invalid gRPC payload: %w", err)
+		}
+
+		messages[i] = m
+	}
+
+	return messages, nil
+}
+
+// grpcClient calls the method for one worker over its own connection
+type grpcClient struct {
+	b    *Benchmark
+	g    *grpcMethod
+	conn *grpc.ClientConn
+	err  error
+}
+
+func newGRPCClient(b *Benchmark) *grpcClient {
+	c := &grpcClient{b: b, g: b.grpc}
+
+	creds := insecure.NewCredentials()
+	if c.g.tls {
+		creds = credentials.NewTLS(b.tlsConfig.Clone())
+	}
+
+	c.conn, c.err = grpc.NewClient(c.g.target, grpc.WithTransportCredentials(creds), grpc.WithUserAgent(KatyushaName))
+	return c
+}
+
+// call sends messages rendered with flow variables, the call ends when all responses were received.
+// Unary call is cancelled with ctx, streaming call with streams context so streams which never end are closed when the benchmark is over.
+func (c *grpcClient) call(ctx, streams context.Context, e *endpoint, flow *flowState) *RequestStat {
+	stat := &RequestStat{Start: time.Now()}
+
+	size, err := c.invoke(ctx, streams, e, flow)
+
+	stat.End = time.Now()
+	stat.Duration = stat.End.Sub(stat.Start)
+	stat.BodySize = size
+
+	// Errors are reported by gRPC status code like HTTP errors by status message
+	if s, ok := status.FromError(err); ok && err != nil {
+		err = errors.New(s.Code().String())
+	}
+
+	stat.Error = err
+	stat.Success = err == nil
+
+	return stat
+}
+
+// invoke calls the method and returns size of the responses
+func (c *grpcClient) invoke(ctx, streams context.Context, e *endpoint, flow *flowState) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	md, err := c.g.method(c.conn)
+	if err != nil {
+		return 0, err
+	}
+
+	flow.buf = c.g.payload.appendTo(flow.buf[:0], flow.vars)
+	messages, err := c.g.messages(flow.buf)
+	if err != nil {
+		return 0, err
+	}
+
+	streaming := md.IsStreamingClient() || md.IsStreamingServer()
+	if streaming {
+		ctx = streams
+	}
+
+	if c.g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.g.timeout)
+		defer cancel()
+	}
+
+	if len(e.headers) > 0 {
+		pairs := make([]string, 0, 2*len(e.headers))
+		for _, h := range e.headers {
+			flow.buf = h.value.appendTo(flow.buf[:0], flow.vars)
+			pairs = append(pairs, strings.ToLower(h.key), string(flow.buf))
+		}
+
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(pairs...))
+	}
+
+	if !streaming {
+		resp := dynamicpb.NewMessage(md.Output())
+		if err := c.conn.Invoke(ctx, c.g.path, messages[0], resp); err != nil {
+			return 0, err
+		}
+
+		return proto.Size(resp), nil
+	}
+
+	if !md.IsStreamingClient() && len(messages) > 1 {
+		return 0, fmt.Errorf("Method %s accepts one message", c.g.name)
+	}
+
+	desc := &grpc.StreamDesc{ServerStreams: md.IsStreamingServer(), ClientStreams: md.IsStreamingClient()}
+	stream, err := c.conn.NewStream(ctx, desc, c.g.path)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range messages {
+		// Stream was closed by the server, its status is returned by RecvMsg
+		if err := stream.SendMsg(m); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return 0, err
+	}
+
+	var size int
+	for {
+		resp := dynamicpb.NewMessage(md.Output())
+		err := stream.RecvMsg(resp)
+		if err == io.EOF || err != nil && context.Cause(ctx) == errBenchmarkIsOver {
+			return size, nil
+		} else if err != nil {
+			return size, err
+		}
+
+		size += proto.Size(resp)
+	}
+}
+
+func (c *grpcClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,446 @@
+package katyusha
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testService counts messages received by the methods.
+// Calls without x-token metadata are rejected.
+// StreamingOutputCall without response parameters sends responses every 5ms until the client cancels it.
+type testService struct {
+	testpb.UnimplementedTestServiceServer
+	messages int64
+}
+
+func (s *testService) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-token")) == 0 || md.Get("x-token")[0] != "secret" {
+		return status.Error(codes.Unauthenticated, "no token")
+	}
+
+	return nil
+}
+
+func (s *testService) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt64(&s.messages, 1)
+	time.Sleep(5 * time.Millisecond)
+
+	return &testpb.SimpleResponse{Payload: &testpb.Payload{Body: make([]byte, req.ResponseSize)}}, nil
+}
+
+func (s *testService) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&s.messages, 1)
+	if len(req.ResponseParameters) == 0 {
+		for {
+			select {
+			case <-stream.Context().Done():
+				return nil
+			case <-time.After(5 * time.Millisecond):
+			}
+
+			if err := stream.Send(&testpb.StreamingOutputCallResponse{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range req.ResponseParameters {
+		err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: make([]byte, p.Size)}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *testService) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	var size int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: size})
+		} else if err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&s.messages, 1)
+		size += int32(len(req.GetPayload().GetBody()))
+	}
+}
+
+func (s *testService) FullDuplexCall(stream testpb.TestService_FullDuplexCallServer) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&s.messages, 1)
+		err = stream.Send(&testpb.StreamingOutputCallResponse{Payload: req.Payload})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// startGRPCServer starts test service with server reflection, TLS server uses the httptest server certificate
+func startGRPCServer(t *testing.T, useTLS bool) (*testService, string) {
+	var opts []grpc.ServerOption
+	scheme := "grpc"
+	if useTLS {
+		ts := httptest.NewTLSServer(http.NotFoundHandler())
+		ts.Close()
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: ts.TLS.Certificates})))
+		scheme = "grpcs"
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+
+	service := &testService{}
+	server := grpc.NewServer(opts...)
+	testpb.RegisterTestServiceServer(server, service)
+	reflection.Register(server)
+
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	return service, scheme + "://" + ln.Addr().String()
+}
+
+// writeDescriptorSet writes descriptor set of the test service with its imports
+func writeDescriptorSet(t *testing.T, dir string) string {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(testpb.File_grpc_testing_test_proto)
+
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("Can't marshal descriptor set: %v", err)
+	}
+
+	file := filepath.Join(dir, "test.protoset")
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatalf("Can't write descriptor set: %v", err)
+	}
+
+	return file
+}
+
+func TestGRPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	descriptorSet := writeDescriptorSet(t, dir)
+
+	tt := []struct {
+		name          string
+		tls           bool
+		method        string
+		payload       string
+		descriptorSet string
+		token         string
+		success       int
+		messages      int64
+		size          int // Size of responses of one call
+		errors        map[string]int
+	}{
+		{
+			name:     "Unary",
+			method:   "grpc.testing.TestService/UnaryCall",
+			payload:  `{"responseSize": 10}`,
+			token:    "secret",
+			success:  20,
+			messages: 20,
+			size:     proto.Size(&testpb.SimpleResponse{Payload: &testpb.Payload{Body: make([]byte, 10)}}),
+		},
+		{
+			name:          "Unary with descriptor set and TLS",
+			tls:           true,
+			method:        "/grpc.testing.TestService/UnaryCall",
+			payload:       `{"responseSize": ${randInt(1, 1)}}`,
+			descriptorSet: descriptorSet,
+			token:         "secret",
+			success:       20,
+			messages:      20,
+			size:          proto.Size(&testpb.SimpleResponse{Payload: &testpb.Payload{Body: make([]byte, 1)}}),
+		},
+		{
+			name:    "Unauthenticated",
+			method:  "grpc.testing.TestService.UnaryCall",
+			payload: `{"responseSize": 10}`,
+			errors:  map[string]int{"Unauthenticated": 20},
+		},
+		{
+			name:     "Server streaming",
+			method:   "grpc.testing.TestService/StreamingOutputCall",
+			payload:  `{"responseParameters": [{"size": 3}, {"size": 3}, {"size": 3}]}`,
+			token:    "secret",
+			success:  20,
+			messages: 20,
+			size:     3 * proto.Size(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: make([]byte, 3)}}),
+		},
+		{
+			name:          "Client streaming",
+			method:        "grpc.testing.TestService/StreamingInputCall",
+			payload:       `[{"payload": {"body": "YWJj"}}, {"payload": {"body": "YWJj"}}]`,
+			descriptorSet: descriptorSet,
+			token:         "secret",
+			success:       20,
+			messages:      40,
+			size:          proto.Size(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: 6}),
+		},
+		{
+			name:     "Bidirectional streaming",
+			method:   "grpc.testing.TestService/FullDuplexCall",
+			payload:  `[{"payload": {"body": "YWJj"}}, {"payload": {"body": "YWJj"}}, {"payload": {"body": "YWJj"}}]`,
+			token:    "secret",
+			success:  20,
+			messages: 60,
+			size:     3 * proto.Size(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: []byte("abc")}}),
+		},
+		{
+			name:    "Unimplemented",
+			method:  "grpc.testing.TestService/EmptyCall",
+			token:   "secret",
+			payload: "{}",
+			errors:  map[string]int{"Unimplemented": 20},
+		},
+		{
+			name:    "Unknown method",
+			method:  "grpc.testing.TestService/Unknown",
+			token:   "secret",
+			payload: "{}",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			service, url := startGRPCServer(t, tc.tls)
+
+			benchmark, err := NewBenchmark(&BenchmarkParameters{
+				URL:             url,
+				Headers:         headers{"X-Token": tc.token},
+				ConcurrentConns: 4,
+				ReqCount:        20,
+				SkipVerify:      true,
+				GRPC:            &GRPC{Method: tc.method, Payload: tc.payload, DescriptorSet: tc.descriptorSet},
+			})
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != tc.success || summary.ReqCount != 20 {
+				t.Errorf("Expected %d successful calls, got %d of %d: %v", tc.success, summary.SuccessReq, summary.ReqCount, summary.Errors)
+			}
+
+			if n := atomic.LoadInt64(&service.messages); n != tc.messages {
+				t.Errorf("Server should receive %d messages, got %d", tc.messages, n)
+			}
+
+			if summary.DataTransfered != tc.success*tc.size {
+				t.Errorf("Expected %d bytes of responses, got %d", tc.success*tc.size, summary.DataTransfered)
+			}
+
+			for e, count := range tc.errors {
+				if summary.Errors[e] != count {
+					t.Errorf("Expected %d %s errors: %v", count, e, summary.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCDuration(t *testing.T) {
+	_, url := startGRPCServer(t, false)
+
+	// Server stream never ends so it is closed when the duration is over
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             url,
+		Headers:         headers{"X-Token": "secret"},
+		ConcurrentConns: 2,
+		Duration:        200 * time.Millisecond,
+		GRPC:            &GRPC{Method: "grpc.testing.TestService/StreamingOutputCall", Payload: "{}"},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	start := time.Now()
+	summary := benchmark.StartBenchmark(context.Background())
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("Benchmark should end after its duration, it took %v", took)
+	}
+
+	if summary.SuccessReq != 2 || summary.ReqCount != 2 {
+		t.Errorf("Streams closed at the end of the benchmark should be successful, got %d of %d: %v", summary.SuccessReq, summary.ReqCount, summary.Errors)
+	}
+
+	// Cancelled benchmark closes streams as well
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	benchmark.Duration = time.Minute
+	start = time.Now()
+	benchmark.StartBenchmark(ctx)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("Cancelled benchmark should end, it took %v", took)
+	}
+}
+
+func TestGRPCReflectionRetry(t *testing.T) {
+	// Port is free until the server starts
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             "grpc://" + addr,
+		ConcurrentConns: 1,
+		ReqCount:        1,
+		GRPC:            &GRPC{Method: "grpc.testing.TestService/UnaryCall", Payload: "{}"},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	c := newGRPCClient(benchmark)
+	if c.err != nil {
+		t.Fatalf("Can't create client: %v", c.err)
+	}
+	defer c.conn.Close()
+
+	if _, err := benchmark.grpc.method(c.conn); err == nil {
+		t.Fatalf("Reflection should fail before the server starts")
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	testpb.RegisterTestServiceServer(server, &testService{})
+	reflection.Register(server)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	// Connection is dialed again after backoff
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		md, err := benchmark.grpc.method(c.conn)
+		if err == nil {
+			if md.FullName() != "grpc.testing.TestService.UnaryCall" {
+				t.Errorf("Wrong method %s", md.FullName())
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Reflection should succeed when the server is ready: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestInvalidGRPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	descriptorSet := writeDescriptorSet(t, dir)
+
+	tt := []struct {
+		name string
+		url  string
+		grpc GRPC
+	}{
+		{name: "HTTP URL", url: "http://127.0.0.1:8080", grpc: GRPC{Method: "grpc.testing.TestService/UnaryCall"}},
+		{name: "No port", url: "grpc://127.0.0.1", grpc: GRPC{Method: "grpc.testing.TestService/UnaryCall"}},
+		{name: "Method", url: "grpc://127.0.0.1:8080", grpc: GRPC{Method: "UnaryCall"}},
+		{name: "Unknown variable", url: "grpc://127.0.0.1:8080", grpc: GRPC{Method: "grpc.testing.TestService/UnaryCall", Payload: `{"username": "${user}"}`}},
+		{name: "Descriptor set file", url: "grpc://127.0.0.1:8080", grpc: GRPC{Method: "grpc.testing.TestService/UnaryCall", DescriptorSet: filepath.Join(dir, "missing")}},
+		{name: "Unknown method", url: "grpc://127.0.0.1:8080", grpc: GRPC{Method: "grpc.testing.TestService/Unknown", DescriptorSet: descriptorSet}},
+		{name: "Invalid payload", url: "grpc://127.0.0.1:8080", grpc: GRPC{Method: "grpc.testing.TestService/UnaryCall", Payload: `{"unknown": 1}`, DescriptorSet: descriptorSet}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			grpc := tc.grpc
+			_, err := NewBenchmark(&BenchmarkParameters{URL: tc.url, ReqCount: 1, GRPC: &grpc})
+			if err == nil {
+				t.Errorf("Benchmark should not be created")
+			}
+		})
+	}
+}
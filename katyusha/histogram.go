@@ -0,0 +1,240 @@
+package katyusha
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Histogram buckets grow exponentially and each of them is divided into linear sub buckets.
+// Values lower than 2^histogramSubBucketBits nanoseconds are recorded exactly, bigger values
+// are recorded with relative error lower than 1/2^histogramSubBucketBits (0.4%).
+const (
+	histogramSubBucketBits = 8
+	histogramHalfCount     = 1 << (histogramSubBucketBits - 1)
+	histogramBucketsLen    = (64-histogramSubBucketBits+1)*histogramHalfCount + histogramHalfCount
+)
+
+// timesStat describes distribution of request times
+type timesStat struct {
+	min, max, avg      time.Duration
+	p50, p75, p90, p99 time.Duration
+	stdDeviation       float64
+}
+
+// histogram records durations in fixed memory
+// Min, max, mean and standard deviation are exact, percentiles have bounded error.
+type histogram struct {
+	counts []int64
+
+	count int64
+	min   time.Duration
+	max   time.Duration
+
+	// Welford's online algorithm for mean and variance
+	mean float64
+	m2   float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{
+		counts: make([]int64, histogramBucketsLen),
+	}
+}
+
+// bucketIndex returns index of the bucket for the value
+func bucketIndex(v uint64) int {
+	msb := bits.Len64(v) - 1
+	if msb < histogramSubBucketBits {
+		return int(v)
+	}
+
+	shift := msb - histogramSubBucketBits + 1
+	return shift*histogramHalfCount + int(v>>uint(shift))
+}
+
+// bucketRange returns the lowest value and the width of the bucket
+func bucketRange(index int) (uint64, uint64) {
+	if index < 1<<histogramSubBucketBits {
+		return uint64(index), 1
+	}
+
+	shift := index/histogramHalfCount - 1
+	sub := index - shift*histogramHalfCount
+	return uint64(sub) << uint(shift), 1 << uint(shift)
+}
+
+// record adds one value to the histogram
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.counts[bucketIndex(uint64(d))]++
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+
+	if d > h.max {
+		h.max = d
+	}
+
+	h.count++
+	delta := float64(d) - h.mean
+	h.mean += delta / float64(h.count)
+	h.m2 += delta * (float64(d) - h.mean)
+}
+
+// recordCorrected adds the value and the values of requests which should have been sent
+// every interval while waiting for this one (coordinated omission correction)
+func (h *histogram) recordCorrected(d time.Duration, interval time.Duration) {
+	h.record(d)
+
+	if interval <= 0 {
+		return
+	}
+
+	for missing := d - interval; missing >= interval; missing -= interval {
+		h.record(missing)
+	}
+}
+
+// merge adds all values recorded in other histogram
+func (h *histogram) merge(other *histogram) {
+	if other.count == 0 {
+		return
+	}
+
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+
+	if other.max > h.max {
+		h.max = other.max
+	}
+
+	// Chan's parallel algorithm for combining variances
+	count := h.count + other.count
+	delta := other.mean - h.mean
+	h.m2 += other.m2 + delta*delta*float64(h.count)*float64(other.count)/float64(count)
+	h.mean += delta * float64(other.count) / float64(count)
+	h.count = count
+}
+
+// reset removes all recorded values
+func (h *histogram) reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+
+	h.count = 0
+	h.min = 0
+	h.max = 0
+	h.mean = 0
+	h.m2 = 0
+}
+
+// percentile returns value below which q percent of values fall
+func (h *histogram) percentile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var cumulative int64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative < rank {
+			continue
+		}
+
+		low, width := bucketRange(i)
+		v := time.Duration(low + width/2)
+
+		if v < h.min {
+			return h.min
+		}
+
+		if v > h.max {
+			return h.max
+		}
+
+		return v
+	}
+
+	return h.max
+}
+
+// stat returns distribution of recorded values
+func (h *histogram) stat() timesStat {
+	if h.count == 0 {
+		return timesStat{}
+	}
+
+	return timesStat{
+		min:          h.min,
+		max:          h.max,
+		avg:          time.Duration(h.mean),
+		p50:          h.percentile(50),
+		p75:          h.percentile(75),
+		p90:          h.percentile(90),
+		p99:          h.percentile(99),
+		stdDeviation: math.Sqrt(h.m2 / float64(h.count)),
+	}
+}
+
+// histogramSnapshot is histogram sent by agent to the coordinator, only non-empty buckets are kept
+type histogramSnapshot struct {
+	Buckets []int   `json:"buckets,omitempty"`
+	Counts  []int64 `json:"counts,omitempty"`
+
+	Count int64         `json:"count"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Mean  float64       `json:"mean"`
+	M2    float64       `json:"m2"`
+}
+
+// snapshot returns recorded values of the histogram
+func (h *histogram) snapshot() histogramSnapshot {
+	s := histogramSnapshot{Count: h.count, Min: h.min, Max: h.max, Mean: h.mean, M2: h.m2}
+	for i, c := range h.counts {
+		if c > 0 {
+			s.Buckets = append(s.Buckets, i)
+			s.Counts = append(s.Counts, c)
+		}
+	}
+
+	return s
+}
+
+// mergeSnapshot adds all values recorded in the histogram snapshot
+func (h *histogram) mergeSnapshot(s histogramSnapshot) error {
+	if len(s.Buckets) != len(s.Counts) {
+		return fmt.Errorf("Histogram has %d buckets and %d counts", len(s.Buckets), len(s.Counts))
+	}
+
+	other := newHistogram()
+	for i, b := range s.Buckets {
+		if b < 0 || b >= len(other.counts) {
+			return fmt.Errorf("Histogram bucket %d out of range", b)
+		}
+
+		other.counts[b] = s.Counts[i]
+	}
+
+	other.count, other.min, other.max, other.mean, other.m2 = s.Count, s.Min, s.Max, s.Mean, s.M2
+	h.merge(other)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package katyusha
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	values := []uint64{0, 1, 127, 255, 256, 257, 1000, 123456789, math.MaxInt64, math.MaxUint64}
+
+	for _, v := range values {
+		index := bucketIndex(v)
+		if index < 0 || index >= histogramBucketsLen {
+			t.Fatalf("Bucket index %d of value %d is out of range", index, v)
+		}
+
+		low, width := bucketRange(index)
+		if v < low || v-low >= width {
+			t.Errorf("Value %d should be in bucket [%d, %d)", v, low, low+width)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	h := newHistogram()
+	values := make([]time.Duration, 100000)
+
+	var sum float64
+	for i := range values {
+		values[i] = time.Duration(r.ExpFloat64() * float64(10*time.Millisecond))
+		sum += float64(values[i])
+		h.record(values[i])
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	stat := h.stat()
+
+	if stat.min != values[0] {
+		t.Errorf("Min should be %v but it is %v", values[0], stat.min)
+	}
+
+	if stat.max != values[len(values)-1] {
+		t.Errorf("Max should be %v but it is %v", values[len(values)-1], stat.max)
+	}
+
+	mean := sum / float64(len(values))
+	if math.Abs(float64(stat.avg)-mean) > 1 {
+		t.Errorf("Average should be %v but it is %v", time.Duration(mean), stat.avg)
+	}
+
+	var variance float64
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	stdDeviation := math.Sqrt(variance / float64(len(values)))
+
+	if math.Abs(stat.stdDeviation-stdDeviation)/stdDeviation > 1e-9 {
+		t.Errorf("Standard deviation should be %f but it is %f", stdDeviation, stat.stdDeviation)
+	}
+
+	percentiles := map[float64]time.Duration{50: stat.p50, 75: stat.p75, 90: stat.p90, 99: stat.p99}
+	for q, got := range percentiles {
+		want := values[int(math.Ceil(q/100*float64(len(values))))-1]
+		if math.Abs(float64(got-want))/float64(want) > 1.0/(1<<histogramSubBucketBits) {
+			t.Errorf("P%.0f should be %v but it is %v", q, want, got)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	h1, h2, all := newHistogram(), newHistogram(), newHistogram()
+
+	for i := 1; i <= 1000; i++ {
+		d := time.Duration(i) * time.Microsecond
+		if i%3 == 0 {
+			h1.record(d)
+		} else {
+			h2.record(d)
+		}
+		all.record(d)
+	}
+
+	h1.merge(h2)
+
+	got, want := h1.stat(), all.stat()
+	if got.min != want.min || got.max != want.max || got.p50 != want.p50 || got.p99 != want.p99 {
+		t.Errorf("Merged histogram %+v should be equal to %+v", got, want)
+	}
+
+	if math.Abs(float64(got.avg-want.avg)) > 1 || math.Abs(got.stdDeviation-want.stdDeviation) > 1e-3 {
+		t.Errorf("Merged histogram mean and deviation %v %f should be %v %f", got.avg, got.stdDeviation, want.avg, want.stdDeviation)
+	}
+}
+
+func TestHistogramSnapshot(t *testing.T) {
+	h, merged := newHistogram(), newHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	// Snapshot is sent as JSON by agents
+	data, err := json.Marshal(h.snapshot())
+	if err != nil {
+		t.Fatalf("Can't marshal snapshot: %v", err)
+	}
+
+	var s histogramSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("Can't unmarshal snapshot: %v", err)
+	}
+
+	if err := merged.mergeSnapshot(s); err != nil {
+		t.Fatalf("Can't merge snapshot: %v", err)
+	}
+
+	if got, want := merged.stat(), h.stat(); got != want {
+		t.Errorf("Merged snapshot %+v should be equal to %+v", got, want)
+	}
+
+	s.Buckets[0] = histogramBucketsLen
+	if err := merged.mergeSnapshot(s); err == nil {
+		t.Errorf("Snapshot with bucket out of range should not be merged")
+	}
+}
+
+func TestHistogramRecordCorrected(t *testing.T) {
+	h := newHistogram()
+	h.recordCorrected(100*time.Millisecond, 10*time.Millisecond)
+
+	// 100ms request and the requests which should have been sent after 10ms, 20ms, ... 90ms
+	if h.count != 10 {
+		t.Errorf("Histogram should have 10 values but it has %d", h.count)
+	}
+
+	if h.min != 10*time.Millisecond {
+		t.Errorf("Min corrected value should be 10ms but it is %v", h.min)
+	}
+}
@@ -0,0 +1,285 @@
+package katyusha
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+)
+
+// StreamStat describes HTTP/2 streams multiplexed over connections
+type StreamStat struct {
+	Connections   int     `json:"connections"`    // Connections used by requests
+	Streams       int     `json:"streams"`        // Streams sent over all connections
+	AvgStreams    float64 `json:"avg_streams"`    // Average streams per connection
+	MaxConcurrent int     `json:"max_concurrent"` // Max streams open at the same time on one connection
+}
+
+// connStreams counts streams of one connection
+type connStreams struct {
+	active int
+	total  int
+	max    int
+}
+
+// streamCounter counts streams of connections shared by workers
+type streamCounter struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*connStreams
+}
+
+func newStreamCounter() *streamCounter {
+	return &streamCounter{conns: make(map[net.Conn]*connStreams)}
+}
+
+// open records a new stream on the connection
+func (s *streamCounter) open(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conns[conn]
+	if !ok {
+		c = &connStreams{}
+		s.conns[conn] = c
+	}
+
+	c.active++
+	c.total++
+	if c.active > c.max {
+		c.max = c.active
+	}
+}
+
+// close records the end of a stream on the connection
+func (s *streamCounter) close(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.conns[conn]; ok {
+		c.active--
+	}
+}
+
+// stat returns streams of all connections
+func (s *streamCounter) stat() *StreamStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat := &StreamStat{Connections: len(s.conns)}
+	for _, c := range s.conns {
+		stat.Streams += c.total
+		if c.max > stat.MaxConcurrent {
+			stat.MaxConcurrent = c.max
+		}
+	}
+
+	if stat.Connections > 0 {
+		stat.AvgStreams = float64(stat.Streams) / float64(stat.Connections)
+	}
+
+	return stat
+}
+
+// mergeStreams returns streams of connections of two agents, s can be nil
+func mergeStreams(s, other *StreamStat) *StreamStat {
+	merged := *other
+	if s != nil {
+		merged.Connections += s.Connections
+		merged.Streams += s.Streams
+		if s.MaxConcurrent > merged.MaxConcurrent {
+			merged.MaxConcurrent = s.MaxConcurrent
+		}
+	}
+
+	if merged.Connections > 0 {
+		merged.AvgStreams = float64(merged.Streams) / float64(merged.Connections)
+	}
+
+	return &merged
+}
+
+// http2Transport keeps HTTP/2 connections shared by all workers of the running benchmark.
+// Connections are dialed by the transport so their phases are passed to the request which got the new connection.
+type http2Transport struct {
+	tls *http.Client // HTTP/2 over TLS
+	h2c *http.Client // HTTP/2 with prior knowledge over TCP
+
+	tlsConfig        *tls.Config
+	dialFunc         fasthttp.DialFunc
+	handshakeTimeout time.Duration
+
+	dials   sync.Map // Phases of new connections by net.Conn
+	streams *streamCounter
+}
+
+func newHTTP2Transport(b *Benchmark) *http2Transport {
+	t := &http2Transport{
+		tlsConfig:        b.tlsConfig,
+		dialFunc:         b.dial,
+		handshakeTimeout: b.WriteTimeout,
+		streams:          newStreamCounter(),
+	}
+
+	// Redirects are not followed like with HTTP/1.1 client
+	noRedirect := func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	// With HTTP/2 read timeout limits the whole request
+	t.tls = &http.Client{
+		Timeout:       b.ReadTimeout,
+		CheckRedirect: noRedirect,
+		Transport: &http2.Transport{
+			TLSClientConfig: b.tlsConfig,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return t.dial(ctx, addr, cfg, true)
+			},
+		},
+	}
+
+	t.h2c = &http.Client{
+		Timeout:       b.ReadTimeout,
+		CheckRedirect: noRedirect,
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return t.dial(ctx, addr, cfg, false)
+			},
+		},
+	}
+
+	return t
+}
+
+// dial opens a new connection and keeps its phases until a request gets it.
+// Dial is given up when ctx is cancelled, connection dialed afterwards is closed
+func (t *http2Transport) dial(ctx context.Context, addr string, cfg *tls.Config, isTLS bool) (net.Conn, error) {
+	timer := newPhaseTimer(cfg, t.dialFunc, t.handshakeTimeout)
+	timer.isTLS = isTLS
+
+	type dialed struct {
+		conn net.Conn
+		err  error
+	}
+
+	done := make(chan dialed, 1)
+	go func() {
+		conn, err := timer.Dial(addr)
+		done <- dialed{conn, err}
+	}()
+
+	var conn net.Conn
+	select {
+	case d := <-done:
+		if d.err != nil {
+			return nil, d.err
+		}
+		conn = d.conn
+	case <-ctx.Done():
+		go func() {
+			if d := <-done; d.err == nil {
+				d.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok && tlsConn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+		conn.Close()
+		return nil, fmt.Errorf("Server %s does not support HTTP/2", addr)
+	}
+
+	t.dials.Store(conn, timer)
+	return conn, nil
+}
+
+func (t *http2Transport) closeIdleConnections() {
+	t.tls.CloseIdleConnections()
+	t.h2c.CloseIdleConnections()
+}
+
+// http2Client is HTTP/2 client of one worker, connections are shared with other workers
+type http2Client struct {
+	t   *http2Transport
+	ctx context.Context
+
+	conn      net.Conn    // Connection of the last request
+	dial      *phaseTimer // Phases of the connection when the last request opened it
+	ready     time.Time
+	firstByte time.Time
+}
+
+func newHTTP2Client(t *http2Transport) *http2Client {
+	c := &http2Client{t: t}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.conn = info.Conn
+			c.ready = time.Now()
+
+			if v, ok := t.dials.Load(info.Conn); ok && !info.Reused {
+				t.dials.Delete(info.Conn)
+				c.dial = v.(*phaseTimer)
+			}
+
+			t.streams.open(info.Conn)
+		},
+		GotFirstResponseByte: func() {
+			c.firstByte = time.Now()
+		},
+	}
+
+	c.ctx = httptrace.WithClientTrace(context.Background(), trace)
+	return c
+}
+
+func (c *http2Client) do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	c.conn, c.dial = nil, nil
+	c.firstByte = time.Time{}
+
+	r, err := newHTTPRequest(c.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	httpClient := c.t.h2c
+	if r.URL.Scheme == "https" {
+		httpClient = c.t.tls
+	}
+
+	res, err := httpClient.Do(r)
+	if c.conn != nil {
+		defer c.t.streams.close(c.conn)
+	}
+
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return readHTTPResponse(res, resp)
+}
+
+func (c *http2Client) setPhases(stat *RequestStat) {
+	if c.dial != nil {
+		stat.NewConnection = true
+		stat.DNS, stat.Connect, stat.TLS = c.dial.dns, c.dial.connect, c.dial.handshake
+	}
+
+	if c.firstByte.IsZero() {
+		return
+	}
+
+	stat.TTFB = c.firstByte.Sub(c.ready)
+	stat.Transfer = stat.End.Sub(c.firstByte)
+}
+
+// closeIdleConnections does nothing, connections are shared and closed when the benchmark ends
+func (c *http2Client) closeIdleConnections() {}
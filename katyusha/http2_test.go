@@ -0,0 +1,222 @@
+package katyusha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestHTTP2(t *testing.T) {
+	var http1 int64
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			atomic.AddInt64(&http1, 1)
+		}
+
+		switch r.URL.Path {
+		case "/login":
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != "user=katyusha" ||
+				r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3"})
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "abc"}`)
+		case "/profile":
+			time.Sleep(10 * time.Millisecond)
+			if c, err := r.Cookie("session"); err != nil || c.Value != "s3" || r.Header.Get("Authorization") != "Bearer abc" ||
+				r.Header.Get("User-Agent") != KatyushaName {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	}
+
+	tt := []struct {
+		name   string
+		server func() *httptest.Server
+	}{
+		{
+			name: "TLS",
+			server: func() *httptest.Server {
+				s := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+				s.EnableHTTP2 = true
+				s.StartTLS()
+				return s
+			},
+		},
+		{
+			name: "h2c",
+			server: func() *httptest.Server {
+				return httptest.NewServer(h2c.NewHandler(http.HandlerFunc(handler), &http2.Server{}))
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := tc.server()
+			defer server.Close()
+			atomic.StoreInt64(&http1, 0)
+
+			req := &BenchmarkParameters{
+				URL:             server.URL,
+				Protocol:        ProtocolHTTP2,
+				ConcurrentConns: 10,
+				ReqCount:        200,
+				SkipVerify:      true,
+				ExpectedStatus:  []string{"2xx", "302"},
+				Scenario: []Endpoint{
+					{
+						Name: "profile flow",
+						Steps: []Step{
+							{
+								Name:    "login",
+								URL:     "/login",
+								Method:  "POST",
+								Headers: headers{"Content-Type": "application/x-www-form-urlencoded"},
+								Body:    "user=katyusha",
+								Extract: []Extract{
+									{Variable: "token", JSON: "token"},
+									{Variable: "session", Cookie: "session"},
+								},
+							},
+							{
+								Name:    "profile",
+								URL:     "/profile",
+								Headers: headers{"Authorization": "Bearer ${token}", "Cookie": "session=${session}"},
+							},
+						},
+					},
+				},
+			}
+
+			benchmark, err := NewBenchmark(req)
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != 400 {
+				t.Errorf("All requests should be successful: %d of %d, %v", summary.SuccessReq, summary.ReqCount, summary.Errors)
+			}
+
+			if n := atomic.LoadInt64(&http1); n != 0 {
+				t.Errorf("%d requests were not sent with HTTP/2", n)
+			}
+
+			if summary.StatusCodes[302] != 200 {
+				t.Errorf("Redirects should not be followed: %v", summary.StatusCodes)
+			}
+
+			streams := summary.Streams
+			if streams == nil || streams.Connections != 1 || streams.Streams != 400 || streams.MaxConcurrent < 2 {
+				t.Errorf("Workers should multiplex streams over one connection: %+v", streams)
+			}
+
+			if summary.Phases.Connect.Count != 1 || summary.Phases.TTFB.Count != 400 || summary.Phases.TTFB.Max < 10*time.Millisecond {
+				t.Errorf("Unexpected phases: %+v", summary.Phases)
+			}
+
+			if tc.name == "TLS" && summary.Phases.TLS.Count != 1 {
+				t.Errorf("Connection should have one TLS handshake: %v", summary.Phases.TLS)
+			}
+		})
+	}
+}
+
+func TestHTTP2NotSupported(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL,
+		Protocol:        ProtocolHTTP2,
+		ConcurrentConns: 1,
+		ReqCount:        1,
+		SkipVerify:      true,
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	var errors []string
+	for e := range summary.Errors {
+		errors = append(errors, e)
+	}
+
+	// Depending on the server TLS stack it rejects h2 in the handshake or negotiates HTTP/1.1
+	if summary.FailReq != 1 || len(errors) != 1 ||
+		!(strings.Contains(errors[0], "does not support HTTP/2") || strings.Contains(errors[0], "no application protocol")) {
+		t.Errorf("Request to HTTP/1.1 server should fail: %v", summary.Errors)
+	}
+}
+
+func TestHTTP2DialCancelled(t *testing.T) {
+	// Server accepts connections but never answers TLS handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             "https://" + l.Addr().String(),
+		Protocol:        ProtocolHTTP2,
+		ConcurrentConns: 1,
+		ReqCount:        1,
+		SkipVerify:      true,
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = newHTTP2Transport(benchmark).dial(ctx, l.Addr().String(), benchmark.tlsConfig, true)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Dial should be cancelled, got error: %v", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Errorf("Cancelled dial took %v", time.Since(start))
+	}
+}
+
+func TestInvalidProtocol(t *testing.T) {
+	_, err := NewBenchmark(&BenchmarkParameters{URL: "http://katyusha.test", ReqCount: 1, Protocol: "spdy"})
+	if err == nil {
+		t.Errorf("Benchmark with unknown protocol should not be created")
+	}
+}
@@ -0,0 +1,159 @@
+package katyusha
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/valyala/fasthttp"
+)
+
+// validateHTTP3 checks that requests of the scenario can be sent with HTTP/3
+func validateHTTP3(s *scenario) error {
+	for _, e := range s.endpoints {
+		if !strings.HasPrefix(e.rawURL, "https://") {
+			return fmt.Errorf("HTTP/3 requires https URL: %s", e.rawURL)
+		}
+	}
+
+	return nil
+}
+
+// http3Client is HTTP/3 client of one worker.
+// Like with HTTP/1.1 every worker has its own QUIC connection so phases of its requests can be measured.
+type http3Client struct {
+	rt        *http3.RoundTripper
+	transport *quic.Transport // UDP socket of the worker connections
+	timeout   time.Duration
+
+	dialed    bool
+	dns       time.Duration
+	handshake time.Duration
+	ready     time.Time
+	firstByte time.Time
+}
+
+func newHTTP3Client(b *Benchmark) *http3Client {
+	c := &http3Client{timeout: b.ReadTimeout}
+
+	c.rt = &http3.RoundTripper{
+		TLSClientConfig: b.tlsConfig,
+		QuicConfig: &quic.Config{
+			HandshakeIdleTimeout: b.WriteTimeout,
+			MaxIdleTimeout:       b.KeepAlive,
+		},
+		Dial: c.dial,
+	}
+
+	return c
+}
+
+// dial opens QUIC connection and measures DNS lookup and QUIC handshake which includes TLS handshake
+func (c *http3Client) dial(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (quic.EarlyConnection, error) {
+	c.dialed = true
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		c.dns = time.Since(start)
+		if err != nil {
+			return nil, err
+		}
+
+		ip = addrs[0].IP
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(ip.String(), port))
+	if err != nil {
+		return nil, err
+	}
+
+	if c.transport == nil {
+		udpConn, err := net.ListenUDP("udp", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.transport = &quic.Transport{Conn: udpConn}
+	}
+
+	start := time.Now()
+	conn, err := c.transport.DialEarly(ctx, udpAddr, tlsConfig, config)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-conn.HandshakeComplete():
+	case <-ctx.Done():
+		conn.CloseWithError(0, "")
+		return nil, ctx.Err()
+	}
+
+	c.handshake = time.Since(start)
+	c.ready = time.Now()
+
+	return conn, nil
+}
+
+func (c *http3Client) do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	c.dialed = false
+	c.dns, c.handshake = 0, 0
+	c.ready = time.Now()
+	c.firstByte = time.Time{}
+
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	r, err := newHTTPRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// Redirects are not followed like with HTTP/1.1 client
+	res, err := c.rt.RoundTrip(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Round trip returns when the response headers were received
+	c.firstByte = time.Now()
+
+	return readHTTPResponse(res, resp)
+}
+
+func (c *http3Client) setPhases(stat *RequestStat) {
+	stat.NewConnection = c.dialed
+	stat.DNS, stat.TLS = c.dns, c.handshake
+
+	if c.firstByte.IsZero() {
+		return
+	}
+
+	stat.TTFB = c.firstByte.Sub(c.ready)
+	stat.Transfer = stat.End.Sub(c.firstByte)
+}
+
+func (c *http3Client) closeIdleConnections() {
+	c.rt.Close()
+	if c.transport != nil {
+		c.transport.Close()
+		c.transport.Conn.Close()
+	}
+}
@@ -0,0 +1,188 @@
+package katyusha
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// startHTTP3Server starts HTTP/3 server on a local UDP port with the httptest server certificate.
+// It returns URL of the server and path of the CA file.
+func startHTTP3Server(t *testing.T, handler http.Handler) (string, string) {
+	ts := httptest.NewTLSServer(handler)
+	t.Cleanup(ts.Close)
+
+	dir, err := ioutil.TempDir("", "http3")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	ca := filepath.Join(dir, "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(ca, pemCert, 0600); err != nil {
+		t.Fatalf("Can't write CA file: %v", err)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen on UDP port: %v", err)
+	}
+
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: ts.TLS.Certificates}),
+	}
+
+	go server.Serve(conn)
+	t.Cleanup(func() {
+		server.Close()
+		conn.Close()
+	})
+
+	return fmt.Sprintf("https://%s", conn.LocalAddr()), ca
+}
+
+func TestHTTP3(t *testing.T) {
+	var notHTTP3 int64
+
+	url, ca := startHTTP3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 3 {
+			atomic.AddInt64(&notHTTP3, 1)
+		}
+
+		switch r.URL.Path {
+		case "/login":
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != `{"user": "katyusha"}` || r.Header.Get("User-Agent") != KatyushaName {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("X-Session", "s3")
+			fmt.Fprintf(w, `{"token": "abc"}`)
+		case "/profile":
+			time.Sleep(10 * time.Millisecond)
+			if r.Header.Get("Authorization") != "Bearer abc" || r.URL.Query().Get("session") != "s3" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	}))
+
+	req := &BenchmarkParameters{
+		URL:             url,
+		Protocol:        ProtocolHTTP3,
+		CA:              ca,
+		ConcurrentConns: 5,
+		ReqCount:        100,
+		ExpectedStatus:  []string{"200", "302"},
+		Scenario: []Endpoint{
+			{
+				Name: "profile flow",
+				Steps: []Step{
+					{
+						Name:   "login",
+						URL:    "/login",
+						Method: "POST",
+						Body:   `{"user": "katyusha"}`,
+						Extract: []Extract{
+							{Variable: "token", JSON: "token"},
+							{Variable: "session", Header: "X-Session"},
+						},
+					},
+					{
+						Name:    "profile",
+						URL:     "/profile?session=${session}",
+						Headers: headers{"Authorization": "Bearer ${token}"},
+					},
+				},
+			},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.SuccessReq != 200 {
+		t.Errorf("All requests should be successful: %d of %d, %v", summary.SuccessReq, summary.ReqCount, summary.Errors)
+	}
+
+	if n := atomic.LoadInt64(&notHTTP3); n != 0 {
+		t.Errorf("%d requests were not sent with HTTP/3", n)
+	}
+
+	if summary.StatusCodes[302] != 100 {
+		t.Errorf("Redirects should not be followed: %v", summary.StatusCodes)
+	}
+
+	phases := summary.Phases
+	if phases.TLS.Count != 5 || phases.Connect.Count != 0 || phases.DNS.Count != 0 {
+		t.Errorf("Every worker should have one QUIC handshake: %+v", phases)
+	}
+
+	if phases.TTFB.Count != 200 || phases.TTFB.Max < 10*time.Millisecond {
+		t.Errorf("Unexpected time to first byte: %v", phases.TTFB)
+	}
+}
+
+func TestHTTP3TLS(t *testing.T) {
+	url, ca := startHTTP3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tt := []struct {
+		name    string
+		ca      string
+		skip    bool
+		success int
+	}{
+		{name: "CA", ca: ca, success: 1},
+		{name: "SkipVerify", skip: true, success: 1},
+		{name: "UnknownAuthority", success: 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			benchmark, err := NewBenchmark(&BenchmarkParameters{
+				URL:             url,
+				Protocol:        ProtocolHTTP3,
+				CA:              tc.ca,
+				SkipVerify:      tc.skip,
+				ConcurrentConns: 1,
+				ReqCount:        1,
+			})
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+			if summary.SuccessReq != tc.success {
+				t.Errorf("Expected %d successful requests, got %d: %v", tc.success, summary.SuccessReq, summary.Errors)
+			}
+		})
+	}
+}
+
+func TestHTTP3RequiresHTTPS(t *testing.T) {
+	_, err := NewBenchmark(&BenchmarkParameters{URL: "http://127.0.0.1", ReqCount: 1, Protocol: ProtocolHTTP3})
+	if err == nil {
+		t.Errorf("HTTP/3 benchmark of http URL should not be created")
+	}
+}
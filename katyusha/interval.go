@@ -0,0 +1,234 @@
+package katyusha
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultReportInterval is used when ReportInterval is not set
+const DefaultReportInterval = time.Second
+
+// Interval provides results of requests finished in one time slice of the benchmark
+type Interval struct {
+	Offset   time.Duration `json:"offset"`   // Interval start since the benchmark start
+	Duration time.Duration `json:"duration"` // Interval length, the last interval can be shorter
+
+	ReqCount       int `json:"requests_count"`
+	SuccessReq     int `json:"success_req"`
+	FailReq        int `json:"fail_req"`
+	DataTransfered int `json:"data_transfered"`
+
+	P50ReqTime time.Duration `json:"p50_req_time"`
+	P90ReqTime time.Duration `json:"p90_req_time"`
+	P99ReqTime time.Duration `json:"p99_req_time"`
+}
+
+// openInterval collects request times until the interval is closed
+type openInterval struct {
+	Interval
+	requestTimes *histogram
+}
+
+// timeline splits requests into intervals by the time they finished
+// Requests don't arrive in the exact order so two last intervals are kept open.
+type timeline struct {
+	start    time.Time
+	interval time.Duration
+
+	closed []Interval
+	open   []*openInterval
+	free   []*histogram
+
+	// keep histograms of closed intervals so they can be merged with intervals of other agents
+	keep       bool
+	histograms []histogramSnapshot
+}
+
+func newTimeline(start time.Time, interval time.Duration) *timeline {
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+
+	return &timeline{
+		start:    start,
+		interval: interval,
+		closed:   make([]Interval, 0),
+	}
+}
+
+// next opens interval following the last one
+func (t *timeline) next() {
+	var offset time.Duration
+	if len(t.open) > 0 {
+		offset = t.open[len(t.open)-1].Offset + t.interval
+	} else if len(t.closed) > 0 {
+		offset = t.closed[len(t.closed)-1].Offset + t.interval
+	}
+
+	var h *histogram
+	if len(t.free) > 0 {
+		h = t.free[len(t.free)-1]
+		t.free = t.free[:len(t.free)-1]
+	} else {
+		h = newHistogram()
+	}
+
+	t.open = append(t.open, &openInterval{
+		Interval:     Interval{Offset: offset, Duration: t.interval},
+		requestTimes: h,
+	})
+}
+
+// closeFirst closes the oldest open interval
+func (t *timeline) closeFirst() {
+	oi := t.open[0]
+	t.open = t.open[1:]
+
+	oi.P50ReqTime = oi.requestTimes.percentile(50)
+	oi.P90ReqTime = oi.requestTimes.percentile(90)
+	oi.P99ReqTime = oi.requestTimes.percentile(99)
+	t.closed = append(t.closed, oi.Interval)
+	if t.keep {
+		t.histograms = append(t.histograms, oi.requestTimes.snapshot())
+	}
+
+	oi.requestTimes.reset()
+	t.free = append(t.free, oi.requestTimes)
+}
+
+// add records request stat in the interval in which the request finished
+func (t *timeline) add(stat *RequestStat) {
+	offset := stat.End.Sub(t.start)
+	if offset < 0 {
+		offset = 0
+	}
+
+	if len(t.open) == 0 {
+		t.next()
+	}
+
+	// Open intervals up to the one of this request, intervals without requests are kept with zero values
+	for offset >= t.open[len(t.open)-1].Offset+t.interval {
+		t.next()
+		if len(t.open) > 2 {
+			t.closeFirst()
+		}
+	}
+
+	// Request which finished before the oldest open interval is counted in it
+	oi := t.open[0]
+	for _, o := range t.open {
+		if offset >= o.Offset {
+			oi = o
+		}
+	}
+
+	oi.ReqCount++
+	oi.requestTimes.record(stat.Duration)
+	if stat.Success {
+		oi.SuccessReq++
+		oi.DataTransfered += stat.BodySize
+	} else {
+		oi.FailReq++
+	}
+}
+
+// intervals closes all intervals and returns them
+// The last interval ends at the benchmark end
+func (t *timeline) intervals(end time.Time) []Interval {
+	total := end.Sub(t.start)
+	if len(t.open) == 0 && total > 0 {
+		t.next()
+	}
+
+	// Intervals at the end without finished requests are kept with zero values
+	for len(t.open) > 0 && t.open[len(t.open)-1].Offset+t.interval < total {
+		t.next()
+		if len(t.open) > 2 {
+			t.closeFirst()
+		}
+	}
+
+	for len(t.open) > 0 {
+		t.closeFirst()
+	}
+
+	if len(t.closed) > 0 {
+		last := &t.closed[len(t.closed)-1]
+		if d := total - last.Offset; d > 0 && d < last.Duration {
+			last.Duration = d
+		}
+	}
+
+	return t.closed
+}
+
+// intervalSnapshot is interval sent by agent to the coordinator
+type intervalSnapshot struct {
+	Interval
+	RequestTimes histogramSnapshot `json:"request_times"`
+}
+
+// snapshot returns closed intervals with their histograms, the timeline has to keep them
+func (t *timeline) snapshot() []intervalSnapshot {
+	intervals := make([]intervalSnapshot, 0, len(t.histograms))
+	for i, h := range t.histograms {
+		intervals = append(intervals, intervalSnapshot{Interval: t.closed[i], RequestTimes: h})
+	}
+
+	return intervals
+}
+
+// merge adds intervals of agents, intervals are merged by their order since agents start at the same time.
+// Only the last interval is kept open so it can be continued up to the end of the benchmark.
+func (t *timeline) merge(agents [][]intervalSnapshot) error {
+	for i := 0; ; i++ {
+		var more bool
+		for _, intervals := range agents {
+			more = more || i < len(intervals)
+		}
+
+		if !more {
+			return nil
+		}
+
+		t.next()
+		if len(t.open) > 1 {
+			t.closeFirst()
+		}
+
+		oi := t.open[len(t.open)-1]
+		for _, intervals := range agents {
+			if i >= len(intervals) {
+				continue
+			}
+
+			in := intervals[i]
+			oi.ReqCount += in.ReqCount
+			oi.SuccessReq += in.SuccessReq
+			oi.FailReq += in.FailReq
+			oi.DataTransfered += in.DataTransfered
+
+			if err := oi.requestTimes.mergeSnapshot(in.RequestTimes); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// IntervalsTable returns intervals as tab separated table
+func (s Summary) IntervalsTable() string {
+	var sb strings.Builder
+
+	w := tabwriter.NewWriter(&sb, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Offset\tDuration\tRequests\tSuccess\tFail\tData\tP50\tP90\tP99")
+	for _, i := range s.Intervals {
+		fmt.Fprintf(w, "%v\t%v\t%d\t%d\t%d\t%d\t%v\t%v\t%v\n", i.Offset, i.Duration, i.ReqCount, i.SuccessReq, i.FailReq,
+			i.DataTransfered, i.P50ReqTime, i.P90ReqTime, i.P99ReqTime)
+	}
+	w.Flush()
+
+	return sb.String()
+}
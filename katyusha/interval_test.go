@@ -0,0 +1,63 @@
+package katyusha
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Now()
+	tl := newTimeline(start, time.Second)
+
+	stats := []*RequestStat{
+		{End: start.Add(100 * time.Millisecond), Duration: 10 * time.Millisecond, RetCode: 200, BodySize: 10, Success: true},
+		{End: start.Add(1200 * time.Millisecond), Duration: 20 * time.Millisecond, RetCode: 200, BodySize: 10, Success: true},
+		// Finished before the previous request but arrived later
+		{End: start.Add(900 * time.Millisecond), Duration: 30 * time.Millisecond, RetCode: 500},
+		// No requests finished in the third second
+		{End: start.Add(3500 * time.Millisecond), Duration: 40 * time.Millisecond, Error: errors.New("timeout")},
+	}
+
+	for _, stat := range stats {
+		tl.add(stat)
+	}
+
+	expected := []Interval{
+		{Offset: 0, Duration: time.Second, ReqCount: 2, SuccessReq: 1, FailReq: 1, DataTransfered: 10,
+			P50ReqTime: 10 * time.Millisecond, P90ReqTime: 30 * time.Millisecond, P99ReqTime: 30 * time.Millisecond},
+		{Offset: time.Second, Duration: time.Second, ReqCount: 1, SuccessReq: 1, DataTransfered: 10,
+			P50ReqTime: 20 * time.Millisecond, P90ReqTime: 20 * time.Millisecond, P99ReqTime: 20 * time.Millisecond},
+		{Offset: 2 * time.Second, Duration: time.Second},
+		{Offset: 3 * time.Second, Duration: time.Second, ReqCount: 1, FailReq: 1,
+			P50ReqTime: 40 * time.Millisecond, P90ReqTime: 40 * time.Millisecond, P99ReqTime: 40 * time.Millisecond},
+		{Offset: 4 * time.Second, Duration: 500 * time.Millisecond},
+	}
+
+	// Percentiles come from histogram and they are approximated
+	approx := cmp.Comparer(func(x, y time.Duration) bool {
+		return math.Abs(float64(x-y)) <= float64(x)/(1<<histogramSubBucketBits)
+	})
+
+	intervals := tl.intervals(start.Add(4500 * time.Millisecond))
+	if diff := cmp.Diff(expected, intervals, approx); diff != "" {
+		t.Errorf("Intervals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTimelineWithoutRequests(t *testing.T) {
+	start := time.Now()
+	tl := newTimeline(start, 0)
+
+	intervals := tl.intervals(start.Add(1500 * time.Millisecond))
+	if len(intervals) != 2 {
+		t.Fatalf("There should be 2 empty intervals but there are %d", len(intervals))
+	}
+
+	if intervals[1].Duration != 500*time.Millisecond {
+		t.Errorf("Last interval should be 500ms long but it is %v", intervals[1].Duration)
+	}
+}
@@ -13,8 +13,8 @@ import (
 )
 
 type BenchmarkConfiguration struct {
-	ID          int64
-	Description string
+	ID          int64  `json:"id"`
+	Description string `json:"description"`
 
 	BenchmarkParameters
 }
@@ -28,24 +28,36 @@ Method:				%s
 Request count:			%d
 Abort:				%d
 Concurrent connections:		%d
+Rate:				%d
 SkipVerify:			%t
 CA:				%s
 Cert:			%s
 Key:			%s
 Duration:			%v
+Report Interval:		%v
 Keep Alive: 			%v
 Request Delay:			%v
 Read Timeout:			%v
 Write Timeout:			%v
+Protocol:			%s
 Headers: 			%v
 Query args: 			%v
+Stages: 			%v
+Scenario: 			%v
+Thresholds: 			%v
+Feeder: 			%v
+Expected status: 		%v
+Assertions: 			%v
+WebSocket: 			%v
+gRPC: 				%v
+Streaming: 			%v
 Body: 		%s
-`, b.ID, b.Description, b.URL, b.Method, b.ReqCount, b.AbortAfter, b.ConcurrentConns, b.SkipVerify, b.CA, b.Cert, b.Key, b.Duration,
-		b.KeepAlive, b.RequestDelay, b.ReadTimeout, b.WriteTimeout, b.Headers, b.Parameters, string(b.Body))
+`, b.ID, b.Description, b.URL, b.Method, b.ReqCount, b.AbortAfter, b.ConcurrentConns, b.Rate, b.SkipVerify, b.CA, b.Cert, b.Key, b.Duration,
+		b.ReportInterval, b.KeepAlive, b.RequestDelay, b.ReadTimeout, b.WriteTimeout, b.Protocol, b.Headers, b.Parameters, b.Stages, b.Scenario, b.Thresholds, b.Feeder, b.ExpectedStatus, b.Assertions, b.WebSocket, b.GRPC, b.Streaming, string(b.Body))
 }
 
 type BenchmarkSummary struct {
-	ID int64
+	ID int64 `json:"id"`
 
 	Summary
 }
@@ -65,7 +77,7 @@ func boolToInt(b bool) int {
 }
 
 // NetInventory creates and initiate new Inventory object with ready to use db handler
-// If file does not exists it will try to create schema
+// If file does not exists it will try to create schema, schema of existing file is upgraded to the latest version
 func NewInventory(dbFile string) (*Inventory, error) {
 	var createSchema bool
 
@@ -84,6 +96,16 @@ func NewInventory(dbFile string) (*Inventory, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Could not create inventory schema: %w", err)
 		}
+
+		_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations)))
+		if err != nil {
+			return nil, fmt.Errorf("Could not set inventory schema version: %w", err)
+		}
+	}
+
+	err = migrate(db)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Inventory{
@@ -91,6 +113,43 @@ func NewInventory(dbFile string) (*Inventory, error) {
 	}, nil
 }
 
+// migrate upgrades inventory schema to the latest version, every migration is applied in its own transaction
+func migrate(db *sql.DB) error {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("Could not read inventory schema version: %w", err)
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("Inventory schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("Can't start transaction: %v", err)
+		}
+
+		_, err = tx.Exec(migrations[version])
+		if err == nil {
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Could not upgrade inventory schema to version %d: %w", version+1, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("Could not upgrade inventory schema to version %d: %w", version+1, err)
+		}
+	}
+
+	return nil
+}
+
 func (i *Inventory) queryParametersTable(ctx context.Context, bcId int64) (parameters, error) {
 	query := "SELECT parameter FROM parameters where benchmark_configuration = ?"
 
@@ -128,11 +187,341 @@ func (i *Inventory) queryParametersTable(ctx context.Context, bcId int64) (param
 	return results, nil
 }
 
+// queryStagesTable returns load profile stages in the order they were created
+func (i *Inventory) queryStagesTable(ctx context.Context, bcId int64) ([]Stage, error) {
+	query := "SELECT duration,connections,rate FROM stages WHERE benchmark_configuration = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, bcId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var results []Stage
+
+	for rows.Next() {
+		var stage Stage
+		err = rows.Scan(&stage.Duration, &stage.Connections, &stage.Rate)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, stage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryEndpointsTable returns scenario endpoints in the order they were created
+func (i *Inventory) queryEndpointsTable(ctx context.Context, bcId int64) ([]Endpoint, error) {
+	query := "SELECT id,name,url,method,body,weight FROM endpoints WHERE benchmark_configuration = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, bcId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var ids []int64
+	var results []Endpoint
+
+	for rows.Next() {
+		var id int64
+		var endpoint Endpoint
+		err = rows.Scan(&id, &endpoint.Name, &endpoint.URL, &endpoint.Method, &endpoint.Body, &endpoint.Weight)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		results = append(results, endpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+
+	for n, id := range ids {
+		h, err := i.queryHeaders(ctx, "SELECT header FROM endpoint_headers WHERE endpoint = ?", id)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(h) > 0 {
+			results[n].Headers = h
+		}
+
+		results[n].Steps, err = i.queryStepsTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// queryStepsTable returns steps of scenario endpoint in the order they were created
+func (i *Inventory) queryStepsTable(ctx context.Context, endpointID int64) ([]Step, error) {
+	query := "SELECT id,name,url,method,body FROM steps WHERE endpoint = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var ids []int64
+	var results []Step
+
+	for rows.Next() {
+		var id int64
+		var step Step
+		err = rows.Scan(&id, &step.Name, &step.URL, &step.Method, &step.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		results = append(results, step)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+
+	for n, id := range ids {
+		h, err := i.queryHeaders(ctx, "SELECT header FROM step_headers WHERE step = ?", id)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(h) > 0 {
+			results[n].Headers = h
+		}
+
+		results[n].Extract, err = i.queryExtractsTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// queryExtractsTable returns extracts of one step
+func (i *Inventory) queryExtractsTable(ctx context.Context, stepID int64) ([]Extract, error) {
+	query := "SELECT variable,json,regex,header,cookie FROM extracts WHERE step = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var results []Extract
+
+	for rows.Next() {
+		var e Extract
+		err = rows.Scan(&e.Variable, &e.JSON, &e.Regex, &e.Header, &e.Cookie)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryEndpointSummaries returns scenario endpoints results of one summary
+func (i *Inventory) queryEndpointSummaries(ctx context.Context, smId int64) ([]*Summary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE parent_summary = ? AND endpoint != '' ORDER BY id", summaryFields)
+
+	summaries, err := i.querySummary(ctx, query, smId)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*Summary
+	for _, sm := range summaries {
+		endpoints = append(endpoints, &sm.Summary)
+	}
+
+	return endpoints, nil
+}
+
+// queryThresholdsTable returns threshold expressions in the order they were created
+func (i *Inventory) queryThresholdsTable(ctx context.Context, bcId int64) ([]string, error) {
+	query := "SELECT threshold FROM thresholds WHERE benchmark_configuration = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, bcId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var results []string
+
+	for rows.Next() {
+		var threshold string
+		err = rows.Scan(&threshold)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, threshold)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryExpectedStatusTable returns expected status codes in the order they were created
+func (i *Inventory) queryExpectedStatusTable(ctx context.Context, bcId int64) ([]string, error) {
+	query := "SELECT status FROM expected_status WHERE benchmark_configuration = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, bcId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var results []string
+
+	for rows.Next() {
+		var status string
+		err = rows.Scan(&status)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, status)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryAssertionsTable returns assertions in the order they were created
+func (i *Inventory) queryAssertionsTable(ctx context.Context, bcId int64) ([]Assertion, error) {
+	query := "SELECT name,status,contains,regex,json,equals,header,min_size,max_size FROM assertions WHERE benchmark_configuration = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, bcId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var results []Assertion
+
+	for rows.Next() {
+		var a Assertion
+		var status string
+		err = rows.Scan(&a.Name, &status, &a.Contains, &a.Regex, &a.JSON, &a.Equals, &a.Header, &a.MinSize, &a.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if status != "" {
+			a.Status = strings.Split(status, ",")
+		}
+
+		results = append(results, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryFeederTable returns the feeder of benchmark configuration or nil when it is not set
+func (i *Inventory) queryFeederTable(ctx context.Context, bcId int64) (*Feeder, error) {
+	query := "SELECT file,format,strategy,exhausted FROM feeders WHERE benchmark_configuration = ?"
+
+	var f Feeder
+	err := i.db.QueryRowContext(ctx, query, bcId).Scan(&f.File, &f.Format, &f.Strategy, &f.Exhausted)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// queryWebSocketTable returns the WebSocket configuration or nil for HTTP benchmark
+func (i *Inventory) queryWebSocketTable(ctx context.Context, bcId int64) (*WebSocket, error) {
+	query := "SELECT message,expect FROM websockets WHERE benchmark_configuration = ?"
+
+	var w WebSocket
+	err := i.db.QueryRowContext(ctx, query, bcId).Scan(&w.Message, &w.Expect)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// queryGRPCTable returns the gRPC configuration or nil for HTTP benchmark
+func (i *Inventory) queryGRPCTable(ctx context.Context, bcId int64) (*GRPC, error) {
+	query := "SELECT method,payload,descriptor_set FROM grpc WHERE benchmark_configuration = ?"
+
+	var g GRPC
+	err := i.db.QueryRowContext(ctx, query, bcId).Scan(&g.Method, &g.Payload, &g.DescriptorSet)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// queryStreamingTable returns the streaming configuration or nil when responses are not streamed
+func (i *Inventory) queryStreamingTable(ctx context.Context, bcId int64) (*Streaming, error) {
+	query := "SELECT format,events,lifetime FROM streaming WHERE benchmark_configuration = ?"
+
+	var st Streaming
+	err := i.db.QueryRowContext(ctx, query, bcId).Scan(&st.Format, &st.Events, &st.Lifetime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
 // querykeyValueTable is used to read table parameters and headers
 func (i *Inventory) queryHeadersTable(ctx context.Context, bcId int64) (headers, error) {
-	query := "SELECT header FROM headers WHERE benchmark_configuration = ?"
+	return i.queryHeaders(ctx, "SELECT header FROM headers WHERE benchmark_configuration = ?", bcId)
+}
 
-	rows, err := i.db.QueryContext(ctx, query, bcId)
+// queryHeaders reads headers stored as "key:value" strings
+func (i *Inventory) queryHeaders(ctx context.Context, query string, id int64) (headers, error) {
+	rows, err := i.db.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +593,124 @@ func (i *Inventory) FindBenchmarkByURL(ctx context.Context, URL string) ([]*Benc
 	return bcs, err
 }
 
+// queryStatusCodes returns status codes table for one summary table
+func (i *Inventory) queryStatusCodes(ctx context.Context, smId int64) (map[int]int, error) {
+	query := "SELECT code,count FROM status_codes WHERE benchmark_summary = ?"
+
+	statusCodes := make(map[int]int)
+	rows, err := i.db.QueryContext(ctx, query, smId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var code, count int
+
+		err = rows.Scan(&code, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		statusCodes[code] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statusCodes, nil
+}
+
+// queryPhases returns request phases of one summary
+func (i *Inventory) queryPhases(ctx context.Context, smId int64) (Phases, error) {
+	query := "SELECT name,count,avg,p50,p90,p99,max FROM phases WHERE benchmark_summary = ?"
+
+	var phases Phases
+	rows, err := i.db.QueryContext(ctx, query, smId)
+	if err != nil {
+		return phases, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var p PhaseStat
+
+		err = rows.Scan(&name, &p.Count, &p.Avg, &p.P50, &p.P90, &p.P99, &p.Max)
+		if err != nil {
+			return phases, err
+		}
+
+		if stat := phases.phase(name); stat != nil {
+			*stat = p
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return phases, err
+	}
+
+	return phases, nil
+}
+
+// queryStreams returns HTTP/2 streams of one summary or nil for HTTP/1.1 benchmark
+func (i *Inventory) queryStreams(ctx context.Context, smId int64) (*StreamStat, error) {
+	query := "SELECT connections,streams,avg_streams,max_concurrent FROM streams WHERE benchmark_summary = ?"
+
+	var s StreamStat
+	err := i.db.QueryRowContext(ctx, query, smId).Scan(&s.Connections, &s.Streams, &s.AvgStreams, &s.MaxConcurrent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// queryWebSocketStat returns WebSocket connections of one summary or nil for HTTP benchmark
+func (i *Inventory) queryWebSocketStat(ctx context.Context, smId int64) (*WebSocketStat, error) {
+	query := "SELECT connections,dropped,setup_count,setup_avg,setup_p50,setup_p90,setup_p99,setup_max FROM websocket_stats WHERE benchmark_summary = ?"
+
+	var w WebSocketStat
+	err := i.db.QueryRowContext(ctx, query, smId).Scan(&w.Connections, &w.Dropped, &w.Setup.Count, &w.Setup.Avg, &w.Setup.P50,
+		&w.Setup.P90, &w.Setup.P99, &w.Setup.Max)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// queryStreamingStat returns events of streamed responses of one summary or nil when responses were not streamed
+func (i *Inventory) queryStreamingStat(ctx context.Context, smId int64) (*StreamingStat, error) {
+	query := `SELECT streams,events,events_per_sec,
+first_event_count,first_event_avg,first_event_p50,first_event_p90,first_event_p99,first_event_max,
+event_gap_count,event_gap_avg,event_gap_p50,event_gap_p90,event_gap_p99,event_gap_max,
+lifetime_count,lifetime_avg,lifetime_p50,lifetime_p90,lifetime_p99,lifetime_max
+FROM streaming_stats WHERE benchmark_summary = ?`
+
+	var st StreamingStat
+	dest := []interface{}{&st.Streams, &st.Events, &st.EventsPerSec}
+	for _, p := range []*PhaseStat{&st.FirstEvent, &st.EventGap, &st.Lifetime} {
+		dest = append(dest, &p.Count, &p.Avg, &p.P50, &p.P90, &p.P99, &p.Max)
+	}
+
+	err := i.db.QueryRowContext(ctx, query, smId).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
 // queryErrors returns errors table for one summary table
 func (i *Inventory) queryErrors(ctx context.Context, smId int64) (map[string]int, error) {
 	query := "SELECT name,count FROM errors WHERE benchmark_summary = ?"
@@ -240,9 +747,39 @@ func (i *Inventory) queryErrors(ctx context.Context, smId int64) (map[string]int
 	return errorsMap, nil
 }
 
+// queryIntervals returns time series of one summary
+func (i *Inventory) queryIntervals(ctx context.Context, smId int64) ([]Interval, error) {
+	query := "SELECT offset,duration,requests_count,success_req,fail_req,data_transfered,p50_req_time,p90_req_time,p99_req_time FROM intervals WHERE benchmark_summary = ? ORDER BY id"
+
+	rows, err := i.db.QueryContext(ctx, query, smId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var intervals []Interval
+
+	for rows.Next() {
+		var in Interval
+		err = rows.Scan(&in.Offset, &in.Duration, &in.ReqCount, &in.SuccessReq, &in.FailReq, &in.DataTransfered,
+			&in.P50ReqTime, &in.P90ReqTime, &in.P99ReqTime)
+		if err != nil {
+			return nil, err
+		}
+
+		intervals = append(intervals, in)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return intervals, nil
+}
+
 // FindSummaryForBenchmark return summaries for benchmark
 func (i *Inventory) FindSummaryForBenchmark(ctx context.Context, bcID int64) ([]*BenchmarkSummary, error) {
-	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE benchmark_configuration = ?", summaryFields)
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE benchmark_configuration = ? AND parent_summary IS NULL", summaryFields)
 
 	summaries, err := i.querySummary(ctx, query, bcID)
 	if err != nil {
@@ -252,6 +789,57 @@ func (i *Inventory) FindSummaryForBenchmark(ctx context.Context, bcID int64) ([]
 	return summaries, err
 }
 
+// FindSummaryByID returns summary with given ID or nil if it does not exist
+func (i *Inventory) FindSummaryByID(ctx context.Context, ID int64) (*BenchmarkSummary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE id = ? AND parent_summary IS NULL", summaryFields)
+
+	return i.querySingleSummary(ctx, query, ID)
+}
+
+// FindLatestSummary returns the most recently saved summary for benchmark or nil if there are no summaries
+func (i *Inventory) FindLatestSummary(ctx context.Context, bcID int64) (*BenchmarkSummary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE benchmark_configuration = ? AND parent_summary IS NULL ORDER BY id DESC LIMIT 1", summaryFields)
+
+	return i.querySingleSummary(ctx, query, bcID)
+}
+
+// FindFirstSummary returns the first saved summary for benchmark or nil if there are no summaries
+func (i *Inventory) FindFirstSummary(ctx context.Context, bcID int64) (*BenchmarkSummary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE benchmark_configuration = ? AND parent_summary IS NULL ORDER BY id LIMIT 1", summaryFields)
+
+	return i.querySingleSummary(ctx, query, bcID)
+}
+
+func (i *Inventory) querySingleSummary(ctx context.Context, query string, args ...interface{}) (*BenchmarkSummary, error) {
+	summaries, err := i.querySummary(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(summaries) != 1 {
+		return nil, nil
+	}
+
+	return summaries[0], nil
+}
+
+// queryStageSummaries returns stages results of one summary
+func (i *Inventory) queryStageSummaries(ctx context.Context, smId int64) ([]*Summary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE parent_summary = ? AND endpoint = '' ORDER BY stage", summaryFields)
+
+	summaries, err := i.querySummary(ctx, query, smId)
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []*Summary
+	for _, sm := range summaries {
+		stages = append(stages, &sm.Summary)
+	}
+
+	return stages, nil
+}
+
 // querySummary return benchmarks summaries based on provided query and args
 func (i *Inventory) querySummary(ctx context.Context, query string, args ...interface{}) ([]*BenchmarkSummary, error) {
 	results := make([]*BenchmarkSummary, 0)
@@ -266,13 +854,17 @@ func (i *Inventory) querySummary(ctx context.Context, query string, args ...inte
 	for rows.Next() {
 		var id int64
 		var reqCount, successReq, failReq, dataTransfered int
-		var start, end string
+		var scheduledReq, lateReq, droppedReq, stage int
+		var start, end, endpoint string
 		var duration, avgReq, minReq, maxReq time.Duration
 		var p50Req, p75Req, p90Req, p99Req time.Duration
-		var reqPerSec float64
+		var avgLatency, maxLatency, p50Latency, p75Latency, p90Latency, p99Latency time.Duration
+		var reqPerSec, stdDeviation float64
 
 		err = rows.Scan(&id, &start, &end, &duration, &reqCount, &successReq, &failReq, &dataTransfered,
-			&reqPerSec, &avgReq, &minReq, &maxReq, &p50Req, &p75Req, &p90Req, &p99Req)
+			&reqPerSec, &avgReq, &minReq, &maxReq, &p50Req, &p75Req, &p90Req, &p99Req, &stdDeviation,
+			&avgLatency, &maxLatency, &p50Latency, &p75Latency, &p90Latency, &p99Latency,
+			&scheduledReq, &lateReq, &droppedReq, &stage, &endpoint)
 		if err != nil {
 			return nil, err
 		}
@@ -305,6 +897,18 @@ func (i *Inventory) querySummary(ctx context.Context, query string, args ...inte
 				P75ReqTime:     p75Req,
 				P90ReqTime:     p90Req,
 				P99ReqTime:     p99Req,
+				StdDeviation:   stdDeviation,
+				AvgLatency:     avgLatency,
+				MaxLatency:     maxLatency,
+				P50Latency:     p50Latency,
+				P75Latency:     p75Latency,
+				P90Latency:     p90Latency,
+				P99Latency:     p99Latency,
+				ScheduledReq:   scheduledReq,
+				LateReq:        lateReq,
+				DroppedReq:     droppedReq,
+				Stage:          stage,
+				Endpoint:       endpoint,
 			},
 		}
 
@@ -314,6 +918,62 @@ func (i *Inventory) querySummary(ctx context.Context, query string, args ...inte
 		}
 
 		s.Errors = errorsMap
+
+		statusCodes, err := i.queryStatusCodes(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.StatusCodes = statusCodes
+
+		phases, err := i.queryPhases(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Phases = phases
+
+		streams, err := i.queryStreams(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Streams = streams
+
+		webSocket, err := i.queryWebSocketStat(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.WebSocket = webSocket
+
+		streaming, err := i.queryStreamingStat(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Streaming = streaming
+
+		intervals, err := i.queryIntervals(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Intervals = intervals
+
+		stages, err := i.queryStageSummaries(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Stages = stages
+
+		endpoints, err := i.queryEndpointSummaries(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Endpoints = endpoints
 		results = append(results, s)
 	}
 
@@ -324,34 +984,79 @@ func (i *Inventory) querySummary(ctx context.Context, query string, args ...inte
 func (i *Inventory) queryBenchmark(ctx context.Context, query string, args ...interface{}) ([]*BenchmarkConfiguration, error) {
 	results := make([]*BenchmarkConfiguration, 0)
 
-	rows, err := i.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var reqCount, abortAfter, concurrentConns, rate int
+		var description, url, method, ca, cert, key, protocol string
+		var duration, reportInterval, keepAlive, requestDelay, readTimeout, writeTimeout time.Duration
+		var skipVerify bool
+		var body []byte
+
+		err = rows.Scan(&id, &description, &url, &method, &reqCount, &concurrentConns, &rate,
+			&skipVerify, &abortAfter, &ca, &cert, &key, &duration, &reportInterval, &keepAlive, &requestDelay,
+			&readTimeout, &writeTimeout, &body, &protocol)
+		if err != nil {
+			return nil, err
+		}
+
+		headers, err := i.queryHeadersTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		parameters, err := i.queryParametersTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		stages, err := i.queryStagesTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		thresholds, err := i.queryThresholdsTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		scenario, err := i.queryEndpointsTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 
-	defer rows.Close()
+		feeder, err := i.queryFeederTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 
-	for rows.Next() {
-		var id int64
-		var reqCount, abortAfter, concurrentConns int
-		var description, url, method, ca, cert, key string
-		var duration, keepAlive, requestDelay, readTimeout, writeTimeout time.Duration
-		var skipVerify bool
-		var body []byte
+		webSocket, err := i.queryWebSocketTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 
-		err = rows.Scan(&id, &description, &url, &method, &reqCount, &concurrentConns,
-			&skipVerify, &abortAfter, &ca, &cert, &key, &duration, &keepAlive, &requestDelay,
-			&readTimeout, &writeTimeout, &body)
+		grpc, err := i.queryGRPCTable(ctx, id)
 		if err != nil {
 			return nil, err
 		}
 
-		headers, err := i.queryHeadersTable(ctx, id)
+		streaming, err := i.queryStreamingTable(ctx, id)
 		if err != nil {
 			return nil, err
 		}
 
-		parameters, err := i.queryParametersTable(ctx, id)
+		assertions, err := i.queryAssertionsTable(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		expectedStatus, err := i.queryExpectedStatusTable(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -365,11 +1070,13 @@ func (i *Inventory) queryBenchmark(ctx context.Context, query string, args ...in
 				ReqCount:        reqCount,
 				AbortAfter:      abortAfter,
 				ConcurrentConns: concurrentConns,
+				Rate:            rate,
 				SkipVerify:      skipVerify,
 				CA:              ca,
 				Cert:            cert,
 				Key:             key,
 				Duration:        duration,
+				ReportInterval:  reportInterval,
 				KeepAlive:       keepAlive,
 				RequestDelay:    requestDelay,
 				ReadTimeout:     readTimeout,
@@ -377,6 +1084,16 @@ func (i *Inventory) queryBenchmark(ctx context.Context, query string, args ...in
 				Headers:         headers,
 				Parameters:      parameters,
 				Body:            body,
+				Protocol:        protocol,
+				Stages:          stages,
+				Thresholds:      thresholds,
+				Scenario:        scenario,
+				Feeder:          feeder,
+				WebSocket:       webSocket,
+				GRPC:            grpc,
+				Streaming:       streaming,
+				Assertions:      assertions,
+				ExpectedStatus:  expectedStatus,
 			},
 		}
 
@@ -402,6 +1119,18 @@ func (i *Inventory) DeleteBenchmark(ctx context.Context, bcID int64) error {
 		return fmt.Errorf("Can't start transaction: %v", err)
 	}
 
+	err = deleteSummaryTables(ctx, tx, bcID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = deleteConfigurationTables(ctx, tx, bcID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	query := "DELETE FROM benchmark_configuration WHERE id = ?"
 	_, err = tx.ExecContext(ctx, query, bcID)
 	if err != nil {
@@ -413,14 +1142,86 @@ func (i *Inventory) DeleteBenchmark(ctx context.Context, bcID int64) error {
 	return err
 }
 
-// InsertBenchmarkSummary creates summary for specific benchmark configuration
-func (i *Inventory) InsertBenchmarkSummary(ctx context.Context, summary *Summary, bcId int64) error {
+// SetBaseline pins summary as the baseline of its benchmark configuration, it replaces the previous baseline
+func (i *Inventory) SetBaseline(ctx context.Context, smID int64) error {
+	var bcID int64
+
+	query := "SELECT benchmark_configuration FROM benchmark_summary WHERE id = ? AND parent_summary IS NULL"
+	err := i.db.QueryRowContext(ctx, query, smID).Scan(&bcID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("No summary at ID %d", smID)
+	} else if err != nil {
+		return fmt.Errorf("Can't get summary benchmark configuration: %v", err)
+	}
+
+	query = "INSERT OR REPLACE INTO baselines(benchmark_configuration,benchmark_summary) VALUES(?,?)"
+	_, err = i.db.ExecContext(ctx, query, bcID, smID)
+	if err != nil {
+		return fmt.Errorf("Can't set baseline: %v", err)
+	}
+
+	return nil
+}
+
+// FindBaseline returns pinned baseline summary of benchmark configuration or nil if baseline is not set
+func (i *Inventory) FindBaseline(ctx context.Context, bcID int64) (*BenchmarkSummary, error) {
+	query := fmt.Sprintf("SELECT id,%s FROM benchmark_summary WHERE id = (SELECT benchmark_summary FROM baselines WHERE benchmark_configuration = ?)", summaryFields)
+
+	return i.querySingleSummary(ctx, query, bcID)
+}
+
+// ClearBaseline removes pinned baseline of benchmark configuration
+func (i *Inventory) ClearBaseline(ctx context.Context, bcID int64) error {
+	query := "DELETE FROM baselines WHERE benchmark_configuration = ?"
+	_, err := i.db.ExecContext(ctx, query, bcID)
+	if err != nil {
+		return fmt.Errorf("Can't clear baseline: %v", err)
+	}
+
+	return nil
+}
+
+// InsertBenchmarkSummary creates summary for specific benchmark configuration and returns its ID
+func (i *Inventory) InsertBenchmarkSummary(ctx context.Context, summary *Summary, bcId int64) (int64, error) {
 	tx, err := i.db.Begin()
 	if err != nil {
-		return fmt.Errorf("Can't start transaction: %v", err)
+		return 0, fmt.Errorf("Can't start transaction: %v", err)
+	}
+
+	smId, err := i.insertSummary(ctx, tx, summary, bcId, sql.NullInt64{})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, stage := range summary.Stages {
+		_, err = i.insertSummary(ctx, tx, stage, bcId, sql.NullInt64{Int64: smId, Valid: true})
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	for _, endpoint := range summary.Endpoints {
+		_, err = i.insertSummary(ctx, tx, endpoint, bcId, sql.NullInt64{Int64: smId, Valid: true})
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("Can't commit summary: %v", err)
 	}
 
-	query := fmt.Sprintf("INSERT INTO benchmark_summary(%s,benchmark_configuration) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", summaryFields)
+	return smId, nil
+}
+
+// insertSummary inserts one summary row with its errors and returns summary ID
+// Stage summaries are linked to the whole benchmark summary by parent
+func (i *Inventory) insertSummary(ctx context.Context, tx *sql.Tx, summary *Summary, bcId int64, parent sql.NullInt64) (int64, error) {
+	query := fmt.Sprintf("INSERT INTO benchmark_summary(%s,benchmark_configuration,parent_summary) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", summaryFields)
 	res, err := tx.ExecContext(ctx, query,
 		summary.Start.Format(time.RFC3339),
 		summary.End.Format(time.RFC3339),
@@ -437,31 +1238,135 @@ func (i *Inventory) InsertBenchmarkSummary(ctx context.Context, summary *Summary
 		summary.P75ReqTime,
 		summary.P90ReqTime,
 		summary.P99ReqTime,
+		summary.StdDeviation,
+		summary.AvgLatency,
+		summary.MaxLatency,
+		summary.P50Latency,
+		summary.P75Latency,
+		summary.P90Latency,
+		summary.P99Latency,
+		summary.ScheduledReq,
+		summary.LateReq,
+		summary.DroppedReq,
+		summary.Stage,
+		summary.Endpoint,
 		bcId,
+		parent,
 	)
 
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("Can't create summary in database: %v", err)
+		return 0, fmt.Errorf("Can't create summary in database: %v", err)
 	}
 
 	smId, err := res.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("Can't get summary ID: %v", err)
+		return 0, fmt.Errorf("Can't get summary ID: %v", err)
 	}
 
 	query = "INSERT INTO errors(name,count,benchmark_summary) VALUES(?,?,?)"
 	for name, count := range summary.Errors {
 		_, err := tx.ExecContext(ctx, query, name, count, smId)
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("Can't create error for summary: %v", err)
+			return 0, fmt.Errorf("Can't create error for summary: %v", err)
 		}
 	}
 
-	err = tx.Commit()
+	query = "INSERT INTO status_codes(code,count,benchmark_summary) VALUES(?,?,?)"
+	for code, count := range summary.StatusCodes {
+		_, err := tx.ExecContext(ctx, query, code, count, smId)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create status code for summary: %v", err)
+		}
+	}
+
+	query = "INSERT INTO phases(name,count,avg,p50,p90,p99,max,benchmark_summary) VALUES(?,?,?,?,?,?,?,?)"
+	for _, name := range phasesOrder {
+		p := summary.Phases.phase(name)
+		if p.Count == 0 {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, query, name, p.Count, p.Avg, p.P50, p.P90, p.P99, p.Max, smId)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create phase for summary: %v", err)
+		}
+	}
+
+	if st := summary.Streams; st != nil {
+		query = "INSERT INTO streams(connections,streams,avg_streams,max_concurrent,benchmark_summary) VALUES(?,?,?,?,?)"
+		_, err := tx.ExecContext(ctx, query, st.Connections, st.Streams, st.AvgStreams, st.MaxConcurrent, smId)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create streams for summary: %v", err)
+		}
+	}
+
+	if ws := summary.WebSocket; ws != nil {
+		query = "INSERT INTO websocket_stats(connections,dropped,setup_count,setup_avg,setup_p50,setup_p90,setup_p99,setup_max,benchmark_summary) VALUES(?,?,?,?,?,?,?,?,?)"
+		_, err := tx.ExecContext(ctx, query, ws.Connections, ws.Dropped, ws.Setup.Count, ws.Setup.Avg, ws.Setup.P50, ws.Setup.P90,
+			ws.Setup.P99, ws.Setup.Max, smId)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create WebSocket connections for summary: %v", err)
+		}
+	}
+
+	if st := summary.Streaming; st != nil {
+		query = `INSERT INTO streaming_stats(streams,events,events_per_sec,
+first_event_count,first_event_avg,first_event_p50,first_event_p90,first_event_p99,first_event_max,
+event_gap_count,event_gap_avg,event_gap_p50,event_gap_p90,event_gap_p99,event_gap_max,
+lifetime_count,lifetime_avg,lifetime_p50,lifetime_p90,lifetime_p99,lifetime_max,benchmark_summary)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`
+
+		args := []interface{}{st.Streams, st.Events, st.EventsPerSec}
+		for _, p := range []PhaseStat{st.FirstEvent, st.EventGap, st.Lifetime} {
+			args = append(args, p.Count, p.Avg, p.P50, p.P90, p.P99, p.Max)
+		}
+
+		_, err := tx.ExecContext(ctx, query, append(args, smId)...)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create streaming events for summary: %v", err)
+		}
+	}
+
+	query = "INSERT INTO intervals(offset,duration,requests_count,success_req,fail_req,data_transfered,p50_req_time,p90_req_time,p99_req_time,benchmark_summary) VALUES(?,?,?,?,?,?,?,?,?,?)"
+	for _, in := range summary.Intervals {
+		_, err := tx.ExecContext(ctx, query, in.Offset, in.Duration, in.ReqCount, in.SuccessReq, in.FailReq, in.DataTransfered,
+			in.P50ReqTime, in.P90ReqTime, in.P99ReqTime, smId)
+		if err != nil {
+			return 0, fmt.Errorf("Can't create interval for summary: %v", err)
+		}
+	}
+
+	return smId, nil
+}
+
+// insertStep creates scenario endpoint step with its headers and extracts
+func insertStep(ctx context.Context, tx *sql.Tx, step *Step, endpointID int64) error {
+	query := "INSERT INTO steps(name,url,method,body,endpoint) VALUES(?,?,?,?,?)"
+	res, err := tx.ExecContext(ctx, query, step.Name, step.URL, step.Method, step.Body, endpointID)
+	if err != nil {
+		return fmt.Errorf("Can't create step: %v", err)
+	}
+
+	stepID, err := res.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("Can't commit summary: %v", err)
+		return fmt.Errorf("Can't get step ID: %v", err)
+	}
+
+	query = "INSERT INTO step_headers(header,step) VALUES(?,?)"
+	for key, value := range step.Headers {
+		header := strings.Join([]string{key, value}, ":")
+		_, err := tx.ExecContext(ctx, query, header, stepID)
+		if err != nil {
+			return fmt.Errorf("Can't create step header: %v", err)
+		}
+	}
+
+	query = "INSERT INTO extracts(variable,json,regex,header,cookie,step) VALUES(?,?,?,?,?,?)"
+	for _, e := range step.Extract {
+		_, err := tx.ExecContext(ctx, query, e.Variable, e.JSON, e.Regex, e.Header, e.Cookie, stepID)
+		if err != nil {
+			return fmt.Errorf("Can't create extract: %v", err)
+		}
 	}
 
 	return nil
@@ -474,7 +1379,7 @@ func (i *Inventory) InsertBenchmarkConfiguration(ctx context.Context, benchParam
 		return 0, fmt.Errorf("Can't start transaction: %v", err)
 	}
 
-	query := fmt.Sprintf("INSERT INTO benchmark_configuration(%s) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", benchmarkFields)
+	query := fmt.Sprintf("INSERT INTO benchmark_configuration(%s) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", benchmarkFields)
 
 	res, err := tx.ExecContext(ctx, query,
 		description,
@@ -482,17 +1387,20 @@ func (i *Inventory) InsertBenchmarkConfiguration(ctx context.Context, benchParam
 		benchParameters.Method,
 		benchParameters.ReqCount,
 		benchParameters.ConcurrentConns,
+		benchParameters.Rate,
 		boolToInt(benchParameters.SkipVerify),
 		benchParameters.AbortAfter,
 		benchParameters.CA,
 		benchParameters.Cert,
 		benchParameters.Key,
 		benchParameters.Duration,
+		benchParameters.ReportInterval,
 		benchParameters.KeepAlive,
 		benchParameters.RequestDelay,
 		benchParameters.ReadTimeout,
 		benchParameters.WriteTimeout,
-		benchParameters.Body)
+		benchParameters.Body,
+		benchParameters.Protocol)
 
 	if err != nil {
 		tx.Rollback()
@@ -519,18 +1427,149 @@ func (i *Inventory) InsertBenchmarkConfiguration(ctx context.Context, benchParam
 		return 0, fmt.Errorf("Can't get benchmark configuration ID: %v", err)
 	}
 
-	query = "INSERT INTO headers(header,benchmark_configuration) VALUES(?,?)"
+	if err := insertConfigurationTables(ctx, tx, benchParameters, bcID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("Can't save benchmark configuration: %v", err)
+	}
+
+	return bcID, nil
+}
+
+// UpdateBenchmarkConfiguration replaces benchmark configuration, its summaries are kept
+func (i *Inventory) UpdateBenchmarkConfiguration(ctx context.Context, bcID int64, benchParameters *BenchmarkParameters, description string) error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Can't start transaction: %v", err)
+	}
+
+	query := fmt.Sprintf("UPDATE benchmark_configuration SET (%s) = (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?) WHERE id = ?", benchmarkFields)
+
+	res, err := tx.ExecContext(ctx, query,
+		description,
+		benchParameters.URL,
+		benchParameters.Method,
+		benchParameters.ReqCount,
+		benchParameters.ConcurrentConns,
+		benchParameters.Rate,
+		boolToInt(benchParameters.SkipVerify),
+		benchParameters.AbortAfter,
+		benchParameters.CA,
+		benchParameters.Cert,
+		benchParameters.Key,
+		benchParameters.Duration,
+		benchParameters.ReportInterval,
+		benchParameters.KeepAlive,
+		benchParameters.RequestDelay,
+		benchParameters.ReadTimeout,
+		benchParameters.WriteTimeout,
+		benchParameters.Body,
+		benchParameters.Protocol,
+		bcID)
+
+	if err != nil {
+		tx.Rollback()
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("Benchmark with provided URL and Description already exists")
+		}
+
+		return fmt.Errorf("Can't update benchmark configuration in database: %v", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Can't update benchmark configuration in database: %v", err)
+	}
+
+	if updated == 0 {
+		tx.Rollback()
+		return fmt.Errorf("No benchmark configuration at ID %d", bcID)
+	}
+
+	if err := deleteConfigurationTables(ctx, tx, bcID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertConfigurationTables(ctx, tx, benchParameters, bcID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Can't save benchmark configuration: %v", err)
+	}
+
+	return nil
+}
+
+// deleteSummaryTables deletes baseline and all summaries of benchmark configuration including stage and endpoint summaries
+func deleteSummaryTables(ctx context.Context, tx *sql.Tx, bcID int64) error {
+	summaries := "SELECT id FROM benchmark_summary WHERE benchmark_configuration = ?"
+
+	var queries []string
+	for _, table := range []string{"errors", "status_codes", "phases", "streams", "websocket_stats", "streaming_stats", "intervals"} {
+		queries = append(queries, fmt.Sprintf("DELETE FROM %s WHERE benchmark_summary IN (%s)", table, summaries))
+	}
+
+	queries = append(queries,
+		"DELETE FROM baselines WHERE benchmark_configuration = ?",
+		"DELETE FROM benchmark_summary WHERE benchmark_configuration = ?")
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, bcID); err != nil {
+			return fmt.Errorf("Can't delete benchmark summaries: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteConfigurationTables deletes rows of benchmark configuration in the tables other than benchmark_configuration.
+// Rows are deleted explicitly because foreign keys are not enforced by SQLite by default.
+func deleteConfigurationTables(ctx context.Context, tx *sql.Tx, bcID int64) error {
+	steps := "SELECT steps.id FROM steps JOIN endpoints ON steps.endpoint = endpoints.id WHERE endpoints.benchmark_configuration = ?"
+	endpoints := "SELECT id FROM endpoints WHERE benchmark_configuration = ?"
+
+	queries := []string{
+		"DELETE FROM extracts WHERE step IN (" + steps + ")",
+		"DELETE FROM step_headers WHERE step IN (" + steps + ")",
+		"DELETE FROM steps WHERE endpoint IN (" + endpoints + ")",
+		"DELETE FROM endpoint_headers WHERE endpoint IN (" + endpoints + ")",
+	}
+
+	for _, table := range []string{"endpoints", "headers", "parameters", "stages", "thresholds", "expected_status", "assertions", "feeders", "websockets", "grpc", "streaming"} {
+		queries = append(queries, fmt.Sprintf("DELETE FROM %s WHERE benchmark_configuration = ?", table))
+	}
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, bcID); err != nil {
+			return fmt.Errorf("Can't delete benchmark configuration: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// insertConfigurationTables creates rows of benchmark configuration in the tables other than benchmark_configuration
+func insertConfigurationTables(ctx context.Context, tx *sql.Tx, benchParameters *BenchmarkParameters, bcID int64) error {
+	query := "INSERT INTO headers(header,benchmark_configuration) VALUES(?,?)"
 
 	for key, value := range benchParameters.Headers {
 		header := strings.Join([]string{key, value}, ":")
 		_, err := tx.ExecContext(ctx, query, header, bcID)
 		if err != nil {
-			tx.Rollback()
-			return 0, fmt.Errorf("Can't create header: %v", err)
+			return fmt.Errorf("Can't create header: %v", err)
 		}
 	}
 
-	query = "INSERT INTO parameters(parameter,benchmark_configuration) VALUES(?,?,?)"
+	query = "INSERT INTO parameters(parameter,benchmark_configuration) VALUES(?,?)"
 
 	for _, params := range benchParameters.Parameters {
 		var i int
@@ -544,15 +1583,110 @@ func (i *Inventory) InsertBenchmarkConfiguration(ctx context.Context, benchParam
 		parameterString := strings.Join(parameters, "&")
 		_, err := tx.ExecContext(ctx, query, parameterString, bcID)
 		if err != nil {
-			tx.Rollback()
-			return 0, fmt.Errorf("Can't create parameter: %v", err)
+			return fmt.Errorf("Can't create parameter: %v", err)
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return 0, fmt.Errorf("Can't save benchmark configuration: %v", err)
+	query = "INSERT INTO stages(duration,connections,rate,benchmark_configuration) VALUES(?,?,?,?)"
+
+	for _, stage := range benchParameters.Stages {
+		_, err := tx.ExecContext(ctx, query, stage.Duration, stage.Connections, stage.Rate, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create stage: %v", err)
+		}
 	}
 
-	return bcID, nil
+	for _, endpoint := range benchParameters.Scenario {
+		query = "INSERT INTO endpoints(name,url,method,body,weight,benchmark_configuration) VALUES(?,?,?,?,?,?)"
+		res, err := tx.ExecContext(ctx, query, endpoint.Name, endpoint.URL, endpoint.Method, endpoint.Body, endpoint.Weight, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create endpoint: %v", err)
+		}
+
+		endpointID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("Can't get endpoint ID: %v", err)
+		}
+
+		query = "INSERT INTO endpoint_headers(header,endpoint) VALUES(?,?)"
+		for key, value := range endpoint.Headers {
+			header := strings.Join([]string{key, value}, ":")
+			_, err := tx.ExecContext(ctx, query, header, endpointID)
+			if err != nil {
+				return fmt.Errorf("Can't create endpoint header: %v", err)
+			}
+		}
+
+		for _, step := range endpoint.Steps {
+			if err := insertStep(ctx, tx, &step, endpointID); err != nil {
+				return err
+			}
+		}
+	}
+
+	query = "INSERT INTO thresholds(threshold,benchmark_configuration) VALUES(?,?)"
+
+	for _, threshold := range benchParameters.Thresholds {
+		_, err := tx.ExecContext(ctx, query, threshold, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create threshold: %v", err)
+		}
+	}
+
+	query = "INSERT INTO expected_status(status,benchmark_configuration) VALUES(?,?)"
+
+	for _, status := range benchParameters.ExpectedStatus {
+		_, err := tx.ExecContext(ctx, query, status, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create expected status: %v", err)
+		}
+	}
+
+	query = "INSERT INTO assertions(name,status,contains,regex,json,equals,header,min_size,max_size,benchmark_configuration) VALUES(?,?,?,?,?,?,?,?,?,?)"
+
+	for _, a := range benchParameters.Assertions {
+		_, err := tx.ExecContext(ctx, query, a.Name, strings.Join(a.Status, ","), a.Contains, a.Regex, a.JSON, a.Equals, a.Header,
+			a.MinSize, a.MaxSize, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create assertion: %v", err)
+		}
+	}
+
+	if f := benchParameters.Feeder; f != nil {
+		query = "INSERT INTO feeders(file,format,strategy,exhausted,benchmark_configuration) VALUES(?,?,?,?,?)"
+
+		_, err := tx.ExecContext(ctx, query, f.File, f.Format, f.Strategy, f.Exhausted, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create feeder: %v", err)
+		}
+	}
+
+	if w := benchParameters.WebSocket; w != nil {
+		query = "INSERT INTO websockets(message,expect,benchmark_configuration) VALUES(?,?,?)"
+
+		_, err := tx.ExecContext(ctx, query, w.Message, w.Expect, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create WebSocket: %v", err)
+		}
+	}
+
+	if g := benchParameters.GRPC; g != nil {
+		query = "INSERT INTO grpc(method,payload,descriptor_set,benchmark_configuration) VALUES(?,?,?,?)"
+
+		_, err := tx.ExecContext(ctx, query, g.Method, g.Payload, g.DescriptorSet, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create gRPC: %v", err)
+		}
+	}
+
+	if st := benchParameters.Streaming; st != nil {
+		query = "INSERT INTO streaming(format,events,lifetime,benchmark_configuration) VALUES(?,?,?,?)"
+
+		_, err := tx.ExecContext(ctx, query, st.Format, st.Events, st.Lifetime, bcID)
+		if err != nil {
+			return fmt.Errorf("Can't create streaming: %v", err)
+		}
+	}
+
+	return nil
 }
@@ -2,12 +2,13 @@ package katyusha
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestInventory(t *testing.T) {
@@ -22,10 +23,45 @@ func TestInventory(t *testing.T) {
 		URL:             "http://katyusha.text",
 		ConcurrentConns: 1,
 		ReqCount:        1,
+		ReportInterval:  10 * time.Second,
+		Protocol:        ProtocolHTTP2,
 	}
 
 	b.Headers = map[string]string{}
 	b.Parameters = []map[string]string{}
+	b.Stages = []Stage{
+		{Duration: time.Minute, Connections: 100},
+		{Duration: 10 * time.Minute, Connections: 100},
+		{Duration: time.Minute, Connections: 1},
+	}
+	b.Thresholds = []string{"p99 < 250ms", "error_rate < 0.5%"}
+	b.ExpectedStatus = []string{"2xx", "304"}
+	b.Assertions = []Assertion{
+		{Status: []string{"200", "201-204"}},
+		{Name: "no error", JSON: "error", Equals: stringPtr("false")},
+		{JSON: "note", Equals: stringPtr("")},
+		{JSON: "deleted_at"},
+		{MinSize: 10, MaxSize: 100},
+	}
+	b.Feeder = &Feeder{File: "users.csv", Format: "csv", Strategy: FeederUnique, Exhausted: FeederStop}
+	b.WebSocket = &WebSocket{Message: `{"user": "${user}"}`, Expect: "ok"}
+	b.GRPC = &GRPC{Method: "users.Users/Get", Payload: `{"id": "${user}"}`, DescriptorSet: "users.protoset"}
+	b.Streaming = &Streaming{Format: StreamFormatSSE, Events: 100, Lifetime: time.Minute}
+	b.Scenario = []Endpoint{
+		{Name: "items", URL: "/items", Method: "GET", Weight: 7},
+		{URL: "/orders", Method: "POST", Headers: headers{"Content-Type": "application/json"}, Body: `{"item": 1}`, Weight: 3},
+		{
+			Name:   "profile flow",
+			Weight: 1,
+			Steps: []Step{
+				{Name: "login", URL: "/login", Method: "POST", Body: "user=u", Extract: []Extract{
+					{Variable: "token", JSON: "data.token"},
+					{Variable: "session", Cookie: "session"},
+				}},
+				{URL: "/profile", Headers: headers{"Authorization": "Bearer ${token}"}},
+			},
+		},
+	}
 
 	bcID, err := inv.InsertBenchmarkConfiguration(context.Background(), b, "Test description")
 	if err != nil {
@@ -67,10 +103,70 @@ func TestInventory(t *testing.T) {
 		P75ReqTime:     time.Duration(75 * time.Second),
 		P90ReqTime:     time.Duration(90 * time.Second),
 		P99ReqTime:     time.Duration(99 * time.Second),
+		StdDeviation:   123456.5,
+		AvgLatency:     time.Duration(360 * time.Millisecond),
+		MaxLatency:     time.Duration(2 * time.Second),
+		P50Latency:     time.Duration(51 * time.Second),
+		P75Latency:     time.Duration(76 * time.Second),
+		P90Latency:     time.Duration(91 * time.Second),
+		P99Latency:     time.Duration(100 * time.Second),
 		Errors:         make(map[string]int),
+		StatusCodes:    map[int]int{200: 8547},
+		Streams:        &StreamStat{Connections: 2, Streams: 8547, AvgStreams: 4273.5, MaxConcurrent: 10},
+		WebSocket: &WebSocketStat{Connections: 10, Dropped: 3,
+			Setup: PhaseStat{Count: 10, Avg: 2 * time.Millisecond, P50: 2 * time.Millisecond, P90: 3 * time.Millisecond, P99: 4 * time.Millisecond, Max: 5 * time.Millisecond}},
+		Streaming: &StreamingStat{Streams: 10, Events: 1000, EventsPerSec: 16.5,
+			FirstEvent: PhaseStat{Count: 10, Avg: 20 * time.Millisecond, P50: 20 * time.Millisecond, P90: 30 * time.Millisecond, P99: 40 * time.Millisecond, Max: 50 * time.Millisecond},
+			EventGap:   PhaseStat{Count: 990, Avg: 60 * time.Millisecond, P50: 55 * time.Millisecond, P90: 90 * time.Millisecond, P99: 120 * time.Millisecond, Max: 200 * time.Millisecond},
+			Lifetime:   PhaseStat{Count: 10, Avg: time.Minute, P50: time.Minute, P90: time.Minute, P99: time.Minute, Max: time.Minute}},
+		Phases: Phases{
+			Connect: PhaseStat{Count: 10, Avg: time.Millisecond, P50: time.Millisecond, P90: 2 * time.Millisecond, P99: 3 * time.Millisecond, Max: 4 * time.Millisecond},
+			TTFB:    PhaseStat{Count: 8547, Avg: 300 * time.Millisecond, P50: 250 * time.Millisecond, P90: 400 * time.Millisecond, P99: 900 * time.Millisecond, Max: time.Second},
+		},
+		Intervals: []Interval{
+			{Offset: 0, Duration: time.Second, ReqCount: 300, SuccessReq: 290, FailReq: 10, DataTransfered: 1024,
+				P50ReqTime: 50 * time.Millisecond, P90ReqTime: 90 * time.Millisecond, P99ReqTime: 99 * time.Millisecond},
+			{Offset: time.Second, Duration: 500 * time.Millisecond},
+		},
+		Stages: []*Summary{
+			{
+				Stage:       1,
+				Start:       start,
+				End:         end,
+				TotalTime:   time.Duration(30 * time.Second),
+				ReqCount:    8547,
+				SuccessReq:  8540,
+				FailReq:     7,
+				P99ReqTime:  time.Duration(99 * time.Second),
+				Errors:      map[string]int{"Internal Server Error": 7},
+				StatusCodes: map[int]int{200: 8540, 500: 7},
+			},
+		},
+		Endpoints: []*Summary{
+			{
+				Endpoint:    "items",
+				Start:       start,
+				End:         end,
+				ReqCount:    6000,
+				SuccessReq:  6000,
+				P99ReqTime:  time.Duration(90 * time.Second),
+				Errors:      map[string]int{},
+				StatusCodes: map[int]int{200: 6000},
+			},
+			{
+				Endpoint:    "POST /orders",
+				Start:       start,
+				End:         end,
+				ReqCount:    2547,
+				SuccessReq:  2540,
+				FailReq:     7,
+				Errors:      map[string]int{"Internal Server Error": 7},
+				StatusCodes: map[int]int{201: 2540, 500: 7},
+			},
+		},
 	}
 
-	err = inv.InsertBenchmarkSummary(context.Background(), summary, bcID)
+	_, err = inv.InsertBenchmarkSummary(context.Background(), summary, bcID)
 	if err != nil {
 		t.Errorf("Error inserting benchmark summary: %v", err)
 	}
@@ -80,9 +176,441 @@ func TestInventory(t *testing.T) {
 		t.Fatalf("Coould not receive benchmark summary; %v", err)
 	}
 
-	var r ReqTimes
-	if diff := cmp.Diff(*summary, sm[0].Summary, cmpopts.IgnoreTypes(r)); diff != "" {
+	if diff := cmp.Diff(*summary, sm[0].Summary); diff != "" {
 		t.Errorf("Benchmark summary mismatch (-want +got):\n%s", diff)
 	}
 
+	second := &Summary{ReqCount: 100, Errors: make(map[string]int)}
+	secondID, err := inv.InsertBenchmarkSummary(context.Background(), second, bcID)
+	if err != nil {
+		t.Errorf("Error inserting benchmark summary: %v", err)
+	}
+
+	first, err := inv.FindFirstSummary(context.Background(), bcID)
+	if err != nil || first == nil || first.ID != sm[0].ID {
+		t.Errorf("First summary should be %d got %v (%v)", sm[0].ID, first, err)
+	}
+
+	latest, err := inv.FindLatestSummary(context.Background(), bcID)
+	if err != nil || latest == nil || latest.ID != secondID || latest.ReqCount != 100 {
+		t.Fatalf("Latest summary should have 100 requests got %v (%v)", latest, err)
+	}
+
+	byID, err := inv.FindSummaryByID(context.Background(), latest.ID)
+	if err != nil || byID == nil || byID.ID != latest.ID {
+		t.Errorf("Summary %d not found by ID: %v", latest.ID, err)
+	}
+
+	missing, err := inv.FindSummaryByID(context.Background(), 1000)
+	if err != nil || missing != nil {
+		t.Errorf("Summary 1000 should not exist got %v (%v)", missing, err)
+	}
+
+	baseline, err := inv.FindBaseline(context.Background(), bcID)
+	if err != nil || baseline != nil {
+		t.Errorf("Baseline should not be set got %v (%v)", baseline, err)
+	}
+
+	for _, smID := range []int64{first.ID, secondID} {
+		if err := inv.SetBaseline(context.Background(), smID); err != nil {
+			t.Fatalf("Can't set baseline: %v", err)
+		}
+
+		baseline, err = inv.FindBaseline(context.Background(), bcID)
+		if err != nil || baseline == nil || baseline.ID != smID {
+			t.Errorf("Baseline should be summary %d got %v (%v)", smID, baseline, err)
+		}
+	}
+
+	if err := inv.SetBaseline(context.Background(), 1000); err == nil {
+		t.Errorf("Expected error setting not existing summary as baseline")
+	}
+
+	if err := inv.ClearBaseline(context.Background(), bcID); err != nil {
+		t.Fatalf("Can't clear baseline: %v", err)
+	}
+
+	baseline, err = inv.FindBaseline(context.Background(), bcID)
+	if err != nil || baseline != nil {
+		t.Errorf("Baseline should be cleared got %v (%v)", baseline, err)
+	}
+}
+
+func TestUpdateBenchmarkConfiguration(t *testing.T) {
+	inv, err := NewInventory("update.db")
+	if err != nil {
+		t.Fatalf("Can't create database file: %v", err)
+	}
+
+	defer os.Remove("update.db")
+
+	ctx := context.Background()
+	b := &BenchmarkParameters{
+		URL:             "http://katyusha.text",
+		ConcurrentConns: 1,
+		ReqCount:        1,
+		Headers:         headers{"X-Test": "1"},
+		Parameters:      []map[string]string{},
+		Thresholds:      []string{"p99 < 250ms"},
+		Feeder:          &Feeder{File: "users.csv"},
+		Scenario: []Endpoint{
+			{Name: "flow", Weight: 1, Steps: []Step{{URL: "/login", Extract: []Extract{{Variable: "token", JSON: "token"}}}}},
+		},
+	}
+
+	bcID, err := inv.InsertBenchmarkConfiguration(ctx, b, "First")
+	if err != nil {
+		t.Fatalf("Error inserting benchmark configuration: %v", err)
+	}
+
+	otherID, err := inv.InsertBenchmarkConfiguration(ctx, b, "Second")
+	if err != nil {
+		t.Fatalf("Error inserting benchmark configuration: %v", err)
+	}
+
+	if _, err := inv.InsertBenchmarkSummary(ctx, &Summary{ReqCount: 1, Errors: map[string]int{}}, bcID); err != nil {
+		t.Fatalf("Error inserting benchmark summary: %v", err)
+	}
+
+	updated := &BenchmarkParameters{
+		URL:             "http://katyusha.text/v2",
+		Method:          "POST",
+		ConcurrentConns: 10,
+		Duration:        time.Minute,
+		Headers:         headers{"X-Test": "2"},
+		Parameters:      []map[string]string{},
+		Stages:          []Stage{{Duration: time.Minute, Connections: 20}},
+		Streaming:       &Streaming{Format: StreamFormatLines},
+	}
+
+	if err := inv.UpdateBenchmarkConfiguration(ctx, bcID, updated, "Updated"); err != nil {
+		t.Fatalf("Can't update benchmark configuration: %v", err)
+	}
+
+	bcs, err := inv.FindBenchmarkByID(ctx, bcID)
+	if err != nil || len(bcs) != 1 {
+		t.Fatalf("Updated benchmark configuration not found: %v", err)
+	}
+
+	if diff := cmp.Diff(*updated, bcs[0].BenchmarkParameters); diff != "" || bcs[0].Description != "Updated" {
+		t.Errorf("Benchmark parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	// Other configuration is not changed
+	bcs, err = inv.FindBenchmarkByID(ctx, otherID)
+	if err != nil || len(bcs) != 1 {
+		t.Fatalf("Benchmark configuration not found: %v", err)
+	}
+
+	if diff := cmp.Diff(*b, bcs[0].BenchmarkParameters); diff != "" {
+		t.Errorf("Benchmark parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	sm, err := inv.FindSummaryForBenchmark(ctx, bcID)
+	if err != nil || len(sm) != 1 {
+		t.Errorf("Summaries should be kept after update: %v (%v)", sm, err)
+	}
+
+	if err := inv.UpdateBenchmarkConfiguration(ctx, otherID, updated, "Updated"); err == nil {
+		t.Errorf("Expected error updating configuration to URL and description of another one")
+	}
+
+	if err := inv.UpdateBenchmarkConfiguration(ctx, 1000, updated, "Missing"); err == nil {
+		t.Errorf("Expected error updating not existing configuration")
+	}
+}
+
+// tableRows returns the number of rows of every table
+func tableRows(t *testing.T, db *sql.DB) map[string]int {
+	t.Helper()
+
+	rows := make(map[string]int)
+	for table := range tableColumns(t, db) {
+		var n int
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&n); err != nil {
+			t.Fatalf("Can't count rows of %s: %v", table, err)
+		}
+
+		rows[table] = n
+	}
+
+	return rows
+}
+
+func TestDeleteBenchmark(t *testing.T) {
+	inv, err := NewInventory("delete.db")
+	if err != nil {
+		t.Fatalf("Can't create database file: %v", err)
+	}
+
+	defer os.Remove("delete.db")
+
+	ctx := context.Background()
+	b := &BenchmarkParameters{
+		URL:             "http://katyusha.text",
+		ConcurrentConns: 1,
+		ReqCount:        1,
+		Headers:         headers{"X-Test": "1"},
+		Parameters:      []map[string]string{{"user": "u"}},
+		Stages:          []Stage{{Duration: time.Minute, Connections: 10}},
+		Thresholds:      []string{"p99 < 250ms"},
+		ExpectedStatus:  []string{"2xx"},
+		Assertions:      []Assertion{{Contains: "ok"}},
+		Feeder:          &Feeder{File: "users.csv"},
+		WebSocket:       &WebSocket{Message: "ping"},
+		GRPC:            &GRPC{Method: "users.Users/Get"},
+		Streaming:       &Streaming{Format: StreamFormatSSE},
+		Scenario: []Endpoint{
+			{URL: "/items", Headers: headers{"Accept": "application/json"}, Weight: 1},
+			{Name: "flow", Weight: 1, Steps: []Step{
+				{URL: "/login", Headers: headers{"X-Step": "1"}, Extract: []Extract{{Variable: "token", JSON: "token"}}},
+			}},
+		},
+	}
+
+	summary := &Summary{
+		ReqCount:    10,
+		Errors:      map[string]int{"timeout": 1},
+		StatusCodes: map[int]int{200: 9},
+		Phases:      Phases{Connect: PhaseStat{Count: 1, Max: time.Millisecond}},
+		Streams:     &StreamStat{Connections: 1, Streams: 10},
+		WebSocket:   &WebSocketStat{Connections: 1},
+		Streaming:   &StreamingStat{Streams: 10},
+		Intervals:   []Interval{{Duration: time.Second, ReqCount: 10}},
+		Stages:      []*Summary{{Stage: 0, ReqCount: 10, Errors: map[string]int{"timeout": 1}}},
+		Endpoints:   []*Summary{{Endpoint: "GET /items", ReqCount: 10, Errors: map[string]int{}, StatusCodes: map[int]int{200: 9}}},
+	}
+
+	keptID, err := inv.InsertBenchmarkConfiguration(ctx, b, "Kept")
+	if err != nil {
+		t.Fatalf("Error inserting benchmark configuration: %v", err)
+	}
+
+	if _, err := inv.InsertBenchmarkSummary(ctx, summary, keptID); err != nil {
+		t.Fatalf("Error inserting benchmark summary: %v", err)
+	}
+
+	rows := tableRows(t, inv.db)
+
+	bcID, err := inv.InsertBenchmarkConfiguration(ctx, b, "Deleted")
+	if err != nil {
+		t.Fatalf("Error inserting benchmark configuration: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		smID, err := inv.InsertBenchmarkSummary(ctx, summary, bcID)
+		if err != nil {
+			t.Fatalf("Error inserting benchmark summary: %v", err)
+		}
+
+		if err := inv.SetBaseline(ctx, smID); err != nil {
+			t.Fatalf("Can't set baseline: %v", err)
+		}
+	}
+
+	if err := inv.DeleteBenchmark(ctx, bcID); err != nil {
+		t.Fatalf("Can't delete benchmark configuration: %v", err)
+	}
+
+	// Only rows of the kept configuration are left
+	if diff := cmp.Diff(rows, tableRows(t, inv.db)); diff != "" {
+		t.Errorf("Rows of deleted benchmark configuration are left (-want +got):\n%s", diff)
+	}
+
+	bcs, err := inv.FindBenchmarkByID(ctx, keptID)
+	if err != nil || len(bcs) != 1 {
+		t.Fatalf("Kept benchmark configuration not found: %v", err)
+	}
+
+	if diff := cmp.Diff(*b, bcs[0].BenchmarkParameters); diff != "" {
+		t.Errorf("Benchmark parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	sm, err := inv.FindSummaryForBenchmark(ctx, keptID)
+	if err != nil || len(sm) != 1 || len(sm[0].Stages) != 1 || len(sm[0].Endpoints) != 1 {
+		t.Errorf("Summary of kept benchmark configuration should not change: %v (%v)", sm, err)
+	}
+}
+
+// schemaV0 is the schema of inventory created before schema versioning
+const schemaV0 = `CREATE TABLE benchmark_configuration (
+    id INTEGER PRIMARY KEY,
+    description TEXT,
+    url TEXT,
+    method TEXT,
+    requests_count INTEGER,
+    concurrent_conns INTEGER,
+    skip_verify INTEGER,
+    abort_after INTEGER,
+    ca TEXT, 
+    cert TEXT,
+    key TEXT,
+    duration TEXT,
+    keep_alive TEXT,
+    request_delay TEXT,
+    read_timeout TEXT,
+    write_timeout TEXT,
+    body BLOB,
+    UNIQUE(description,url)
+);
+
+CREATE TABLE headers (
+    id INTEGER PRIMARY KEY,
+    header TEXT,
+    benchmark_configuration INTEGER,    
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE parameters (
+    id INTEGER PRIMARY KEY,
+    parameter TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id) 
+    ON DELETE CASCADE
+);
+
+CREATE TABLE benchmark_summary (
+    id INTEGER PRIMARY KEY,
+    start TEXT,
+    end TEXT,
+    duration TEXT,
+    requests_count INTEGER,
+    success_req INTEGER,
+    fail_req INTEGER,
+    data_transfered INTEGER,
+    req_per_sec REAL,
+    avg_req_time TEXT,
+    min_req_time TEXT,
+    max_req_time TEXT,
+    p50_req_time TEXT,
+    p75_req_time TEXT,
+    p90_req_time TEXT,
+    p99_req_time TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE errors (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    count INTEGER,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id) 
+    ON DELETE CASCADE
+);`
+
+// tableColumns returns columns of every table of the inventory
+func tableColumns(t *testing.T, db *sql.DB) map[string][]string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p WHERE m.type = 'table' ORDER BY m.name, p.name")
+	if err != nil {
+		t.Fatalf("Can't read tables: %v", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			t.Fatalf("Can't read columns: %v", err)
+		}
+
+		columns[table] = append(columns[table], column)
+	}
+
+	return columns
+}
+
+func TestInventoryMigration(t *testing.T) {
+	defer os.Remove("migration.db")
+	defer os.Remove("latest.db")
+
+	db, err := sql.Open("sqlite3", "migration.db")
+	if err != nil {
+		t.Fatalf("Can't create database file: %v", err)
+	}
+
+	if _, err := db.Exec(schemaV0); err != nil {
+		t.Fatalf("Can't create schema: %v", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO benchmark_configuration(description,url,method,requests_count,concurrent_conns,skip_verify,abort_after,ca,cert,key,duration,keep_alive,request_delay,read_timeout,write_timeout,body)
+VALUES('Old','http://katyusha.test','GET',10,2,0,0,'','','',0,30000000000,0,0,0,'')`)
+	if err != nil {
+		t.Fatalf("Can't insert benchmark configuration: %v", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO benchmark_summary(start,end,duration,requests_count,success_req,fail_req,data_transfered,req_per_sec,avg_req_time,min_req_time,max_req_time,p50_req_time,p75_req_time,p90_req_time,p99_req_time,benchmark_configuration)
+VALUES('2020-03-07T18:57:46+01:00','2020-03-07T18:58:10+01:00',24000000000,10,10,0,100,0.4,1000000,1000000,1000000,1000000,1000000,1000000,1000000,1)`)
+	if err != nil {
+		t.Fatalf("Can't insert benchmark summary: %v", err)
+	}
+	db.Close()
+
+	inv, err := NewInventory("migration.db")
+	if err != nil {
+		t.Fatalf("Can't upgrade inventory: %v", err)
+	}
+
+	latest, err := NewInventory("latest.db")
+	if err != nil {
+		t.Fatalf("Can't create database file: %v", err)
+	}
+
+	if diff := cmp.Diff(tableColumns(t, latest.db), tableColumns(t, inv.db)); diff != "" {
+		t.Errorf("Upgraded schema mismatch (-want +got):\n%s", diff)
+	}
+
+	ctx := context.Background()
+	bcs, err := inv.FindBenchmarkByID(ctx, 1)
+	if err != nil || len(bcs) != 1 || bcs[0].Description != "Old" || bcs[0].KeepAlive != 30*time.Second {
+		t.Fatalf("Benchmark configuration should be read after upgrade: %v (%v)", bcs, err)
+	}
+
+	sm, err := inv.FindSummaryForBenchmark(ctx, 1)
+	if err != nil || len(sm) != 1 || sm[0].ReqCount != 10 || sm[0].P99ReqTime != time.Millisecond {
+		t.Fatalf("Benchmark summary should be read after upgrade: %v (%v)", sm, err)
+	}
+
+	// New columns and tables can be used
+	b := bcs[0].BenchmarkParameters
+	b.Rate = 100
+	b.Protocol = ProtocolHTTP2
+	b.Thresholds = []string{"p99 < 250ms"}
+	if err := inv.UpdateBenchmarkConfiguration(ctx, 1, &b, "Old"); err != nil {
+		t.Errorf("Can't update benchmark configuration: %v", err)
+	}
+
+	if _, err := inv.InsertBenchmarkSummary(ctx, &Summary{ReqCount: 1, ScheduledReq: 1, Errors: map[string]int{}}, 1); err != nil {
+		t.Errorf("Can't insert benchmark summary: %v", err)
+	}
+	inv.db.Close()
+
+	// Upgraded inventory is opened without changes
+	inv, err = NewInventory("migration.db")
+	if err != nil {
+		t.Fatalf("Can't open upgraded inventory: %v", err)
+	}
+
+	var version int
+	if err := inv.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d (%v)", len(migrations), version, err)
+	}
+
+	// Inventory of newer version is not opened
+	if _, err := inv.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations)+1)); err != nil {
+		t.Fatalf("Can't set schema version: %v", err)
+	}
+	inv.db.Close()
+
+	if _, err := NewInventory("migration.db"); err == nil {
+		t.Errorf("Expected error opening inventory of newer schema version")
+	}
 }
@@ -0,0 +1,333 @@
+package katyusha
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// PhaseStat describes distribution of one request phase
+type PhaseStat struct {
+	Count int           `json:"count"` // Number of requests in which the phase was measured
+	Avg   time.Duration `json:"avg"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+func (p PhaseStat) String() string {
+	return fmt.Sprintf("avg %v, p50 %v, p90 %v, p99 %v, max %v (%d requests)", p.Avg, p.P50, p.P90, p.P99, p.Max, p.Count)
+}
+
+// Phases are timings of request phases.
+// DNS, Connect and TLS are measured only for requests which opened a new connection.
+// With HTTP/3 there is no TCP connect and TLS is the QUIC handshake.
+type Phases struct {
+	DNS      PhaseStat `json:"dns"`      // Host name lookup
+	Connect  PhaseStat `json:"connect"`  // TCP connect
+	TLS      PhaseStat `json:"tls"`      // TLS handshake
+	TTFB     PhaseStat `json:"ttfb"`     // From sending the request to the first byte of the response
+	Transfer PhaseStat `json:"transfer"` // From the first byte to the end of the response
+}
+
+// Phase names used in the inventory
+const (
+	PhaseDNS      = "dns"
+	PhaseConnect  = "connect"
+	PhaseTLS      = "tls"
+	PhaseTTFB     = "ttfb"
+	PhaseTransfer = "transfer"
+)
+
+// phasesOrder lists phases in the order they happen
+var phasesOrder = []string{PhaseDNS, PhaseConnect, PhaseTLS, PhaseTTFB, PhaseTransfer}
+
+// phaseLabels are used in the summary text
+var phaseLabels = map[string]string{
+	PhaseDNS:      "DNS lookup:\t\t\t\t",
+	PhaseConnect:  "TCP connect:\t\t\t\t",
+	PhaseTLS:      "TLS handshake:\t\t\t",
+	PhaseTTFB:     "Time to first byte:\t\t\t",
+	PhaseTransfer: "Transfer:\t\t\t\t",
+}
+
+// phase returns statistic of the named phase
+func (p *Phases) phase(name string) *PhaseStat {
+	switch name {
+	case PhaseDNS:
+		return &p.DNS
+	case PhaseConnect:
+		return &p.Connect
+	case PhaseTLS:
+		return &p.TLS
+	case PhaseTTFB:
+		return &p.TTFB
+	case PhaseTransfer:
+		return &p.Transfer
+	}
+
+	return nil
+}
+
+func (p Phases) String() string {
+	var sb strings.Builder
+	for _, name := range phasesOrder {
+		stat := p.phase(name)
+		if stat.Count > 0 {
+			fmt.Fprintf(&sb, "  %s%v\n", phaseLabels[name], stat)
+		}
+	}
+
+	return sb.String()
+}
+
+// phaseTimer measures phases of requests sent by one worker.
+// The worker has its own client, so a connection dialed or read during the request belongs to it.
+type phaseTimer struct {
+	tlsConfig  *tls.Config
+	tlsConfigs map[string]*tls.Config // TLS configuration with server name of each address
+
+	// dial opens connection without DNS lookup, it is used instead of TCP dial when set
+	dial fasthttp.DialFunc
+
+	handshakeTimeout time.Duration
+
+	isTLS bool // Request URL scheme is https
+
+	dialed    bool
+	dns       time.Duration
+	connect   time.Duration
+	handshake time.Duration
+	ready     time.Time // Time when the connection was ready to send the request
+	firstByte time.Time
+}
+
+func newPhaseTimer(tlsConfig *tls.Config, dial fasthttp.DialFunc, handshakeTimeout time.Duration) *phaseTimer {
+	return &phaseTimer{
+		tlsConfig:        tlsConfig,
+		tlsConfigs:       make(map[string]*tls.Config),
+		dial:             dial,
+		handshakeTimeout: handshakeTimeout,
+	}
+}
+
+// start prepares timer for the next request
+func (p *phaseTimer) start(start time.Time, isTLS bool) {
+	p.isTLS = isTLS
+	p.dialed = false
+	p.dns, p.connect, p.handshake = 0, 0, 0
+	p.ready = start
+	p.firstByte = time.Time{}
+}
+
+// setPhases sets phases of the finished request in stat
+func (p *phaseTimer) setPhases(stat *RequestStat) {
+	stat.NewConnection = p.dialed
+	stat.DNS, stat.Connect, stat.TLS = p.dns, p.connect, p.handshake
+
+	if p.firstByte.IsZero() {
+		return
+	}
+
+	stat.TTFB = p.firstByte.Sub(p.ready)
+	stat.Transfer = stat.End.Sub(p.firstByte)
+}
+
+// Dial is used by the worker client to open connections.
+// TLS handshake is done here so it can be measured, the client does not repeat it for tls.Conn.
+func (p *phaseTimer) Dial(addr string) (net.Conn, error) {
+	p.dialed = true
+
+	var conn net.Conn
+	var err error
+
+	if p.dial != nil {
+		start := time.Now()
+		conn, err = p.dial(addr)
+		p.connect = time.Since(start)
+	} else {
+		conn, err = p.dialTCP(addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn = &timedConn{Conn: conn, timer: p}
+
+	if p.isTLS {
+		start := time.Now()
+
+		if p.handshakeTimeout > 0 {
+			conn.SetDeadline(start.Add(p.handshakeTimeout))
+		}
+
+		tlsConn := tls.Client(conn, p.tlsConfigFor(addr))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		conn.SetDeadline(time.Time{})
+
+		p.handshake = time.Since(start)
+		conn = tlsConn
+	}
+
+	// Handshake reads are not the response
+	p.firstByte = time.Time{}
+	p.ready = time.Now()
+
+	return conn, nil
+}
+
+// dialTCP resolves the host and connects to the first address which accepts the connection
+func (p *phaseTimer) dialTCP(addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		start := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), fasthttp.DefaultDialTimeout)
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		cancel()
+
+		p.dns = time.Since(start)
+		if err != nil {
+			return nil, err
+		}
+
+		ips = ips[:0]
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	start := time.Now()
+	defer func() {
+		p.connect = time.Since(start)
+	}()
+
+	dialer := net.Dialer{Timeout: fasthttp.DefaultDialTimeout}
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.Dial("tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// tlsConfigFor returns TLS configuration with server name of addr.
+// Configuration is kept for the address so TLS sessions can be resumed.
+func (p *phaseTimer) tlsConfigFor(addr string) *tls.Config {
+	if c, ok := p.tlsConfigs[addr]; ok {
+		return c
+	}
+
+	var c *tls.Config
+	if p.tlsConfig == nil {
+		c = &tls.Config{}
+	} else {
+		c = p.tlsConfig.Clone()
+	}
+
+	if c.ClientSessionCache == nil {
+		c.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	if c.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		c.ServerName = host
+	}
+
+	p.tlsConfigs[addr] = c
+	return c
+}
+
+// timedConn records the time of the first byte of the response
+type timedConn struct {
+	net.Conn
+	timer *phaseTimer
+}
+
+func (c *timedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.timer.firstByte.IsZero() {
+		c.timer.firstByte = time.Now()
+	}
+
+	return n, err
+}
+
+// phasesCollector aggregates phases of requests
+type phasesCollector struct {
+	histograms map[string]*histogram
+}
+
+func newPhasesCollector() *phasesCollector {
+	c := &phasesCollector{histograms: make(map[string]*histogram)}
+	for _, name := range phasesOrder {
+		c.histograms[name] = newHistogram()
+	}
+
+	return c
+}
+
+// add records phases of one request
+func (c *phasesCollector) add(stat *RequestStat) {
+	if stat.NewConnection {
+		if stat.DNS > 0 {
+			c.histograms[PhaseDNS].record(stat.DNS)
+		}
+
+		if stat.Connect > 0 {
+			c.histograms[PhaseConnect].record(stat.Connect)
+		}
+
+		if stat.TLS > 0 {
+			c.histograms[PhaseTLS].record(stat.TLS)
+		}
+	}
+
+	if stat.TTFB > 0 {
+		c.histograms[PhaseTTFB].record(stat.TTFB)
+		c.histograms[PhaseTransfer].record(stat.Transfer)
+	}
+}
+
+// phases returns statistics of recorded phases
+func (c *phasesCollector) phases() Phases {
+	var p Phases
+	for _, name := range phasesOrder {
+		h := c.histograms[name]
+		if h.count == 0 {
+			continue
+		}
+
+		*p.phase(name) = h.phaseStat()
+	}
+
+	return p
+}
+
+// phaseStat returns distribution of values recorded in the histogram
+func (h *histogram) phaseStat() PhaseStat {
+	s := h.stat()
+	return PhaseStat{Count: int(h.count), Avg: s.avg, P50: s.p50, P90: s.p90, P99: s.p99, Max: s.max}
+}
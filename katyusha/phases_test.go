@@ -0,0 +1,112 @@
+package katyusha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("Test"))
+	}
+
+	tt := []struct {
+		name string
+		tls  bool
+		host string
+	}{
+		{"HTTP", false, "127.0.0.1"},
+		{"HTTP with DNS lookup", false, "localhost"},
+		{"HTTPS", true, "127.0.0.1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var server *httptest.Server
+			if tc.tls {
+				server = httptest.NewTLSServer(http.HandlerFunc(handler))
+			} else {
+				server = httptest.NewServer(http.HandlerFunc(handler))
+			}
+			defer server.Close()
+
+			req := &BenchmarkParameters{
+				URL:             strings.Replace(server.URL, "127.0.0.1", tc.host, 1),
+				ConcurrentConns: 2,
+				ReqCount:        10,
+				SkipVerify:      true,
+			}
+
+			benchmark, err := NewBenchmark(req)
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+			if summary.SuccessReq != 10 {
+				t.Fatalf("All requests should be successful: %v", summary.Errors)
+			}
+
+			phases := summary.Phases
+
+			// Connections are kept alive so only the first request of each worker connects
+			if phases.Connect.Count != 2 {
+				t.Errorf("Expected 2 connects got %d", phases.Connect.Count)
+			}
+
+			if tc.host == "localhost" && phases.DNS.Count != 2 {
+				t.Errorf("Expected 2 DNS lookups got %d", phases.DNS.Count)
+			} else if tc.host != "localhost" && phases.DNS.Count != 0 {
+				t.Errorf("IP address should not be resolved: %d lookups", phases.DNS.Count)
+			}
+
+			if tc.tls && (phases.TLS.Count != 2 || phases.TLS.P50 == 0) {
+				t.Errorf("Expected 2 TLS handshakes got %v", phases.TLS)
+			} else if !tc.tls && phases.TLS.Count != 0 {
+				t.Errorf("Plain HTTP should not have TLS handshakes: %v", phases.TLS)
+			}
+
+			if phases.TTFB.Count != 10 || phases.TTFB.P50 < 20*time.Millisecond {
+				t.Errorf("TTFB should include server time of 20ms: %v", phases.TTFB)
+			}
+
+			if phases.Transfer.Count != 10 || phases.Transfer.Max >= phases.TTFB.P50 {
+				t.Errorf("Transfer of small body should be shorter than TTFB: %v", phases.Transfer)
+			}
+
+			if phases.TTFB.Max > summary.MaxReqTime {
+				t.Errorf("TTFB %v can't be longer than request time %v", phases.TTFB.Max, summary.MaxReqTime)
+			}
+		})
+	}
+}
+
+func TestPhasesConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{URL: url, ConcurrentConns: 1, ReqCount: 3})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.FailReq != 3 || len(summary.StatusCodes) != 0 {
+		t.Errorf("Requests should fail without status code: %d failed, status codes %v", summary.FailReq, summary.StatusCodes)
+	}
+
+	if summary.Phases.TTFB.Count != 0 {
+		t.Errorf("Failed connections should not have TTFB: %v", summary.Phases.TTFB)
+	}
+
+	if summary.Phases.Connect.Count != 3 {
+		t.Errorf("Every request should try to connect: %v", summary.Phases.Connect)
+	}
+}
@@ -0,0 +1,129 @@
+package katyusha
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultProgressInterval is used when ProgressInterval is not set
+const DefaultProgressInterval = time.Second
+
+// Progress describes the state of a running benchmark.
+// It is passed to Benchmark.OnProgress every ProgressInterval.
+type Progress struct {
+	Elapsed   time.Duration `json:"elapsed"`
+	Remaining time.Duration `json:"remaining"` // Estimated time to the end, zero if it is not known
+
+	Stage int `json:"stage"` // Current stage number starting from 1, 0 without stages
+
+	ReqCount   int `json:"requests_count"`
+	SuccessReq int `json:"success_req"`
+	FailReq    int `json:"fail_req"`
+
+	ReqPerSec  float64       `json:"req_per_sec"`  // Requests per second in the last interval
+	P99ReqTime time.Duration `json:"p99_req_time"` // 99th percentile of requests finished in the last interval
+}
+
+func (p Progress) String() string {
+	var stage string
+	if p.Stage != 0 {
+		stage = fmt.Sprintf(" stage %d", p.Stage)
+	}
+
+	return fmt.Sprintf("Elapsed %v remaining %v%s | %.2f req/s | %d requests | %d errors | P99 %v",
+		p.Elapsed.Round(time.Second), p.Remaining.Round(time.Second), stage, p.ReqPerSec, p.ReqCount, p.FailReq, p.P99ReqTime)
+}
+
+// progress tracks requests between progress reports
+type progress struct {
+	b     *Benchmark
+	start time.Time
+	last  time.Time
+
+	reqCount     int
+	requestTimes *histogram
+}
+
+func newProgress(b *Benchmark, start time.Time) *progress {
+	return &progress{
+		b:            b,
+		start:        start,
+		last:         start,
+		requestTimes: newHistogram(),
+	}
+}
+
+// add records request finished in the current interval
+func (p *progress) add(stat *RequestStat) {
+	p.reqCount++
+	p.requestTimes.record(stat.Duration)
+}
+
+// report returns progress based on the total results and requests since the last report
+func (p *progress) report(now time.Time, total *collector) Progress {
+	elapsed := now.Sub(p.start)
+
+	pr := Progress{
+		Elapsed:    elapsed,
+		ReqCount:   total.success + total.fail,
+		SuccessReq: total.success,
+		FailReq:    total.fail,
+		P99ReqTime: p.requestTimes.percentile(99),
+	}
+
+	if d := now.Sub(p.last); d > 0 {
+		pr.ReqPerSec = float64(p.reqCount) / d.Seconds()
+	}
+
+	switch {
+	case len(p.b.Stages) > 0:
+		pr.Remaining = p.b.stagesDuration() - elapsed
+		if l, ok := p.b.loadAt(elapsed); ok {
+			pr.Stage = l.stage + 1
+		}
+	case p.b.Duration != time.Duration(0):
+		pr.Remaining = p.b.Duration - elapsed
+	case pr.ReqCount > 0 && p.b.ReqCount > pr.ReqCount:
+		pr.Remaining = time.Duration(float64(elapsed) / float64(pr.ReqCount) * float64(p.b.ReqCount-pr.ReqCount))
+	}
+
+	if pr.Remaining < 0 {
+		pr.Remaining = 0
+	}
+
+	p.last = now
+	p.reqCount = 0
+	p.requestTimes.reset()
+
+	return pr
+}
+
+// mergeProgress returns progress of the distributed benchmark from progress of agents.
+// Requests and request rates are summed, P99 is the highest of agents.
+func mergeProgress(agents []Progress) Progress {
+	var p Progress
+	for _, a := range agents {
+		if a.Elapsed > p.Elapsed {
+			p.Elapsed = a.Elapsed
+		}
+
+		if a.Remaining > p.Remaining {
+			p.Remaining = a.Remaining
+		}
+
+		if a.Stage > p.Stage {
+			p.Stage = a.Stage
+		}
+
+		if a.P99ReqTime > p.P99ReqTime {
+			p.P99ReqTime = a.P99ReqTime
+		}
+
+		p.ReqCount += a.ReqCount
+		p.SuccessReq += a.SuccessReq
+		p.FailReq += a.FailReq
+		p.ReqPerSec += a.ReqPerSec
+	}
+
+	return p
+}
@@ -0,0 +1,211 @@
+package katyusha
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// results are collected while the benchmark is running and turned into Summary when it ends.
+// Agents send snapshot of their results to the coordinator which merges them.
+type results struct {
+	b *Benchmark
+
+	start     time.Time
+	end       time.Time
+	total     *collector
+	stages    []*collector
+	endpoints []*collector
+	timeline  *timeline
+	intervals []Interval // Set by the timeline when the benchmark ends
+	webSocket *webSocketCollector
+	streaming *streamingCollector
+	streams   *StreamStat
+	sched     scheduleStat
+}
+
+func newResults(b *Benchmark, start time.Time) *results {
+	r := &results{
+		b:        b,
+		start:    start,
+		total:    newCollector(b),
+		stages:   make([]*collector, len(b.Stages)),
+		timeline: newTimeline(start, b.ReportInterval),
+	}
+
+	for i := range r.stages {
+		r.stages[i] = newCollector(b)
+	}
+
+	if len(b.Scenario) > 0 {
+		for _, e := range b.scenario.endpoints {
+			c := newCollector(b)
+			c.url = e.rawURL
+			r.endpoints = append(r.endpoints, c)
+		}
+	}
+
+	if b.webSocket != nil {
+		r.webSocket = newWebSocketCollector()
+	}
+
+	if b.streaming != nil {
+		r.streaming = newStreamingCollector()
+	}
+
+	return r
+}
+
+// add records one request stat
+func (r *results) add(stat *RequestStat) {
+	r.total.add(stat)
+	r.timeline.add(stat)
+	if len(r.stages) > 0 {
+		r.stages[stat.Stage].add(stat)
+	}
+	if len(r.endpoints) > 0 {
+		r.endpoints[stat.Endpoint].add(stat)
+	}
+	if r.webSocket != nil {
+		r.webSocket.add(stat)
+	}
+	if r.streaming != nil {
+		r.streaming.add(stat)
+	}
+}
+
+// summary returns Summary of the finished benchmark
+func (r *results) summary() *Summary {
+	summary := r.total.summary(r.start, r.end)
+	summary.Intervals = r.intervals
+	summary.ScheduledReq = int(atomic.LoadInt64(&r.sched.scheduled))
+	summary.LateReq = int(atomic.LoadInt64(&r.sched.late))
+	summary.DroppedReq = int(atomic.LoadInt64(&r.sched.dropped))
+	summary.Streams = r.streams
+
+	if r.webSocket != nil {
+		summary.WebSocket = r.webSocket.stat()
+	}
+
+	if r.streaming != nil {
+		summary.Streaming = r.streaming.stat()
+	}
+
+	stageStart := r.start
+	for i, stage := range r.stages {
+		if !stageStart.Before(r.end) {
+			break
+		}
+
+		stageEnd := stageStart.Add(r.b.Stages[i].Duration)
+		if stageEnd.After(r.end) {
+			stageEnd = r.end
+		}
+
+		stageSummary := stage.summary(stageStart, stageEnd)
+		stageSummary.Stage = i + 1
+		summary.Stages = append(summary.Stages, stageSummary)
+
+		stageStart = stageEnd
+	}
+
+	for i, e := range r.endpoints {
+		endpointSummary := e.summary(r.start, r.end)
+		endpointSummary.Endpoint = r.b.scenario.endpoints[i].name
+		summary.Endpoints = append(summary.Endpoints, endpointSummary)
+	}
+
+	return summary
+}
+
+// resultsSnapshot is sent by agent to the coordinator when its part of the benchmark ends.
+// Histograms are sent with their buckets so agents and the coordinator have to run the same version.
+type resultsSnapshot struct {
+	Total     collectorSnapshot   `json:"total"`
+	Stages    []collectorSnapshot `json:"stages,omitempty"`
+	Endpoints []collectorSnapshot `json:"endpoints,omitempty"`
+	Intervals []intervalSnapshot  `json:"intervals,omitempty"`
+	WebSocket *webSocketSnapshot  `json:"websocket,omitempty"`
+	Streaming *streamingSnapshot  `json:"streaming,omitempty"`
+	Streams   *StreamStat         `json:"streams,omitempty"`
+
+	Scheduled int64 `json:"scheduled"`
+	Late      int64 `json:"late"`
+	Dropped   int64 `json:"dropped"`
+}
+
+// snapshot returns results of the finished benchmark, the timeline has to keep histograms of intervals
+func (r *results) snapshot() *resultsSnapshot {
+	s := &resultsSnapshot{
+		Total:     r.total.snapshot(),
+		Intervals: r.timeline.snapshot(),
+		Streams:   r.streams,
+		Scheduled: atomic.LoadInt64(&r.sched.scheduled),
+		Late:      atomic.LoadInt64(&r.sched.late),
+		Dropped:   atomic.LoadInt64(&r.sched.dropped),
+	}
+
+	for _, c := range r.stages {
+		s.Stages = append(s.Stages, c.snapshot())
+	}
+
+	for _, c := range r.endpoints {
+		s.Endpoints = append(s.Endpoints, c.snapshot())
+	}
+
+	if r.webSocket != nil {
+		s.WebSocket = r.webSocket.snapshot()
+	}
+
+	if r.streaming != nil {
+		s.Streaming = r.streaming.snapshot()
+	}
+
+	return s
+}
+
+// merge adds results of one agent, intervals are merged separately by the timeline
+func (r *results) merge(s *resultsSnapshot) error {
+	if len(s.Stages) != len(r.stages) || len(s.Endpoints) != len(r.endpoints) {
+		return fmt.Errorf("Agent results have %d stages and %d endpoints, expected %d and %d",
+			len(s.Stages), len(s.Endpoints), len(r.stages), len(r.endpoints))
+	}
+
+	if err := r.total.merge(s.Total); err != nil {
+		return err
+	}
+
+	for i := range s.Stages {
+		if err := r.stages[i].merge(s.Stages[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.Endpoints {
+		if err := r.endpoints[i].merge(s.Endpoints[i]); err != nil {
+			return err
+		}
+	}
+
+	if r.webSocket != nil && s.WebSocket != nil {
+		if err := r.webSocket.merge(s.WebSocket); err != nil {
+			return err
+		}
+	}
+
+	if r.streaming != nil && s.Streaming != nil {
+		if err := r.streaming.merge(s.Streaming); err != nil {
+			return err
+		}
+	}
+
+	if s.Streams != nil {
+		r.streams = mergeStreams(r.streams, s.Streams)
+	}
+
+	r.sched.scheduled += s.Scheduled
+	r.sched.late += s.Late
+	r.sched.dropped += s.Dropped
+
+	return nil
+}
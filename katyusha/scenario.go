@@ -0,0 +1,300 @@
+package katyusha
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Endpoint is a request template of multi-endpoint scenario.
+// Requests are spread between endpoints proportionally to their weights.
+// URL starting with / is relative to BenchmarkParameters.URL and empty Method means BenchmarkParameters.Method.
+// Headers are added to BenchmarkParameters.Headers.
+// Endpoint with Steps sends the steps requests in sequence instead of a single request.
+type Endpoint struct {
+	Name    string  `mapstructure:"name" json:"name"` // Name used in Summary, "<method> <url>" when not set
+	URL     string  `mapstructure:"url" json:"url"`
+	Method  string  `mapstructure:"method" json:"method"`
+	Headers headers `mapstructure:"headers" json:"headers,omitempty"`
+	Body    string  `mapstructure:"body" json:"body,omitempty"`
+	Weight  int     `mapstructure:"weight" json:"weight"` // 1 when not set
+	Steps   []Step  `mapstructure:"steps" json:"steps,omitempty"`
+}
+
+func (e Endpoint) String() string {
+	var s string
+	if e.Name != "" {
+		s = e.Name + ": "
+	}
+
+	if len(e.Steps) > 0 {
+		return fmt.Sprintf("%s%v (weight %d)", s, e.Steps, e.Weight)
+	}
+
+	return fmt.Sprintf("%s%s %s (weight %d)", s, e.Method, e.URL, e.Weight)
+}
+
+// Step is one request of an Endpoint which sends requests in sequence.
+// Values extracted from the step response can be used in URL, Headers and Body of the next steps as ${variable}.
+// When the step fails the next steps are not sent.
+type Step struct {
+	Name    string    `mapstructure:"name" json:"name"` // Name used in Summary, "<method> <url>" when not set
+	URL     string    `mapstructure:"url" json:"url"`
+	Method  string    `mapstructure:"method" json:"method"`
+	Headers headers   `mapstructure:"headers" json:"headers,omitempty"`
+	Body    string    `mapstructure:"body" json:"body,omitempty"`
+	Extract []Extract `mapstructure:"extract" json:"extract,omitempty"`
+}
+
+func (s Step) String() string {
+	if s.Name != "" {
+		return fmt.Sprintf("%s: %s %s", s.Name, s.Method, s.URL)
+	}
+
+	return fmt.Sprintf("%s %s", s.Method, s.URL)
+}
+
+// headerTemplate is a request header with compiled value
+type headerTemplate struct {
+	key   string
+	value *template
+}
+
+// endpoint is a request ready to be sent by workers
+type endpoint struct {
+	name    string
+	rawURL  string // URL with variable references used in Summary
+	url     *template
+	method  string
+	headers []headerTemplate
+	body    *template
+	extract []*extractor
+}
+
+// flowState keeps variables of one flow set by the feeder and extracted by the steps
+type flowState struct {
+	vars map[string]string
+	buf  []byte // Buffer reused to render templates
+}
+
+func newFlowState() *flowState {
+	return &flowState{vars: make(map[string]string)}
+}
+
+// reset removes variables of the previous flow
+func (f *flowState) reset() {
+	for key := range f.vars {
+		delete(f.vars, key)
+	}
+}
+
+// scenario picks flows according to their weights.
+// Flow is a list of endpoints requested in sequence, every endpoint has its own results in Summary.
+type scenario struct {
+	endpoints []endpoint
+	flows     [][]int
+	weights   []int // Cumulative weights of flows
+}
+
+// pick returns endpoints indexes of the flow which should be requested next
+func (s *scenario) pick() []int {
+	if len(s.flows) == 1 {
+		return s.flows[0]
+	}
+
+	r := rand.Intn(s.weights[len(s.weights)-1])
+	for i, w := range s.weights {
+		if r < w {
+			return s.flows[i]
+		}
+	}
+
+	return s.flows[len(s.flows)-1]
+}
+
+// newScenario validates Scenario and prepares requests of the benchmark.
+// Without Scenario it has one endpoint built from URL, Method, Headers and Body.
+// Columns are the variables set by the feeder for every request.
+func newScenario(b *BenchmarkParameters, columns []string) (*scenario, error) {
+	s := &scenario{}
+
+	// variables returns variables known at the start of a flow
+	variables := func() map[string]bool {
+		v := make(map[string]bool)
+		for _, c := range columns {
+			v[c] = true
+		}
+
+		return v
+	}
+
+	if len(b.Scenario) == 0 {
+		e, err := newEndpoint(b, "", b.URL, b.Method, nil, string(b.Body), nil, variables())
+		if err != nil {
+			return nil, err
+		}
+
+		s.endpoints = []endpoint{*e}
+		s.flows = [][]int{{0}}
+		s.weights = []int{1}
+
+		return s, nil
+	}
+
+	names := make(map[string]bool)
+	var total int
+
+	for i, e := range b.Scenario {
+		weight := e.Weight
+		if weight < 0 {
+			return nil, fmt.Errorf("Endpoint %d weight can't be negative: %d", i+1, e.Weight)
+		} else if weight == 0 {
+			weight = 1
+		}
+
+		var flow []int
+		if len(e.Steps) == 0 {
+			ep, err := newEndpoint(b, e.Name, e.URL, e.Method, e.Headers, e.Body, nil, variables())
+			if err != nil {
+				return nil, fmt.Errorf("Endpoint %d: %w", i+1, err)
+			}
+
+			flow = append(flow, len(s.endpoints))
+			s.endpoints = append(s.endpoints, *ep)
+		} else {
+			if e.Name == "" {
+				return nil, fmt.Errorf("Endpoint %d with steps has no name", i+1)
+			}
+
+			if e.URL != "" || e.Body != "" {
+				return nil, fmt.Errorf("Endpoint %s with steps can't have URL or body", e.Name)
+			}
+
+			// Variables set by the feeder and extracted by the previous steps
+			extracted := variables()
+
+			for j, step := range e.Steps {
+				ep, err := newEndpoint(b, step.Name, step.URL, step.Method, mergeHeaders(e.Headers, step.Headers), step.Body,
+					step.Extract, extracted)
+				if err != nil {
+					return nil, fmt.Errorf("Endpoint %s step %d: %w", e.Name, j+1, err)
+				}
+
+				ep.name = fmt.Sprintf("%s: %s", e.Name, ep.name)
+				flow = append(flow, len(s.endpoints))
+				s.endpoints = append(s.endpoints, *ep)
+			}
+		}
+
+		for _, n := range flow {
+			name := s.endpoints[n].name
+			if names[name] {
+				return nil, fmt.Errorf("Endpoint name %s is not unique", name)
+			}
+			names[name] = true
+		}
+
+		total += weight
+		s.weights = append(s.weights, total)
+		s.flows = append(s.flows, flow)
+	}
+
+	return s, nil
+}
+
+// newEndpoint compiles request templates.
+// Variables used in templates have to be in extracted, variables set by extracts are added to it.
+func newEndpoint(b *BenchmarkParameters, name, url, method string, h headers, body string, extracts []Extract, extracted map[string]bool) (*endpoint, error) {
+	if url == "" {
+		return nil, fmt.Errorf("No URL")
+	}
+
+	if strings.HasPrefix(url, "/") {
+		if b.URL == "" {
+			return nil, fmt.Errorf("Relative URL %s but benchmark URL is not set", url)
+		}
+
+		url = strings.TrimSuffix(b.URL, "/") + url
+	}
+
+	if method == "" {
+		method = b.Method
+	}
+
+	if name == "" {
+		n := method
+		if n == "" {
+			n = "GET"
+		}
+
+		name = fmt.Sprintf("%s %s", n, strings.TrimPrefix(url, strings.TrimSuffix(b.URL, "/")))
+	}
+
+	e := &endpoint{
+		name:   name,
+		rawURL: url,
+		method: method,
+	}
+
+	var templates []*template
+	compile := func(value string) (*template, error) {
+		t, err := compileTemplate(value)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, t)
+		return t, nil
+	}
+
+	var err error
+	if e.url, err = compile(url); err != nil {
+		return nil, err
+	}
+
+	if e.body, err = compile(body); err != nil {
+		return nil, err
+	}
+
+	for key, value := range mergeHeaders(b.Headers, h) {
+		t, err := compile(value)
+		if err != nil {
+			return nil, err
+		}
+
+		e.headers = append(e.headers, headerTemplate{key: key, value: t})
+	}
+
+	for _, t := range templates {
+		for _, v := range t.variables() {
+			if !extracted[v] {
+				return nil, fmt.Errorf("Unknown variable %s, variables are set by feeder columns and extracts of the previous steps", v)
+			}
+		}
+	}
+
+	for _, ex := range extracts {
+		extractor, err := newExtractor(ex)
+		if err != nil {
+			return nil, err
+		}
+
+		e.extract = append(e.extract, extractor)
+		extracted[ex.Variable] = true
+	}
+
+	return e, nil
+}
+
+// mergeHeaders returns headers from h with values from override
+func mergeHeaders(h, override headers) headers {
+	merged := NewHeader()
+	for key, value := range h {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,306 @@
+package katyusha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestScenario(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+
+		if r.Header.Get("X-Endpoint") != r.URL.Path {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodPost && string(body) != `{"item": 1}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Path == "/orders" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintf(w, "Test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		Method:          "GET",
+		ConcurrentConns: 4,
+		ReqCount:        2000,
+		Headers:         headers{"X-Endpoint": "/items"},
+		Scenario: []Endpoint{
+			{URL: "/items", Weight: 7},
+			{Name: "item", URL: server.URL + "/items/1", Headers: headers{"X-Endpoint": "/items/1"}, Weight: 2},
+			{URL: "/orders", Method: "POST", Headers: headers{"X-Endpoint": "/orders"}, Body: `{"item": 1}`},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.ReqCount != 2000 {
+		t.Fatalf("Summary should have 2000 requests but it has %d", summary.ReqCount)
+	}
+
+	if len(summary.Endpoints) != 3 {
+		t.Fatalf("Summary should have 3 endpoints but it has %d", len(summary.Endpoints))
+	}
+
+	tt := []struct {
+		name     string
+		path     string
+		expected int // Expected share of requests in percent
+	}{
+		{"GET /items", "GET /items", 70},
+		{"item", "GET /items/1", 20},
+		{"POST /orders", "POST /orders", 10},
+	}
+
+	var reqCount int
+	for i, tc := range tt {
+		e := summary.Endpoints[i]
+		if e.Endpoint != tc.name {
+			t.Errorf("Endpoint %d name should be %s but it is %s", i, tc.name, e.Endpoint)
+		}
+
+		if e.ReqCount != requests[tc.path] {
+			t.Errorf("Endpoint %s reported %d requests but server received %d", tc.name, e.ReqCount, requests[tc.path])
+		}
+
+		share := e.ReqCount * 100 / summary.ReqCount
+		if share < tc.expected-5 || share > tc.expected+5 {
+			t.Errorf("Endpoint %s should get about %d%% of requests but it got %d%%", tc.name, tc.expected, share)
+		}
+
+		reqCount += e.ReqCount
+	}
+
+	if reqCount != summary.ReqCount {
+		t.Errorf("Endpoints requests should sum up to %d but it is %d", summary.ReqCount, reqCount)
+	}
+
+	// Only /orders fails, so headers and body were sent to the right endpoints
+	if summary.Endpoints[0].FailReq != 0 || summary.Endpoints[1].FailReq != 0 {
+		t.Errorf("GET endpoints should not fail: %v %v", summary.Endpoints[0].Errors, summary.Endpoints[1].Errors)
+	}
+
+	if summary.Endpoints[2].Errors["Internal Server Error"] != summary.Endpoints[2].ReqCount {
+		t.Errorf("All POST /orders requests should fail with Internal Server Error: %v", summary.Endpoints[2].Errors)
+	}
+}
+
+func TestInvalidScenario(t *testing.T) {
+	tt := []struct {
+		name   string
+		params BenchmarkParameters
+	}{
+		{"no URL", BenchmarkParameters{Scenario: []Endpoint{{Method: "GET"}}}},
+		{"relative URL without benchmark URL", BenchmarkParameters{Scenario: []Endpoint{{URL: "/items"}}}},
+		{"negative weight", BenchmarkParameters{URL: "http://localhost", Scenario: []Endpoint{{URL: "/items", Weight: -1}}}},
+		{"duplicated name", BenchmarkParameters{URL: "http://localhost", Scenario: []Endpoint{{URL: "/items"}, {URL: "/items"}}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewBenchmark(&tc.params)
+			if err == nil {
+				t.Errorf("Benchmark with invalid scenario should not be created")
+			}
+		})
+	}
+}
+
+func TestScenarioSteps(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3"})
+		w.Header().Set("X-Request-Id", "42")
+		fmt.Fprintf(w, `{"data": {"token": "abc", "roles": ["admin"]}, "user": "id=7;"}`)
+	})
+	mux.HandleFunc("/profile/7", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer abc" || r.URL.Query().Get("session") != "s3" ||
+			string(body) != "request=42&role=admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprintf(w, "Profile")
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 2,
+		ReqCount:        100,
+		Scenario: []Endpoint{
+			{
+				Name: "profile flow",
+				Steps: []Step{
+					{
+						Name:   "login",
+						URL:    "/login",
+						Method: "POST",
+						Extract: []Extract{
+							{Variable: "token", JSON: "data.token"},
+							{Variable: "role", JSON: "data.roles.0"},
+							{Variable: "user", Regex: `id=(\d+);`},
+							{Variable: "request", Header: "X-Request-Id"},
+							{Variable: "session", Cookie: "session"},
+						},
+					},
+					{
+						URL:     "/profile/${user}?session=${session}",
+						Method:  "POST",
+						Headers: headers{"Authorization": "Bearer ${token}"},
+						Body:    "request=${request}&role=${role}",
+					},
+				},
+			},
+			{
+				Name: "missing flow",
+				Steps: []Step{
+					{Name: "missing", URL: "/missing"},
+					{Name: "never sent", URL: "/login"},
+				},
+			},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if len(summary.Endpoints) != 4 {
+		t.Fatalf("Summary should have 4 endpoints but it has %d", len(summary.Endpoints))
+	}
+
+	login, profile, missing, never := summary.Endpoints[0], summary.Endpoints[1], summary.Endpoints[2], summary.Endpoints[3]
+
+	if login.Endpoint != "profile flow: login" || profile.Endpoint != "profile flow: POST /profile/${user}?session=${session}" {
+		t.Errorf("Unexpected steps names %s and %s", login.Endpoint, profile.Endpoint)
+	}
+
+	if login.ReqCount == 0 || login.ReqCount != profile.ReqCount {
+		t.Errorf("Every login (%d) should be followed by profile request (%d)", login.ReqCount, profile.ReqCount)
+	}
+
+	if profile.FailReq != 0 {
+		t.Errorf("Profile requests should use extracted values: %v", profile.Errors)
+	}
+
+	if missing.ReqCount == 0 || missing.FailReq != missing.ReqCount {
+		t.Errorf("All missing step requests should fail: %d of %d", missing.FailReq, missing.ReqCount)
+	}
+
+	if never.ReqCount != 0 {
+		t.Errorf("Step after failed step should not be sent but it was sent %d times", never.ReqCount)
+	}
+
+	if login.ReqCount+missing.ReqCount != 100 {
+		t.Errorf("Benchmark should run 100 flows but it run %d", login.ReqCount+missing.ReqCount)
+	}
+
+	if profile.P99ReqTime == 0 || profile.P99Latency == 0 {
+		t.Errorf("Steps should have own request times")
+	}
+}
+
+func TestScenarioStepsExtractError(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": {}}`)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL,
+		ConcurrentConns: 1,
+		ReqCount:        10,
+		Scenario: []Endpoint{
+			{
+				Name: "flow",
+				Steps: []Step{
+					{Name: "login", URL: "/login", Extract: []Extract{{Variable: "token", JSON: "data.token"}}},
+					{Name: "profile", URL: "/profile", Headers: headers{"Authorization": "Bearer ${token}"}},
+				},
+			},
+		},
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+
+	if summary.Endpoints[0].Errors["Can't extract token"] != 10 {
+		t.Errorf("Login should fail with extract error: %v", summary.Endpoints[0].Errors)
+	}
+
+	if summary.Endpoints[1].ReqCount != 0 {
+		t.Errorf("Profile should not be requested without token")
+	}
+}
+
+func TestInvalidScenarioSteps(t *testing.T) {
+	tt := []struct {
+		name  string
+		steps []Step
+	}{
+		{"unknown variable", []Step{{URL: "/profile/${user}"}}},
+		{"variable from the same step", []Step{{URL: "/profile/${user}", Extract: []Extract{{Variable: "user", Header: "X-User"}}}}},
+		{"unterminated variable", []Step{{URL: "/profile/${user"}}},
+		{"extract without source", []Step{{URL: "/login", Extract: []Extract{{Variable: "user"}}}}},
+		{"extract with two sources", []Step{{URL: "/login", Extract: []Extract{{Variable: "user", Header: "X-User", Cookie: "user"}}}}},
+		{"invalid regex", []Step{{URL: "/login", Extract: []Extract{{Variable: "user", Regex: "(["}}}}},
+		{"generator variable", []Step{{URL: "/login", Extract: []Extract{{Variable: "uuid", Header: "X-User"}}}, {URL: "/profile/${uuid}"}}},
+		{"unused generator variable", []Step{{URL: "/login", Extract: []Extract{{Variable: "seq", JSON: "data.seq"}}}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			params := &BenchmarkParameters{URL: "http://localhost", Scenario: []Endpoint{{Name: "flow", Steps: tc.steps}}}
+			_, err := NewBenchmark(params)
+			if err == nil {
+				t.Errorf("Benchmark with invalid steps should not be created")
+			}
+		})
+	}
+
+	_, err := NewBenchmark(&BenchmarkParameters{URL: "http://localhost", Scenario: []Endpoint{{Steps: []Step{{URL: "/"}}}}})
+	if err == nil {
+		t.Errorf("Endpoint with steps and without name should not be created")
+	}
+}
@@ -1,7 +1,7 @@
 package katyusha
 
-var summaryFields = "start,end,duration,requests_count,success_req,fail_req,data_transfered,req_per_sec,avg_req_time,min_req_time,max_req_time,p50_req_time,p75_req_time,p90_req_time,p99_req_time"
-var benchmarkFields = "description,url,method,requests_count,concurrent_conns,skip_verify,abort_after,ca,cert,key,duration,keep_alive,request_delay,read_timeout,write_timeout,body"
+var summaryFields = "start,end,duration,requests_count,success_req,fail_req,data_transfered,req_per_sec,avg_req_time,min_req_time,max_req_time,p50_req_time,p75_req_time,p90_req_time,p99_req_time,std_deviation,avg_latency,max_latency,p50_latency,p75_latency,p90_latency,p99_latency,scheduled_req,late_req,dropped_req,stage,endpoint"
+var benchmarkFields = "description,url,method,requests_count,concurrent_conns,rate,skip_verify,abort_after,ca,cert,key,duration,report_interval,keep_alive,request_delay,read_timeout,write_timeout,body,protocol"
 
 var schema = `CREATE TABLE benchmark_configuration (
     id INTEGER PRIMARY KEY,
@@ -10,17 +10,20 @@ var schema = `CREATE TABLE benchmark_configuration (
     method TEXT,
     requests_count INTEGER,
     concurrent_conns INTEGER,
+    rate INTEGER,
     skip_verify INTEGER,
     abort_after INTEGER,
     ca TEXT, 
     cert TEXT,
     key TEXT,
     duration TEXT,
+    report_interval TEXT,
     keep_alive TEXT,
     request_delay TEXT,
     read_timeout TEXT,
     write_timeout TEXT,
     body BLOB,
+    protocol TEXT,
     UNIQUE(description,url)
 );
 
@@ -42,6 +45,152 @@ CREATE TABLE parameters (
     ON DELETE CASCADE
 );
 
+CREATE TABLE stages (
+    id INTEGER PRIMARY KEY,
+    duration TEXT,
+    connections INTEGER,
+    rate INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE endpoints (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    url TEXT,
+    method TEXT,
+    body BLOB,
+    weight INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE endpoint_headers (
+    id INTEGER PRIMARY KEY,
+    header TEXT,
+    endpoint INTEGER,
+
+    FOREIGN KEY(endpoint) REFERENCES endpoints(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE steps (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    url TEXT,
+    method TEXT,
+    body BLOB,
+    endpoint INTEGER,
+
+    FOREIGN KEY(endpoint) REFERENCES endpoints(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE step_headers (
+    id INTEGER PRIMARY KEY,
+    header TEXT,
+    step INTEGER,
+
+    FOREIGN KEY(step) REFERENCES steps(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE extracts (
+    id INTEGER PRIMARY KEY,
+    variable TEXT,
+    json TEXT,
+    regex TEXT,
+    header TEXT,
+    cookie TEXT,
+    step INTEGER,
+
+    FOREIGN KEY(step) REFERENCES steps(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE thresholds (
+    id INTEGER PRIMARY KEY,
+    threshold TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE assertions (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    status TEXT,
+    contains TEXT,
+    regex TEXT,
+    json TEXT,
+    equals TEXT,
+    header TEXT,
+    min_size INTEGER,
+    max_size INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE expected_status (
+    id INTEGER PRIMARY KEY,
+    status TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE feeders (
+    id INTEGER PRIMARY KEY,
+    file TEXT,
+    format TEXT,
+    strategy TEXT,
+    exhausted TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE websockets (
+    id INTEGER PRIMARY KEY,
+    message TEXT,
+    expect TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE grpc (
+    id INTEGER PRIMARY KEY,
+    method TEXT,
+    payload TEXT,
+    descriptor_set TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE streaming (
+    id INTEGER PRIMARY KEY,
+    format TEXT,
+    events INTEGER,
+    lifetime TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
 CREATE TABLE benchmark_summary (
     id INTEGER PRIMARY KEY,
     start TEXT,
@@ -59,9 +208,51 @@ CREATE TABLE benchmark_summary (
     p75_req_time TEXT,
     p90_req_time TEXT,
     p99_req_time TEXT,
+    std_deviation REAL,
+    avg_latency TEXT,
+    max_latency TEXT,
+    p50_latency TEXT,
+    p75_latency TEXT,
+    p90_latency TEXT,
+    p99_latency TEXT,
+    scheduled_req INTEGER,
+    late_req INTEGER,
+    dropped_req INTEGER,
+    stage INTEGER,
+    endpoint TEXT,
     benchmark_configuration INTEGER,
+    parent_summary INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(parent_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE baselines (
+    benchmark_configuration INTEGER PRIMARY KEY,
+    benchmark_summary INTEGER,
 
     FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE intervals (
+    id INTEGER PRIMARY KEY,
+    offset TEXT,
+    duration TEXT,
+    requests_count INTEGER,
+    success_req INTEGER,
+    fail_req INTEGER,
+    data_transfered INTEGER,
+    p50_req_time TEXT,
+    p90_req_time TEXT,
+    p99_req_time TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
     ON DELETE CASCADE
 );
 
@@ -73,4 +264,386 @@ CREATE TABLE errors (
 
     FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id) 
     ON DELETE CASCADE
+);
+
+CREATE TABLE status_codes (
+    id INTEGER PRIMARY KEY,
+    code INTEGER,
+    count INTEGER,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE phases (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    count INTEGER,
+    avg TEXT,
+    p50 TEXT,
+    p90 TEXT,
+    p99 TEXT,
+    max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE streams (
+    id INTEGER PRIMARY KEY,
+    connections INTEGER,
+    streams INTEGER,
+    avg_streams REAL,
+    max_concurrent INTEGER,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE websocket_stats (
+    id INTEGER PRIMARY KEY,
+    connections INTEGER,
+    dropped INTEGER,
+    setup_count INTEGER,
+    setup_avg TEXT,
+    setup_p50 TEXT,
+    setup_p90 TEXT,
+    setup_p99 TEXT,
+    setup_max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE streaming_stats (
+    id INTEGER PRIMARY KEY,
+    streams INTEGER,
+    events INTEGER,
+    events_per_sec REAL,
+    first_event_count INTEGER,
+    first_event_avg TEXT,
+    first_event_p50 TEXT,
+    first_event_p90 TEXT,
+    first_event_p99 TEXT,
+    first_event_max TEXT,
+    event_gap_count INTEGER,
+    event_gap_avg TEXT,
+    event_gap_p50 TEXT,
+    event_gap_p90 TEXT,
+    event_gap_p99 TEXT,
+    event_gap_max TEXT,
+    lifetime_count INTEGER,
+    lifetime_avg TEXT,
+    lifetime_p50 TEXT,
+    lifetime_p90 TEXT,
+    lifetime_p99 TEXT,
+    lifetime_max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
 );`
+
+// migrations upgrade schema of inventory created by older version, the version of the schema is kept in user_version.
+// Inventory created before versioning has version 0. New inventory is created with schema at the latest version,
+// so every change of the schema needs a new migration at the end.
+// Added columns have defaults because existing rows are scanned into non-NULL values.
+var migrations = []string{
+	// Rate of open-loop benchmark
+	`ALTER TABLE benchmark_configuration ADD COLUMN rate INTEGER DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN scheduled_req INTEGER DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN late_req INTEGER DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN dropped_req INTEGER DEFAULT 0;`,
+
+	// Latency corrected for coordinated omission
+	`ALTER TABLE benchmark_summary ADD COLUMN avg_latency TEXT DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN max_latency TEXT DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN p50_latency TEXT DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN p75_latency TEXT DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN p90_latency TEXT DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN p99_latency TEXT DEFAULT 0;`,
+
+	// Stages
+	`CREATE TABLE stages (
+    id INTEGER PRIMARY KEY,
+    duration TEXT,
+    connections INTEGER,
+    rate INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+ALTER TABLE benchmark_summary ADD COLUMN stage INTEGER DEFAULT 0;
+ALTER TABLE benchmark_summary ADD COLUMN parent_summary INTEGER REFERENCES benchmark_summary(id) ON DELETE CASCADE;`,
+
+	// Standard deviation of request time
+	`ALTER TABLE benchmark_summary ADD COLUMN std_deviation REAL DEFAULT 0;`,
+
+	// Intervals
+	`ALTER TABLE benchmark_configuration ADD COLUMN report_interval TEXT DEFAULT 0;
+CREATE TABLE intervals (
+    id INTEGER PRIMARY KEY,
+    offset TEXT,
+    duration TEXT,
+    requests_count INTEGER,
+    success_req INTEGER,
+    fail_req INTEGER,
+    data_transfered INTEGER,
+    p50_req_time TEXT,
+    p90_req_time TEXT,
+    p99_req_time TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// Thresholds
+	`CREATE TABLE thresholds (
+    id INTEGER PRIMARY KEY,
+    threshold TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);`,
+
+	// Baselines
+	`CREATE TABLE baselines (
+    benchmark_configuration INTEGER PRIMARY KEY,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// Scenario endpoints
+	`CREATE TABLE endpoints (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    url TEXT,
+    method TEXT,
+    body BLOB,
+    weight INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE endpoint_headers (
+    id INTEGER PRIMARY KEY,
+    header TEXT,
+    endpoint INTEGER,
+
+    FOREIGN KEY(endpoint) REFERENCES endpoints(id)
+    ON DELETE CASCADE
+);
+ALTER TABLE benchmark_summary ADD COLUMN endpoint TEXT DEFAULT '';`,
+
+	// Scenario steps and extracts
+	`CREATE TABLE steps (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    url TEXT,
+    method TEXT,
+    body BLOB,
+    endpoint INTEGER,
+
+    FOREIGN KEY(endpoint) REFERENCES endpoints(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE step_headers (
+    id INTEGER PRIMARY KEY,
+    header TEXT,
+    step INTEGER,
+
+    FOREIGN KEY(step) REFERENCES steps(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE extracts (
+    id INTEGER PRIMARY KEY,
+    variable TEXT,
+    json TEXT,
+    regex TEXT,
+    header TEXT,
+    cookie TEXT,
+    step INTEGER,
+
+    FOREIGN KEY(step) REFERENCES steps(id)
+    ON DELETE CASCADE
+);`,
+
+	// Feeders
+	`CREATE TABLE feeders (
+    id INTEGER PRIMARY KEY,
+    file TEXT,
+    format TEXT,
+    strategy TEXT,
+    exhausted TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);`,
+
+	// Assertions
+	`CREATE TABLE assertions (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    status TEXT,
+    contains TEXT,
+    regex TEXT,
+    json TEXT,
+    equals TEXT,
+    header TEXT,
+    min_size INTEGER,
+    max_size INTEGER,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);`,
+
+	// Expected status codes and status code counts
+	`CREATE TABLE expected_status (
+    id INTEGER PRIMARY KEY,
+    status TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE status_codes (
+    id INTEGER PRIMARY KEY,
+    code INTEGER,
+    count INTEGER,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// Request phases
+	`CREATE TABLE phases (
+    id INTEGER PRIMARY KEY,
+    name TEXT,
+    count INTEGER,
+    avg TEXT,
+    p50 TEXT,
+    p90 TEXT,
+    p99 TEXT,
+    max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// Protocol and HTTP/2 streams
+	`ALTER TABLE benchmark_configuration ADD COLUMN protocol TEXT DEFAULT '';
+CREATE TABLE streams (
+    id INTEGER PRIMARY KEY,
+    connections INTEGER,
+    streams INTEGER,
+    avg_streams REAL,
+    max_concurrent INTEGER,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// WebSocket
+	`CREATE TABLE websockets (
+    id INTEGER PRIMARY KEY,
+    message TEXT,
+    expect TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE websocket_stats (
+    id INTEGER PRIMARY KEY,
+    connections INTEGER,
+    dropped INTEGER,
+    setup_count INTEGER,
+    setup_avg TEXT,
+    setup_p50 TEXT,
+    setup_p90 TEXT,
+    setup_p99 TEXT,
+    setup_max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// gRPC
+	`CREATE TABLE grpc (
+    id INTEGER PRIMARY KEY,
+    method TEXT,
+    payload TEXT,
+    descriptor_set TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);`,
+
+	// Streaming
+	`CREATE TABLE streaming (
+    id INTEGER PRIMARY KEY,
+    format TEXT,
+    events INTEGER,
+    lifetime TEXT,
+    benchmark_configuration INTEGER,
+
+    FOREIGN KEY(benchmark_configuration) REFERENCES benchmark_configuration(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE streaming_stats (
+    id INTEGER PRIMARY KEY,
+    streams INTEGER,
+    events INTEGER,
+    events_per_sec REAL,
+    first_event_count INTEGER,
+    first_event_avg TEXT,
+    first_event_p50 TEXT,
+    first_event_p90 TEXT,
+    first_event_p99 TEXT,
+    first_event_max TEXT,
+    event_gap_count INTEGER,
+    event_gap_avg TEXT,
+    event_gap_p50 TEXT,
+    event_gap_p90 TEXT,
+    event_gap_p99 TEXT,
+    event_gap_max TEXT,
+    lifetime_count INTEGER,
+    lifetime_avg TEXT,
+    lifetime_p50 TEXT,
+    lifetime_p90 TEXT,
+    lifetime_p99 TEXT,
+    lifetime_max TEXT,
+    benchmark_summary INTEGER,
+
+    FOREIGN KEY(benchmark_summary) REFERENCES benchmark_summary(id)
+    ON DELETE CASCADE
+);`,
+
+	// Assertions without equals have NULL, empty string is a value
+	`UPDATE assertions SET equals = NULL WHERE equals = ''`,
+}
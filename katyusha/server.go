@@ -0,0 +1,585 @@
+package katyusha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of benchmark runs started by Server
+const (
+	RunRunning   = "running"
+	RunFinished  = "finished"
+	RunCancelled = "cancelled" // Run was cancelled, its summary has results collected so far
+	RunFailed    = "failed"    // Summary of the run could not be saved
+)
+
+// Run is a benchmark run started by Server
+type Run struct {
+	ID            int64      `json:"id"`
+	Configuration int64      `json:"configuration"` // Benchmark configuration ID
+	Status        string     `json:"status"`
+	Start         time.Time  `json:"start"`
+	End           *time.Time `json:"end,omitempty"`
+
+	Progress   *Progress         `json:"progress,omitempty"`   // Last progress of the running benchmark
+	Summary    int64             `json:"summary,omitempty"`    // ID of the saved summary when the run ended
+	Thresholds []ThresholdResult `json:"thresholds,omitempty"` // Thresholds of the configuration checked against the summary
+	Error      string            `json:"error,omitempty"`
+}
+
+// serverRun is the run with its state used by the server
+type serverRun struct {
+	Run
+
+	cancel  context.CancelFunc
+	changed chan struct{} // Closed and replaced when the run changes
+}
+
+// notify wakes up clients streaming progress of the run, server mutex has to be held
+func (r *serverRun) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// apiError is the body of error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// Server provides REST API to manage benchmark configurations of the Inventory, run them and get their summaries.
+// It runs one benchmark at a time so runs don't distort results of each other, summaries of runs are saved in the Inventory.
+// Other paths serve the web dashboard which uses the API.
+//
+//	GET    /api/benchmarks                  lists benchmark configurations
+//	POST   /api/benchmarks                  creates benchmark configuration
+//	GET    /api/benchmarks/{id}             returns benchmark configuration
+//	PUT    /api/benchmarks/{id}             replaces benchmark configuration
+//	DELETE /api/benchmarks/{id}             deletes benchmark configuration with its summaries
+//	GET    /api/benchmarks/{id}/summaries   lists summaries of benchmark configuration
+//	POST   /api/benchmarks/{id}/runs        starts run of benchmark configuration
+//	GET    /api/runs                        lists runs
+//	GET    /api/runs/{id}                   returns run with its last progress
+//	GET    /api/runs/{id}/progress          streams the run as Server-Sent Events until it ends
+//	POST   /api/runs/{id}/cancel            cancels the run
+//	GET    /api/summaries/{id}              returns summary
+//	GET    /api/compare?base=&current=      compares current summary against base summary, tolerance is optional
+type Server struct {
+	inv       *Inventory
+	dashboard http.Handler
+
+	mu      sync.Mutex
+	runs    map[int64]*serverRun
+	lastRun int64
+	running *serverRun
+	wg      sync.WaitGroup
+}
+
+func NewServer(inv *Inventory) *Server {
+	return &Server{
+		inv:       inv,
+		dashboard: newDashboard(),
+		runs:      make(map[int64]*serverRun),
+	}
+}
+
+// Close cancels the running benchmark and waits until its summary is saved
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.running != nil {
+		s.running.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "api" {
+		methods(w, r, map[string]func(){
+			http.MethodGet:  func() { s.dashboard.ServeHTTP(w, r) },
+			http.MethodHead: func() { s.dashboard.ServeHTTP(w, r) },
+		})
+		return
+	}
+
+	if len(parts) < 2 {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s", r.URL.Path))
+		return
+	}
+	parts = parts[1:]
+
+	var id int64
+	if len(parts) > 1 {
+		var err error
+		id, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("Invalid ID %s", parts[1]))
+			return
+		}
+	}
+
+	route := parts[0]
+	if len(parts) > 2 {
+		route += "/" + parts[2]
+	}
+
+	switch {
+	case len(parts) == 1 && route == "benchmarks":
+		methods(w, r, map[string]func(){
+			http.MethodGet:  func() { s.listBenchmarks(w, r) },
+			http.MethodPost: func() { s.createBenchmark(w, r) },
+		})
+	case len(parts) == 2 && route == "benchmarks":
+		methods(w, r, map[string]func(){
+			http.MethodGet:    func() { s.getBenchmark(w, r, id) },
+			http.MethodPut:    func() { s.updateBenchmark(w, r, id) },
+			http.MethodDelete: func() { s.deleteBenchmark(w, r, id) },
+		})
+	case len(parts) == 3 && route == "benchmarks/summaries":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.listSummaries(w, r, id) },
+		})
+	case len(parts) == 3 && route == "benchmarks/runs":
+		methods(w, r, map[string]func(){
+			http.MethodPost: func() { s.startRun(w, r, id) },
+		})
+	case len(parts) == 1 && route == "runs":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.listRuns(w) },
+		})
+	case len(parts) == 2 && route == "runs":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.getRun(w, id) },
+		})
+	case len(parts) == 3 && route == "runs/progress":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.streamRun(w, r, id) },
+		})
+	case len(parts) == 3 && route == "runs/cancel":
+		methods(w, r, map[string]func(){
+			http.MethodPost: func() { s.cancelRun(w, id) },
+		})
+	case len(parts) == 2 && route == "summaries":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.getSummary(w, r, id) },
+		})
+	case len(parts) == 1 && route == "compare":
+		methods(w, r, map[string]func(){
+			http.MethodGet: func() { s.compareSummaries(w, r) },
+		})
+	default:
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s", r.URL.Path))
+	}
+}
+
+// methods calls handler of the request method
+func methods(w http.ResponseWriter, r *http.Request, handlers map[string]func()) {
+	if handler, ok := handlers[r.Method]; ok {
+		handler()
+		return
+	}
+
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+}
+
+func (s *Server) listBenchmarks(w http.ResponseWriter, r *http.Request) {
+	bcs, err := s.inv.FindAllBenchmarks(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if bcs == nil {
+		bcs = []*BenchmarkConfiguration{}
+	}
+
+	writeJSON(w, http.StatusOK, bcs)
+}
+
+// findBenchmark returns benchmark configuration or writes error response when it is not found
+func (s *Server) findBenchmark(w http.ResponseWriter, r *http.Request, id int64) *BenchmarkConfiguration {
+	bcs, err := s.inv.FindBenchmarkByID(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return nil
+	}
+
+	if len(bcs) == 0 {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("No benchmark configuration at ID %d", id))
+		return nil
+	}
+
+	return bcs[0]
+}
+
+func (s *Server) getBenchmark(w http.ResponseWriter, r *http.Request, id int64) {
+	if bc := s.findBenchmark(w, r, id); bc != nil {
+		writeJSON(w, http.StatusOK, bc)
+	}
+}
+
+// readBenchmark reads benchmark configuration from the request body and validates it.
+// Configuration with the same URL and description as another one is a conflict.
+func (s *Server) readBenchmark(w http.ResponseWriter, r *http.Request, id int64) *BenchmarkConfiguration {
+	var bc BenchmarkConfiguration
+	if err := json.NewDecoder(r.Body).Decode(&bc); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("Can't parse benchmark configuration: %w", err))
+		return nil
+	}
+
+	if _, err := NewBenchmark(&bc.BenchmarkParameters); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return nil
+	}
+
+	other, err := s.inv.FindBenchmark(r.Context(), bc.URL, bc.Description)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return nil
+	}
+
+	if other != nil && other.ID != id {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("Benchmark with provided URL and Description already exists (id %d)", other.ID))
+		return nil
+	}
+
+	return &bc
+}
+
+func (s *Server) createBenchmark(w http.ResponseWriter, r *http.Request) {
+	bc := s.readBenchmark(w, r, 0)
+	if bc == nil {
+		return
+	}
+
+	id, err := s.inv.InsertBenchmarkConfiguration(r.Context(), &bc.BenchmarkParameters, bc.Description)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if bc = s.findBenchmark(w, r, id); bc != nil {
+		writeJSON(w, http.StatusCreated, bc)
+	}
+}
+
+func (s *Server) updateBenchmark(w http.ResponseWriter, r *http.Request, id int64) {
+	if s.findBenchmark(w, r, id) == nil {
+		return
+	}
+
+	bc := s.readBenchmark(w, r, id)
+	if bc == nil {
+		return
+	}
+
+	if err := s.inv.UpdateBenchmarkConfiguration(r.Context(), id, &bc.BenchmarkParameters, bc.Description); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if bc = s.findBenchmark(w, r, id); bc != nil {
+		writeJSON(w, http.StatusOK, bc)
+	}
+}
+
+func (s *Server) deleteBenchmark(w http.ResponseWriter, r *http.Request, id int64) {
+	if s.findBenchmark(w, r, id) == nil {
+		return
+	}
+
+	if err := s.inv.DeleteBenchmark(r.Context(), id); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listSummaries(w http.ResponseWriter, r *http.Request, id int64) {
+	if s.findBenchmark(w, r, id) == nil {
+		return
+	}
+
+	summaries, err := s.inv.FindSummaryForBenchmark(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if summaries == nil {
+		summaries = []*BenchmarkSummary{}
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) getSummary(w http.ResponseWriter, r *http.Request, id int64) {
+	summary, err := s.inv.FindSummaryByID(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if summary == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("No summary at ID %d", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// compareSummaries compares summaries with base and current IDs, tolerance is DefaultTolerance when it is not set
+func (s *Server) compareSummaries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	tolerance := DefaultTolerance
+	if t := query.Get("tolerance"); t != "" {
+		var err error
+		tolerance, err = strconv.ParseFloat(t, 64)
+		if err != nil || tolerance < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("Invalid tolerance %s", t))
+			return
+		}
+	}
+
+	var summaries [2]*BenchmarkSummary
+	for i, param := range []string{"base", "current"} {
+		id, err := strconv.ParseInt(query.Get(param), 10, 64)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("Invalid %s summary ID %s", param, query.Get(param)))
+			return
+		}
+
+		summaries[i], err = s.inv.FindSummaryByID(r.Context(), id)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if summaries[i] == nil {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("No summary at ID %d", id))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, CompareSummaries(summaries[0], summaries[1], tolerance))
+}
+
+// startRun starts the benchmark in the background, it is not stopped when the client disconnects
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request, id int64) {
+	bc := s.findBenchmark(w, r, id)
+	if bc == nil {
+		return
+	}
+
+	b, err := NewBenchmark(&bc.BenchmarkParameters)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	if s.running != nil {
+		running := s.running.ID
+		s.mu.Unlock()
+		cancel()
+
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("Run %d is running", running))
+		return
+	}
+
+	s.lastRun++
+	run := &serverRun{
+		Run: Run{
+			ID:            s.lastRun,
+			Configuration: id,
+			Status:        RunRunning,
+			Start:         time.Now(),
+		},
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+	s.runs[run.ID] = run
+	s.running = run
+
+	b.OnProgress = func(p Progress) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		run.Progress = &p
+		run.notify()
+	}
+
+	started := run.Run
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.run(ctx, run, b, bc)
+
+	writeJSON(w, http.StatusAccepted, started)
+}
+
+// run runs the benchmark and saves its summary
+func (s *Server) run(ctx context.Context, run *serverRun, b *Benchmark, bc *BenchmarkConfiguration) {
+	defer s.wg.Done()
+
+	summary := b.StartBenchmark(ctx)
+
+	status := RunFinished
+	if ctx.Err() != nil {
+		status = RunCancelled
+	}
+
+	var results []ThresholdResult
+	smID, err := s.inv.InsertBenchmarkSummary(context.Background(), summary, bc.ID)
+	if err == nil && len(bc.Thresholds) > 0 {
+		results, err = CheckThresholds(summary, bc.Thresholds)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := time.Now()
+	run.End = &end
+	run.Status = status
+	run.Summary = smID
+	run.Thresholds = results
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	}
+
+	run.cancel()
+	run.notify()
+	s.running = nil
+}
+
+func (s *Server) listRuns(w http.ResponseWriter) {
+	s.mu.Lock()
+	runs := make([]Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run.Run)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// findRun returns copy of the run and the channel closed when it changes
+func (s *Server) findRun(id int64) (Run, <-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, nil, false
+	}
+
+	return run.Run, run.changed, true
+}
+
+func (s *Server) getRun(w http.ResponseWriter, id int64) {
+	run, _, ok := s.findRun(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("No run at ID %d", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
+
+// streamRun sends the run as Server-Sent Event every time its progress changes and when it ends
+func (s *Server) streamRun(w http.ResponseWriter, r *http.Request, id int64) {
+	run, changed, ok := s.findRun(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("No run at ID %d", id))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		data, err := json.Marshal(run)
+		if err != nil {
+			return
+		}
+
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		if run.Status != RunRunning {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+
+		run, changed, _ = s.findRun(id)
+	}
+}
+
+func (s *Server) cancelRun(w http.ResponseWriter, id int64) {
+	s.mu.Lock()
+	run, ok := s.runs[id]
+	var status string
+	if ok {
+		status = run.Status
+		if status == RunRunning {
+			run.cancel()
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("No run at ID %d", id))
+	case status != RunRunning:
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("Run %d is %s", id, status))
+	default:
+		// Run ends when its summary is saved
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
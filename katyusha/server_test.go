@@ -0,0 +1,244 @@
+package katyusha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiRequest sends request to the server and decodes the response into v
+func apiRequest(t *testing.T, method, url string, body interface{}, status int, v interface{}) {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("Can't encode request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &reqBody)
+	if err != nil {
+		t.Fatalf("Can't create request: %v", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer res.Body.Close()
+
+	data, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != status {
+		t.Fatalf("%s %s should return %d but it returned %d: %s", method, url, status, res.StatusCode, data)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("Can't decode response %s: %v", data, err)
+		}
+	}
+}
+
+// streamRun reads run events until the run ends
+func streamRun(t *testing.T, url string) []Run {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Can't stream run: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Run should be streamed as Server-Sent Events, got %s", ct)
+	}
+
+	var runs []Run
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		data := strings.TrimPrefix(scanner.Text(), "data: ")
+		if data == "" {
+			continue
+		}
+
+		var run Run
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			t.Fatalf("Can't decode run event %s: %v", data, err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+func TestServer(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		fmt.Fprintf(w, "Test")
+	}))
+	defer target.Close()
+
+	inv, err := NewInventory("server.db")
+	if err != nil {
+		t.Fatalf("Can't create database file: %v", err)
+	}
+	defer os.Remove("server.db")
+
+	s := NewServer(inv)
+	defer s.Close()
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+	api := server.URL + "/api"
+
+	bc := BenchmarkConfiguration{
+		Description: "API",
+		BenchmarkParameters: BenchmarkParameters{
+			URL:             target.URL,
+			Method:          "GET",
+			ConcurrentConns: 2,
+			ReqCount:        50,
+			Thresholds:      []string{"error_rate < 1%"},
+		},
+	}
+
+	var created BenchmarkConfiguration
+	apiRequest(t, http.MethodPost, api+"/benchmarks", bc, http.StatusCreated, &created)
+	if created.ID == 0 || created.Description != "API" || created.ReqCount != 50 {
+		t.Fatalf("Unexpected created benchmark configuration: %+v", created)
+	}
+	benchmark := fmt.Sprintf("%s/benchmarks/%d", api, created.ID)
+
+	apiRequest(t, http.MethodPost, api+"/benchmarks", bc, http.StatusConflict, nil)
+
+	invalid := bc
+	invalid.Rate = -1
+	apiRequest(t, http.MethodPost, api+"/benchmarks", invalid, http.StatusBadRequest, nil)
+
+	var bcs []BenchmarkConfiguration
+	apiRequest(t, http.MethodGet, api+"/benchmarks", nil, http.StatusOK, &bcs)
+	if len(bcs) != 1 || bcs[0].ID != created.ID {
+		t.Errorf("Expected one benchmark configuration: %+v", bcs)
+	}
+
+	bc.ReqCount = 100
+	var updated BenchmarkConfiguration
+	apiRequest(t, http.MethodPut, benchmark, bc, http.StatusOK, &updated)
+	if updated.ID != created.ID || updated.ReqCount != 100 {
+		t.Errorf("Benchmark configuration should be updated: %+v", updated)
+	}
+
+	var run Run
+	apiRequest(t, http.MethodPost, benchmark+"/runs", nil, http.StatusAccepted, &run)
+	if run.ID == 0 || run.Configuration != created.ID || run.Status != RunRunning {
+		t.Fatalf("Unexpected started run: %+v", run)
+	}
+
+	runs := streamRun(t, fmt.Sprintf("%s/runs/%d/progress", api, run.ID))
+	last := runs[len(runs)-1]
+	if last.Status != RunFinished || last.Summary == 0 || last.End == nil {
+		t.Fatalf("Run should be finished with summary: %+v", last)
+	}
+
+	if len(last.Thresholds) != 1 || !last.Thresholds[0].Pass {
+		t.Errorf("Run threshold should pass: %+v", last.Thresholds)
+	}
+
+	var summary BenchmarkSummary
+	apiRequest(t, http.MethodGet, fmt.Sprintf("%s/summaries/%d", api, last.Summary), nil, http.StatusOK, &summary)
+	if summary.ID != last.Summary || summary.ReqCount != 100 || summary.SuccessReq != 100 {
+		t.Errorf("Summary should have 100 successful requests: %d of %d", summary.SuccessReq, summary.ReqCount)
+	}
+
+	apiRequest(t, http.MethodPost, fmt.Sprintf("%s/runs/%d/cancel", api, run.ID), nil, http.StatusConflict, nil)
+
+	var comparison Comparison
+	apiRequest(t, http.MethodGet, fmt.Sprintf("%s/compare?base=%d&current=%d&tolerance=10", api, last.Summary, last.Summary), nil, http.StatusOK, &comparison)
+	if comparison.BaseID != last.Summary || comparison.Tolerance != 10 || len(comparison.Metrics) == 0 || len(comparison.Regressions()) > 0 {
+		t.Errorf("Summary compared to itself should have no regressions: %+v", comparison)
+	}
+
+	apiRequest(t, http.MethodGet, fmt.Sprintf("%s/compare?base=%d&current=1000", api, last.Summary), nil, http.StatusNotFound, nil)
+	apiRequest(t, http.MethodGet, fmt.Sprintf("%s/compare?base=%d", api, last.Summary), nil, http.StatusBadRequest, nil)
+
+	// Long run is cancelled and its summary has requests sent before
+	bc.ReqCount = 0
+	bc.Duration = time.Minute
+	apiRequest(t, http.MethodPut, benchmark, bc, http.StatusOK, nil)
+
+	apiRequest(t, http.MethodPost, benchmark+"/runs", nil, http.StatusAccepted, &run)
+	apiRequest(t, http.MethodPost, benchmark+"/runs", nil, http.StatusConflict, nil)
+
+	time.Sleep(3 * DefaultProgressInterval / 2)
+	apiRequest(t, http.MethodGet, fmt.Sprintf("%s/runs/%d", api, run.ID), nil, http.StatusOK, &run)
+	if run.Status != RunRunning || run.Progress == nil || run.Progress.ReqCount == 0 {
+		t.Errorf("Running run should have progress: %+v", run)
+	}
+
+	apiRequest(t, http.MethodPost, fmt.Sprintf("%s/runs/%d/cancel", api, run.ID), nil, http.StatusAccepted, nil)
+
+	runs = streamRun(t, fmt.Sprintf("%s/runs/%d/progress", api, run.ID))
+	last = runs[len(runs)-1]
+	if last.Status != RunCancelled || last.Summary == 0 {
+		t.Fatalf("Run should be cancelled with summary: %+v", last)
+	}
+
+	var summaries []BenchmarkSummary
+	apiRequest(t, http.MethodGet, benchmark+"/summaries", nil, http.StatusOK, &summaries)
+	if len(summaries) != 2 || summaries[1].ReqCount == 0 {
+		t.Errorf("Benchmark configuration should have 2 summaries: %+v", summaries)
+	}
+
+	var allRuns []Run
+	apiRequest(t, http.MethodGet, api+"/runs", nil, http.StatusOK, &allRuns)
+	if len(allRuns) != 2 || allRuns[0].Status != RunFinished || allRuns[1].Status != RunCancelled {
+		t.Errorf("Expected finished and cancelled runs: %+v", allRuns)
+	}
+
+	apiRequest(t, http.MethodDelete, benchmark, nil, http.StatusNoContent, nil)
+	apiRequest(t, http.MethodGet, benchmark, nil, http.StatusNotFound, nil)
+	apiRequest(t, http.MethodPost, benchmark+"/runs", nil, http.StatusNotFound, nil)
+
+	apiRequest(t, http.MethodGet, api+"/runs/1000", nil, http.StatusNotFound, nil)
+	apiRequest(t, http.MethodGet, api+"/unknown", nil, http.StatusNotFound, nil)
+	apiRequest(t, http.MethodPatch, benchmark, nil, http.StatusMethodNotAllowed, nil)
+}
+
+func TestServerDashboard(t *testing.T) {
+	server := httptest.NewServer(NewServer(nil))
+	defer server.Close()
+
+	external := regexp.MustCompile(`(src|href)=["']?(https?:)?//|url\(["']?(https?:)?//|@import|fetch\(["'\x60]https?:`)
+
+	for path, contentType := range map[string]string{"/": "text/html", "/app.js": "javascript", "/style.css": "text/css"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Can't get %s: %v", path, err)
+		}
+
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || !strings.Contains(res.Header.Get("Content-Type"), contentType) || len(body) == 0 {
+			t.Errorf("%s should be served as %s, got %d %s", path, contentType, res.StatusCode, res.Header.Get("Content-Type"))
+		}
+
+		// Dashboard has no external resources
+		if external.Match(body) {
+			t.Errorf("%s should not load external resources", path)
+		}
+	}
+
+	apiRequest(t, http.MethodGet, server.URL+"/missing.js", nil, http.StatusNotFound, nil)
+	apiRequest(t, http.MethodPost, server.URL+"/", nil, http.StatusMethodNotAllowed, nil)
+}
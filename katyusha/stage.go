@@ -0,0 +1,114 @@
+package katyusha
+
+import (
+	"fmt"
+	"time"
+)
+
+// profileTick is how often the number of workers is adjusted during a staged benchmark
+const profileTick = 100 * time.Millisecond
+
+// Stage describes one part of multi-stage load profile.
+// During the stage the number of concurrent connections changes linearly from the value of
+// the previous stage (ConcurrentConns for the first stage) to Connections.
+// Rate is changed the same way when the benchmark is open-loop (BenchmarkParameters.Rate is set).
+type Stage struct {
+	Duration    time.Duration `mapstructure:"duration" json:"duration"`
+	Connections int           `mapstructure:"connections" json:"connections"`
+	Rate        int           `mapstructure:"rate" json:"rate"`
+}
+
+func (s Stage) String() string {
+	if s.Rate != 0 {
+		return fmt.Sprintf("%v to %d connections and %d req/s", s.Duration, s.Connections, s.Rate)
+	}
+
+	return fmt.Sprintf("%v to %d connections", s.Duration, s.Connections)
+}
+
+// load describes the load at a moment of the benchmark
+type load struct {
+	stage int
+	conns int
+	rate  float64
+}
+
+// interpolate returns value changing linearly from start to end
+func interpolate(start, end int, elapsed, duration time.Duration) float64 {
+	return float64(start) + float64(end-start)*float64(elapsed)/float64(duration)
+}
+
+// loadAt returns the load expected after elapsed time since the benchmark start.
+// It returns false when the benchmark should end.
+// Without stages the load is constant and the benchmark ends after Duration if it is set.
+func (b *BenchmarkParameters) loadAt(elapsed time.Duration) (load, bool) {
+	if len(b.Stages) == 0 {
+		if b.Duration != time.Duration(0) && elapsed >= b.Duration {
+			return load{}, false
+		}
+
+		return load{conns: b.ConcurrentConns, rate: float64(b.Rate)}, true
+	}
+
+	conns, rate := b.ConcurrentConns, b.Rate
+	for i, stage := range b.Stages {
+		if elapsed < stage.Duration {
+			c := interpolate(conns, stage.Connections, elapsed, stage.Duration)
+			return load{
+				stage: i,
+				conns: int(c + 0.5),
+				rate:  interpolate(rate, stage.Rate, elapsed, stage.Duration),
+			}, true
+		}
+
+		elapsed -= stage.Duration
+		conns, rate = stage.Connections, stage.Rate
+	}
+
+	return load{}, false
+}
+
+// stagesDuration returns how long all stages take
+func (b *BenchmarkParameters) stagesDuration() time.Duration {
+	var d time.Duration
+	for _, stage := range b.Stages {
+		d += stage.Duration
+	}
+
+	return d
+}
+
+// maxConns returns the highest number of concurrent connections used during the benchmark
+func (b *BenchmarkParameters) maxConns() int {
+	conns := b.ConcurrentConns
+	for _, stage := range b.Stages {
+		if stage.Connections > conns {
+			conns = stage.Connections
+		}
+	}
+
+	return conns
+}
+
+// validateStages checks stages configuration
+func (b *BenchmarkParameters) validateStages() error {
+	for i, stage := range b.Stages {
+		if stage.Duration <= 0 {
+			return fmt.Errorf("Stage %d duration needs to be positive", i+1)
+		}
+
+		if stage.Connections < 0 {
+			return fmt.Errorf("Stage %d connections can't be negative", i+1)
+		}
+
+		if stage.Rate < 0 {
+			return fmt.Errorf("Stage %d rate can't be negative", i+1)
+		}
+
+		if stage.Rate != 0 && b.Rate == 0 {
+			return fmt.Errorf("Stage %d rate requires Rate to be set", i+1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,463 @@
+package katyusha
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Formats of streamed responses
+const (
+	StreamFormatSSE   = "sse"   // Server-Sent Events separated by blank lines (default)
+	StreamFormatLines = "lines" // Every non-empty line is an event, e.g. NDJSON or chunked logs
+)
+
+// DefaultEventTimeout is the time to wait for the next event when ReadTimeout is not set
+const DefaultEventTimeout = 30 * time.Second
+
+var (
+	errEventTimeout   = errors.New("No event within read timeout")
+	errLifetimeIsOver = errors.New("Stream lifetime is over")
+)
+
+// Streaming configures benchmark of streamed responses like Server-Sent Events.
+// Every request reads events of the response until the server ends it or a limit is reached.
+type Streaming struct {
+	Format   string        `mapstructure:"format" json:"format"`               // sse or lines
+	Events   int           `mapstructure:"events" json:"events,omitempty"`     // Stream is closed after Events events, 0 reads it until the server ends it
+	Lifetime time.Duration `mapstructure:"lifetime" json:"lifetime,omitempty"` // Stream is closed after Lifetime, 0 reads it until the server ends it
+}
+
+func (s Streaming) String() string {
+	return fmt.Sprintf("%s (events %d, lifetime %v)", s.Format, s.Events, s.Lifetime)
+}
+
+// StreamingStat describes streamed responses of the benchmark.
+// Streams closed because of a limit are successful, the ones broken by an error count only the events received before it.
+type StreamingStat struct {
+	Streams      int       `json:"streams"`        // Streams opened with expected status code
+	Events       int       `json:"events"`         // Events received by all streams
+	EventsPerSec float64   `json:"events_per_sec"` // Average events per second received by one connection while the stream was open
+	FirstEvent   PhaseStat `json:"first_event"`    // From sending the request to the first event
+	EventGap     PhaseStat `json:"event_gap"`      // Time between consecutive events of one stream
+	Lifetime     PhaseStat `json:"lifetime"`       // From sending the request to the end of the stream
+}
+
+// streaming is validated Streaming configuration
+type streaming struct {
+	format   string
+	events   int
+	lifetime time.Duration
+	timeout  time.Duration // Time to wait for the response and every next event
+}
+
+// newStreaming validates Streaming configuration
+func newStreaming(b *BenchmarkParameters) (*streaming, error) {
+	u, err := url.Parse(b.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Streaming requires http or https URL: %s", b.URL)
+	}
+
+	if len(b.Scenario) > 0 {
+		return nil, fmt.Errorf("Streaming benchmark can't have scenario")
+	}
+
+	if b.Protocol != "" && b.Protocol != ProtocolHTTP1 {
+		return nil, fmt.Errorf("Streaming benchmark can't use protocol %s", b.Protocol)
+	}
+
+	if len(b.Assertions) > 0 {
+		return nil, fmt.Errorf("Streaming benchmark can't have assertions, the response body is not kept")
+	}
+
+	if b.WebSocket != nil || b.GRPC != nil {
+		return nil, fmt.Errorf("Streaming can't be combined with WebSocket or gRPC benchmark")
+	}
+
+	s := &streaming{
+		format:   b.Streaming.Format,
+		events:   b.Streaming.Events,
+		lifetime: b.Streaming.Lifetime,
+		timeout:  b.ReadTimeout,
+	}
+
+	switch s.format {
+	case "":
+		s.format = StreamFormatSSE
+	case StreamFormatSSE, StreamFormatLines:
+	default:
+		return nil, fmt.Errorf("Unknown stream format %s, use sse or lines", s.format)
+	}
+
+	if s.events < 0 || s.lifetime < 0 {
+		return nil, fmt.Errorf("Stream events and lifetime can't be negative")
+	}
+
+	if s.timeout == 0 {
+		s.timeout = DefaultEventTimeout
+	}
+
+	return s, nil
+}
+
+// next reads the response until the end of the next event and returns the number of bytes read
+func (s *streaming) next(r *bufio.Reader) (int, error) {
+	if s.format == StreamFormatLines {
+		return nextLine(r)
+	}
+
+	return nextSSE(r)
+}
+
+// nextSSE reads the next Server-Sent Event, it ends with a blank line.
+// Comments and events without data are skipped like in the browser.
+func nextSSE(r *bufio.Reader) (int, error) {
+	var n int
+	var data bool
+
+	lineStart := true
+	for {
+		line, err := r.ReadSlice('\n')
+		n += len(line)
+
+		if lineStart {
+			field := bytes.TrimRight(line, "\r\n")
+			switch {
+			case len(field) == 0 && err == nil:
+				if data {
+					return n, nil
+				}
+			case bytes.HasPrefix(field, []byte("data:")) || string(field) == "data":
+				data = true
+			}
+		}
+
+		// Long line is returned in parts
+		lineStart = err != bufio.ErrBufferFull
+		if err != nil && err != bufio.ErrBufferFull {
+			return n, err
+		}
+	}
+}
+
+// nextLine reads the next non-empty line
+func nextLine(r *bufio.Reader) (int, error) {
+	var n int
+	for {
+		line, err := r.ReadSlice('\n')
+		n += len(line)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if (err == nil || err == io.EOF) && len(bytes.TrimRight(line, "\r\n")) > 0 {
+			// The last line does not need line break
+			return n, nil
+		}
+
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// streamClient reads streamed responses of one worker.
+// Streams can't be read with fasthttp client so worker has its own net/http client with one connection.
+type streamClient struct {
+	b      *Benchmark
+	s      *streaming
+	client *http.Client
+	trace  *httptrace.ClientTrace
+	reader *bufio.Reader
+
+	dials     sync.Map    // Phases of new connections by net.Conn
+	dial      *phaseTimer // Phases of the connection when the last request opened it
+	ready     time.Time
+	firstByte time.Time
+}
+
+func newStreamClient(b *Benchmark) *streamClient {
+	c := &streamClient{
+		b:      b,
+		s:      b.streaming,
+		reader: bufio.NewReader(nil),
+	}
+
+	// Connections are dialed by the transport goroutine so their phases are kept until the request gets the connection
+	dial := func(addr string, isTLS bool) (net.Conn, error) {
+		timer := newPhaseTimer(b.tlsConfig, b.dial, b.WriteTimeout)
+		timer.isTLS = isTLS
+
+		conn, err := timer.Dial(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		c.dials.Store(conn, timer)
+		return conn, nil
+	}
+
+	c.client = &http.Client{
+		// Redirects are not followed like with HTTP/1.1 client
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dial(addr, false)
+			},
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dial(addr, true)
+			},
+			MaxConnsPerHost:    1,
+			IdleConnTimeout:    b.KeepAlive,
+			DisableCompression: true,
+		},
+	}
+
+	c.trace = &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.ready = time.Now()
+
+			if v, ok := c.dials.Load(info.Conn); ok && !info.Reused {
+				c.dials.Delete(info.Conn)
+				c.dial = v.(*phaseTimer)
+			}
+		},
+		GotFirstResponseByte: func() {
+			c.firstByte = time.Now()
+		},
+	}
+
+	return c
+}
+
+// send sends the endpoint request and reads events of the response.
+// Stream is closed when ctx is done, the one closed because the benchmark is over ends without error.
+func (c *streamClient) send(ctx context.Context, e *endpoint, flow *flowState) *RequestStat {
+	c.dial = nil
+	c.firstByte = time.Time{}
+
+	req := fasthttp.AcquireRequest()
+	args := fasthttp.AcquireArgs()
+
+	c.b.prepareRequest(req, args, e, flow)
+	if c.s.format == StreamFormatSSE && len(req.Header.Peek(fasthttp.HeaderAccept)) == 0 {
+		req.Header.Set(fasthttp.HeaderAccept, "text/event-stream")
+	}
+
+	ctx, cancel := context.WithCancelCause(httptrace.WithClientTrace(ctx, c.trace))
+	defer cancel(nil)
+
+	r, err := newHTTPRequest(ctx, req)
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseArgs(args)
+
+	stat := &RequestStat{Start: time.Now()}
+	if err == nil {
+		err = c.read(ctx, cancel, r, stat)
+	}
+
+	stat.End = time.Now()
+	stat.Duration = stat.End.Sub(stat.Start)
+
+	// Stream was opened when the response has expected status, even if it was broken later
+	opened := matchStatus(c.b.expectedStatus, stat.RetCode)
+	if opened {
+		stat.Lifetime = stat.Duration
+	}
+
+	stat.Error = err
+	stat.Success = err == nil && opened
+
+	c.setPhases(stat)
+	return stat
+}
+
+// read sends the request and reads the stream until it ends.
+// Stream closed because of events or lifetime limit or because the benchmark is over ends without error.
+func (c *streamClient) read(ctx context.Context, cancel context.CancelCauseFunc, r *http.Request, stat *RequestStat) error {
+	idle := time.AfterFunc(c.s.timeout, func() { cancel(errEventTimeout) })
+	defer idle.Stop()
+
+	if c.s.lifetime > 0 {
+		lifetime := time.AfterFunc(c.s.lifetime, func() { cancel(errLifetimeIsOver) })
+		defer lifetime.Stop()
+	}
+
+	res, err := c.client.Do(r)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+
+		return err
+	}
+	defer res.Body.Close()
+
+	stat.RetCode = res.StatusCode
+	if !matchStatus(c.b.expectedStatus, stat.RetCode) {
+		return nil
+	}
+
+	c.reader.Reset(res.Body)
+	defer c.reader.Reset(nil)
+
+	last := stat.Start
+	for c.s.events == 0 || stat.Events < c.s.events {
+		n, err := c.s.next(c.reader)
+		stat.BodySize += n
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			if cause := context.Cause(ctx); cause == errLifetimeIsOver || cause == errBenchmarkIsOver {
+				return nil
+			} else if cause != nil {
+				return cause
+			}
+
+			return err
+		}
+
+		now := time.Now()
+		if stat.Events == 0 {
+			stat.FirstEvent = now.Sub(stat.Start)
+		} else {
+			stat.EventGaps = append(stat.EventGaps, now.Sub(last))
+		}
+
+		stat.Events++
+		last = now
+		idle.Reset(c.s.timeout)
+	}
+
+	return nil
+}
+
+func (c *streamClient) setPhases(stat *RequestStat) {
+	if c.dial != nil {
+		stat.NewConnection = true
+		stat.DNS, stat.Connect, stat.TLS = c.dial.dns, c.dial.connect, c.dial.handshake
+	}
+
+	if c.firstByte.IsZero() {
+		return
+	}
+
+	stat.TTFB = c.firstByte.Sub(c.ready)
+	stat.Transfer = stat.End.Sub(c.firstByte)
+}
+
+func (c *streamClient) closeIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+// streamingCollector aggregates events of streamed responses
+type streamingCollector struct {
+	events     int
+	seconds    float64 // Time of all streams
+	firstEvent *histogram
+	eventGaps  *histogram
+	lifetimes  *histogram
+}
+
+func newStreamingCollector() *streamingCollector {
+	return &streamingCollector{
+		firstEvent: newHistogram(),
+		eventGaps:  newHistogram(),
+		lifetimes:  newHistogram(),
+	}
+}
+
+func (c *streamingCollector) add(stat *RequestStat) {
+	if stat.Lifetime == 0 {
+		return
+	}
+
+	c.lifetimes.record(stat.Lifetime)
+	c.seconds += stat.Lifetime.Seconds()
+	c.events += stat.Events
+
+	if stat.Events > 0 {
+		c.firstEvent.record(stat.FirstEvent)
+	}
+
+	for _, gap := range stat.EventGaps {
+		c.eventGaps.record(gap)
+	}
+}
+
+func (c *streamingCollector) stat() *StreamingStat {
+	stat := &StreamingStat{
+		Streams: int(c.lifetimes.count),
+		Events:  c.events,
+	}
+
+	// Worker reads one stream at a time so the time of all streams is the time connections were streaming
+	if c.seconds > 0 {
+		stat.EventsPerSec = float64(c.events) / c.seconds
+	}
+
+	for _, s := range []struct {
+		h    *histogram
+		stat *PhaseStat
+	}{{c.firstEvent, &stat.FirstEvent}, {c.eventGaps, &stat.EventGap}, {c.lifetimes, &stat.Lifetime}} {
+		if s.h.count > 0 {
+			*s.stat = s.h.phaseStat()
+		}
+	}
+
+	return stat
+}
+
+// streamingSnapshot is streaming collector sent by agent to the coordinator
+type streamingSnapshot struct {
+	Events     int               `json:"events"`
+	Seconds    float64           `json:"seconds"`
+	FirstEvent histogramSnapshot `json:"first_event"`
+	EventGaps  histogramSnapshot `json:"event_gaps"`
+	Lifetimes  histogramSnapshot `json:"lifetimes"`
+}
+
+func (c *streamingCollector) snapshot() *streamingSnapshot {
+	return &streamingSnapshot{
+		Events:     c.events,
+		Seconds:    c.seconds,
+		FirstEvent: c.firstEvent.snapshot(),
+		EventGaps:  c.eventGaps.snapshot(),
+		Lifetimes:  c.lifetimes.snapshot(),
+	}
+}
+
+// merge adds streams collected by an agent
+func (c *streamingCollector) merge(s *streamingSnapshot) error {
+	c.events += s.Events
+	c.seconds += s.Seconds
+
+	if err := c.firstEvent.mergeSnapshot(s.FirstEvent); err != nil {
+		return err
+	}
+
+	if err := c.eventGaps.mergeSnapshot(s.EventGaps); err != nil {
+		return err
+	}
+
+	return c.lifetimes.mergeSnapshot(s.Lifetimes)
+}
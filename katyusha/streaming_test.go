@@ -0,0 +1,333 @@
+package katyusha
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamServer sends events every 5ms.
+// /sse sends 5 events and ends the stream, /infinite sends events until the client closes it
+// and /stall sends one event and waits.
+func streamServer() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		flusher := w.(http.Flusher)
+		send := func(event string) bool {
+			select {
+			case <-r.Context().Done():
+				return false
+			case <-time.After(5 * time.Millisecond):
+			}
+
+			fmt.Fprint(w, event)
+			flusher.Flush()
+			return true
+		}
+
+		switch r.URL.Path {
+		case "/sse":
+			if r.Header.Get("Accept") != "text/event-stream" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": welcome\nretry: 1000\n\n")
+			for i := 0; i < 5; i++ {
+				send(fmt.Sprintf("event: tick\nid: %d\ndata: %d\n\n", i, i))
+			}
+		case "/lines":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 0; i < 3; i++ {
+				send(fmt.Sprintf("{\"tick\": %d}\n\n", i))
+			}
+			send(`{"tick": 3}`)
+		case "/infinite":
+			for i := 0; send(fmt.Sprintf("data: %d\n\n", i)); i++ {
+			}
+		case "/stall":
+			send("data: 0\n\n")
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestStreaming(t *testing.T) {
+	tt := []struct {
+		name      string
+		path      string
+		tls       bool
+		streaming Streaming
+		timeout   time.Duration
+
+		success     int
+		streams     int
+		events      int
+		minLifetime time.Duration
+		maxLifetime time.Duration
+		errors      map[string]int
+	}{
+		{
+			name:        "Server ends stream",
+			path:        "/sse",
+			streaming:   Streaming{},
+			success:     10,
+			streams:     10,
+			events:      50,
+			minLifetime: 25 * time.Millisecond,
+		},
+		{
+			name:        "TLS",
+			path:        "/sse",
+			tls:         true,
+			streaming:   Streaming{Format: StreamFormatSSE},
+			success:     10,
+			streams:     10,
+			events:      50,
+			minLifetime: 25 * time.Millisecond,
+		},
+		{
+			name:        "Lines",
+			path:        "/lines",
+			streaming:   Streaming{Format: StreamFormatLines},
+			success:     10,
+			streams:     10,
+			events:      40,
+			minLifetime: 20 * time.Millisecond,
+		},
+		{
+			name:        "Events limit",
+			path:        "/infinite",
+			streaming:   Streaming{Events: 3},
+			success:     10,
+			streams:     10,
+			events:      30,
+			minLifetime: 15 * time.Millisecond,
+		},
+		{
+			name:        "Lifetime",
+			path:        "/infinite",
+			streaming:   Streaming{Lifetime: 50 * time.Millisecond},
+			success:     10,
+			streams:     10,
+			minLifetime: 50 * time.Millisecond,
+			maxLifetime: 500 * time.Millisecond,
+		},
+		{
+			name:      "Event timeout",
+			path:      "/stall",
+			streaming: Streaming{},
+			timeout:   50 * time.Millisecond,
+			streams:   10,
+			events:    10,
+			errors:    map[string]int{errEventTimeout.Error(): 10},
+		},
+		{
+			name:      "Unexpected status",
+			path:      "/missing",
+			streaming: Streaming{},
+			errors:    map[string]int{"Not Found": 10},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var server *httptest.Server
+			if tc.tls {
+				server = httptest.NewTLSServer(streamServer())
+			} else {
+				server = httptest.NewServer(streamServer())
+			}
+			defer server.Close()
+
+			streaming := tc.streaming
+			benchmark, err := NewBenchmark(&BenchmarkParameters{
+				URL:             server.URL + tc.path,
+				Method:          "GET",
+				Headers:         headers{"X-Token": "secret"},
+				ConcurrentConns: 2,
+				ReqCount:        10,
+				ReadTimeout:     tc.timeout,
+				SkipVerify:      true,
+				Streaming:       &streaming,
+			})
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != tc.success || summary.ReqCount != 10 {
+				t.Errorf("Expected %d successful streams, got %d of %d: %v", tc.success, summary.SuccessReq, summary.ReqCount, summary.Errors)
+			}
+
+			for e, count := range tc.errors {
+				if summary.Errors[e] != count {
+					t.Errorf("Expected %d %s errors: %v", count, e, summary.Errors)
+				}
+			}
+
+			st := summary.Streaming
+			if st == nil || st.Streams != tc.streams || st.Lifetime.Count != tc.streams {
+				t.Fatalf("Expected %d streams: %+v", tc.streams, st)
+			}
+
+			if tc.events > 0 && st.Events != tc.events {
+				t.Errorf("Expected %d events, got %d", tc.events, st.Events)
+			}
+
+			if st.Events > 0 {
+				if st.FirstEvent.Count != tc.streams || st.FirstEvent.Max < 5*time.Millisecond {
+					t.Errorf("Every stream should have time to first event: %v", st.FirstEvent)
+				}
+
+				if st.EventGap.Count != st.Events-st.FirstEvent.Count || st.EventGap.Count > 0 && st.EventGap.P50 < 4*time.Millisecond {
+					t.Errorf("Unexpected gaps between events: %v", st.EventGap)
+				}
+
+				if st.EventsPerSec <= 0 || st.EventsPerSec > 250 {
+					t.Errorf("Server sends at most 200 events per second, got %.2f", st.EventsPerSec)
+				}
+			}
+
+			if tc.streams > 0 && (st.Lifetime.Max < tc.minLifetime || tc.maxLifetime > 0 && st.Lifetime.Max > tc.maxLifetime) {
+				t.Errorf("Unexpected stream lifetime: %v", st.Lifetime)
+			}
+
+			if tc.success > 0 && summary.DataTransfered == 0 {
+				t.Errorf("Events should be counted in data transfered")
+			}
+
+			if summary.Phases.TTFB.Count != 10 || summary.Phases.Connect.Count == 0 || tc.tls && summary.Phases.TLS.Count == 0 {
+				t.Errorf("Phases should be measured: %+v", summary.Phases)
+			}
+		})
+	}
+}
+
+func TestStreamingDuration(t *testing.T) {
+	server := httptest.NewServer(streamServer())
+	defer server.Close()
+
+	// Streams never end so they are closed when the duration is over
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             server.URL + "/infinite",
+		Method:          "GET",
+		Headers:         headers{"X-Token": "secret"},
+		ConcurrentConns: 2,
+		Duration:        200 * time.Millisecond,
+		Streaming:       &Streaming{},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	start := time.Now()
+	summary := benchmark.StartBenchmark(context.Background())
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("Benchmark should end after its duration, it took %v", took)
+	}
+
+	if summary.SuccessReq != 2 || summary.ReqCount != 2 {
+		t.Errorf("Streams closed at the end of the benchmark should be successful, got %d of %d: %v", summary.SuccessReq, summary.ReqCount, summary.Errors)
+	}
+
+	if st := summary.Streaming; st == nil || st.Streams != 2 || st.Events == 0 {
+		t.Errorf("Expected 2 streams with events: %+v", st)
+	}
+
+	// Cancelled benchmark closes streams as well
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	benchmark.Duration = time.Minute
+	start = time.Now()
+	benchmark.StartBenchmark(ctx)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("Cancelled benchmark should end, it took %v", took)
+	}
+}
+
+func TestNextSSE(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	stream := ": comment\n\nevent: ping\n\ndata: " + long + "\r\n\r\ndata\nid: 2\n\n" + long + "\n\ndata: partial"
+
+	// Small buffer returns the long lines in parts
+	r := bufio.NewReaderSize(strings.NewReader(stream), 16)
+
+	var sizes []int
+	for {
+		n, err := nextSSE(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Can't read event: %v", err)
+		}
+
+		sizes = append(sizes, n)
+	}
+
+	expected := []int{len(": comment\n\nevent: ping\n\ndata: " + long + "\r\n\r\n"), len("data\nid: 2\n\n")}
+	if fmt.Sprint(sizes) != fmt.Sprint(expected) {
+		t.Errorf("Expected events of %v bytes, got %v", expected, sizes)
+	}
+}
+
+func TestInvalidStreaming(t *testing.T) {
+	tt := []struct {
+		name   string
+		params BenchmarkParameters
+	}{
+		{
+			name:   "WebSocket URL",
+			params: BenchmarkParameters{URL: "ws://127.0.0.1"},
+		},
+		{
+			name:   "Scenario",
+			params: BenchmarkParameters{URL: "http://127.0.0.1", Scenario: []Endpoint{{URL: "/events"}}},
+		},
+		{
+			name:   "Protocol",
+			params: BenchmarkParameters{URL: "https://127.0.0.1", Protocol: ProtocolHTTP2},
+		},
+		{
+			name:   "Assertions",
+			params: BenchmarkParameters{URL: "http://127.0.0.1", Assertions: []Assertion{{Contains: "ok"}}},
+		},
+		{
+			name:   "Format",
+			params: BenchmarkParameters{URL: "http://127.0.0.1", Streaming: &Streaming{Format: "json"}},
+		},
+		{
+			name:   "Negative lifetime",
+			params: BenchmarkParameters{URL: "http://127.0.0.1", Streaming: &Streaming{Lifetime: -time.Second}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params.ReqCount = 1
+			if tc.params.Streaming == nil {
+				tc.params.Streaming = &Streaming{}
+			}
+
+			_, err := NewBenchmark(&tc.params)
+			if err == nil {
+				t.Errorf("Benchmark should not be created")
+			}
+		})
+	}
+}
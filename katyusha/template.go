@@ -0,0 +1,131 @@
+package katyusha
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templatePart is a literal text, a variable reference or a generator
+type templatePart struct {
+	literal   string
+	variable  string
+	generator generator
+}
+
+// template is a value of URL, header or body with ${name} references to variables
+// and generators like ${uuid} or ${randInt(1, 100)}.
+// It is compiled once so building a request only appends parts to a buffer.
+type template struct {
+	parts []templatePart
+	text  string // Value of template without variables and generators
+}
+
+// compileTemplate parses ${name} references and generators in value
+func compileTemplate(value string) (*template, error) {
+	t := &template{}
+	s := value
+
+	for len(s) > 0 {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			t.parts = append(t.parts, templatePart{literal: s})
+			break
+		}
+
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{literal: s[:start]})
+		}
+
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("Unterminated reference in %s", value)
+		}
+
+		expression := strings.TrimSpace(s[start+2 : start+end])
+		if expression == "" {
+			return nil, fmt.Errorf("Empty reference in %s", value)
+		}
+
+		g, err := parseGenerator(expression)
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse ${%s} in %s: %w", expression, value, err)
+		}
+
+		if g != nil {
+			t.parts = append(t.parts, templatePart{generator: g})
+		} else {
+			t.parts = append(t.parts, templatePart{variable: expression})
+		}
+
+		s = s[start+end+1:]
+	}
+
+	if t.static() {
+		t.text = value
+	}
+
+	return t, nil
+}
+
+// static returns true when template does not reference variables or generators
+func (t *template) static() bool {
+	for _, p := range t.parts {
+		if p.variable != "" || p.generator != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// variables returns names of referenced variables
+func (t *template) variables() []string {
+	var names []string
+	for _, p := range t.parts {
+		if p.variable != "" {
+			names = append(names, p.variable)
+		}
+	}
+
+	return names
+}
+
+// unknownVariable returns referenced variable which is not in known or empty string when all are known
+func (t *template) unknownVariable(known []string) string {
+	for _, v := range t.variables() {
+		found := false
+		for _, k := range known {
+			if v == k {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// empty returns true when template value is always empty
+func (t *template) empty() bool {
+	return len(t.parts) == 0
+}
+
+// appendTo appends template value with variables from vars and generated values to dst
+func (t *template) appendTo(dst []byte, vars map[string]string) []byte {
+	for _, p := range t.parts {
+		switch {
+		case p.generator != nil:
+			dst = p.generator(dst)
+		case p.variable != "":
+			dst = append(dst, vars[p.variable]...)
+		default:
+			dst = append(dst, p.literal...)
+		}
+	}
+
+	return dst
+}
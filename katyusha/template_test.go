@@ -0,0 +1,124 @@
+package katyusha
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTemplate(t *testing.T) {
+	vars := map[string]string{"user": "7", "token": "abc"}
+
+	tt := []struct {
+		value     string
+		expected  string
+		variables []string
+		err       bool
+	}{
+		{"/items", "/items", nil, false},
+		{"", "", nil, false},
+		{"/users/${user}", "/users/7", []string{"user"}, false},
+		{"${token}", "abc", []string{"token"}, false},
+		{"${ user }/${token}?a=${user}", "7/abc?a=7", []string{"user", "token", "user"}, false},
+		{"${missing}", "", []string{"missing"}, false},
+		{"$user {user}", "$user {user}", nil, false},
+		{"/users/${user", "", nil, true},
+		{"/users/${}", "", nil, true},
+	}
+
+	for _, tc := range tt {
+		tmpl, err := compileTemplate(tc.value)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Template %q should not be compiled", tc.value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Can't compile template %q: %v", tc.value, err)
+			continue
+		}
+
+		if got := string(tmpl.appendTo(nil, vars)); got != tc.expected {
+			t.Errorf("Template %q should render %q but it rendered %q", tc.value, tc.expected, got)
+		}
+
+		if diff := cmp.Diff(tc.variables, tmpl.variables()); diff != "" {
+			t.Errorf("Template %q variables mismatch (-want +got):\n%s", tc.value, diff)
+		}
+
+		if tmpl.static() != (tc.variables == nil) {
+			t.Errorf("Template %q static should be %t", tc.value, tc.variables == nil)
+		}
+	}
+}
+
+func TestTemplateRequests(t *testing.T) {
+	var mu sync.Mutex
+	paths := make(map[string]int)
+	ids := make(map[string]int)
+	bodies := make(map[string]int)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		paths[r.URL.Path]++
+		ids[r.Header.Get("X-Request-Id")]++
+		bodies[string(body)]++
+		mu.Unlock()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	req := &BenchmarkParameters{
+		URL:             server.URL + "/items/${seq}",
+		Method:          "POST",
+		ConcurrentConns: 4,
+		ReqCount:        200,
+		Headers:         headers{"X-Request-Id": "${uuid}"},
+		Body:            []byte(`{"name": "${randString(16)}"}`),
+	}
+
+	benchmark, err := NewBenchmark(req)
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+	if summary.SuccessReq != 200 {
+		t.Fatalf("All requests should succeed: %v", summary.Errors)
+	}
+
+	for name, values := range map[string]map[string]int{"paths": paths, "ids": ids, "bodies": bodies} {
+		if len(values) != 200 {
+			t.Errorf("Every request should have unique %s but there are %d of 200", name, len(values))
+		}
+	}
+
+	if paths["/items/1"] != 1 || paths["/items/200"] != 1 {
+		t.Errorf("Sequence should go from 1 to 200")
+	}
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	tt := []BenchmarkParameters{
+		{URL: "http://localhost/${user}"},
+		{URL: "http://localhost/${randInt(1)}"},
+		{URL: "http://localhost/", Headers: headers{"X-Id": "${uuid"}},
+		{URL: "http://localhost/", Body: []byte("${pick()}")},
+	}
+
+	for _, params := range tt {
+		if _, err := NewBenchmark(&params); err == nil {
+			t.Errorf("Benchmark with invalid template %v should not be created", params)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package katyusha
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const thresholdRegexp = `^\s*([\w]+)\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`
+
+// metricKind tells how threshold value is parsed and printed
+type metricKind int
+
+const (
+	metricNumber   metricKind = iota
+	metricDuration            // Go duration like 250ms
+	metricRatio               // Fraction or percent like 0.005 or 0.5%
+)
+
+type metric struct {
+	kind  metricKind
+	value func(s *Summary) float64
+}
+
+func durationMetric(f func(s *Summary) time.Duration) metric {
+	return metric{kind: metricDuration, value: func(s *Summary) float64 { return float64(f(s)) }}
+}
+
+func numberMetric(f func(s *Summary) float64) metric {
+	return metric{kind: metricNumber, value: f}
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total)
+}
+
+// metrics available in threshold expressions
+var metrics = map[string]metric{
+	"avg":           durationMetric(func(s *Summary) time.Duration { return s.AvgReqTime }),
+	"min":           durationMetric(func(s *Summary) time.Duration { return s.MinReqTime }),
+	"max":           durationMetric(func(s *Summary) time.Duration { return s.MaxReqTime }),
+	"p50":           durationMetric(func(s *Summary) time.Duration { return s.P50ReqTime }),
+	"p75":           durationMetric(func(s *Summary) time.Duration { return s.P75ReqTime }),
+	"p90":           durationMetric(func(s *Summary) time.Duration { return s.P90ReqTime }),
+	"p99":           durationMetric(func(s *Summary) time.Duration { return s.P99ReqTime }),
+	"std_deviation": durationMetric(func(s *Summary) time.Duration { return time.Duration(s.StdDeviation) }),
+	"avg_latency":   durationMetric(func(s *Summary) time.Duration { return s.AvgLatency }),
+	"max_latency":   durationMetric(func(s *Summary) time.Duration { return s.MaxLatency }),
+	"p50_latency":   durationMetric(func(s *Summary) time.Duration { return s.P50Latency }),
+	"p75_latency":   durationMetric(func(s *Summary) time.Duration { return s.P75Latency }),
+	"p90_latency":   durationMetric(func(s *Summary) time.Duration { return s.P90Latency }),
+	"p99_latency":   durationMetric(func(s *Summary) time.Duration { return s.P99Latency }),
+	"req_per_sec":   numberMetric(func(s *Summary) float64 { return s.ReqPerSec }),
+	"requests":      numberMetric(func(s *Summary) float64 { return float64(s.ReqCount) }),
+	"success_req":   numberMetric(func(s *Summary) float64 { return float64(s.SuccessReq) }),
+	"fail_req":      numberMetric(func(s *Summary) float64 { return float64(s.FailReq) }),
+	"late_req":      numberMetric(func(s *Summary) float64 { return float64(s.LateReq) }),
+	"dropped_req":   numberMetric(func(s *Summary) float64 { return float64(s.DroppedReq) }),
+	"error_rate": {
+		kind:  metricRatio,
+		value: func(s *Summary) float64 { return ratio(s.FailReq, s.ReqCount) },
+	},
+	"success_rate": {
+		kind:  metricRatio,
+		value: func(s *Summary) float64 { return ratio(s.SuccessReq, s.ReqCount) },
+	},
+	"drop_rate": {
+		kind:  metricRatio,
+		value: func(s *Summary) float64 { return ratio(s.DroppedReq, s.ScheduledReq) },
+	},
+}
+
+// threshold is parsed threshold expression
+type threshold struct {
+	expression string
+	metric     string
+	operator   string
+	value      float64
+}
+
+// parseThreshold parses expression in format "<metric> <operator> <value>", for example "p99 < 250ms"
+func parseThreshold(expression string) (*threshold, error) {
+	r := regexp.MustCompile(thresholdRegexp)
+	matches := r.FindStringSubmatch(expression)
+	if len(matches) != 4 {
+		return nil, fmt.Errorf("Can't parse threshold %s", expression)
+	}
+
+	m, ok := metrics[matches[1]]
+	if !ok {
+		return nil, fmt.Errorf("Unknown metric %s in threshold %s", matches[1], expression)
+	}
+
+	value, err := parseMetricValue(m.kind, matches[3])
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse value of threshold %s: %w", expression, err)
+	}
+
+	return &threshold{
+		expression: expression,
+		metric:     matches[1],
+		operator:   matches[2],
+		value:      value,
+	}, nil
+}
+
+func parseMetricValue(kind metricKind, value string) (float64, error) {
+	switch kind {
+	case metricDuration:
+		d, err := time.ParseDuration(value)
+		return float64(d), err
+	case metricRatio:
+		if strings.HasSuffix(value, "%") {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			return v / 100, err
+		}
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func formatMetricValue(kind metricKind, value float64) string {
+	switch kind {
+	case metricDuration:
+		return time.Duration(value).String()
+	case metricRatio:
+		return fmt.Sprintf("%.4g%%", value*100)
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (t *threshold) check(actual float64) bool {
+	switch t.operator {
+	case "<":
+		return actual < t.value
+	case "<=":
+		return actual <= t.value
+	case ">":
+		return actual > t.value
+	case ">=":
+		return actual >= t.value
+	case "==":
+		return actual == t.value
+	case "!=":
+		return actual != t.value
+	}
+
+	return false
+}
+
+// ThresholdResult is the result of checking one threshold against Summary
+type ThresholdResult struct {
+	Threshold string `json:"threshold"`
+	Actual    string `json:"actual"` // Value of the metric in the same unit as the threshold
+	Pass      bool   `json:"pass"`
+}
+
+func (r ThresholdResult) String() string {
+	status := "PASS"
+	if !r.Pass {
+		status = "FAIL"
+	}
+
+	return fmt.Sprintf("%s  %s (actual %s)", status, r.Threshold, r.Actual)
+}
+
+// ValidateThresholds checks if threshold expressions can be parsed
+func ValidateThresholds(thresholds []string) error {
+	for _, expression := range thresholds {
+		if _, err := parseThreshold(expression); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckThresholds evaluates threshold expressions against the summary.
+// Expression has format "<metric> <operator> <value>", for example "p99 < 250ms", "error_rate < 0.5%"
+// or "req_per_sec > 1000".
+func CheckThresholds(summary *Summary, thresholds []string) ([]ThresholdResult, error) {
+	results := make([]ThresholdResult, 0, len(thresholds))
+
+	for _, expression := range thresholds {
+		t, err := parseThreshold(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		m := metrics[t.metric]
+		actual := m.value(summary)
+
+		results = append(results, ThresholdResult{
+			Threshold: strings.TrimSpace(expression),
+			Actual:    formatMetricValue(m.kind, actual),
+			Pass:      t.check(actual),
+		})
+	}
+
+	return results, nil
+}
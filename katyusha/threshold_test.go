@@ -0,0 +1,81 @@
+package katyusha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		expression string
+		metric     string
+		operator   string
+		value      float64
+		err        bool
+	}{
+		{"p99 < 250ms", "p99", "<", float64(250 * time.Millisecond), false},
+		{"error_rate<0.5%", "error_rate", "<", 0.005, false},
+		{"error_rate <= 0.01", "error_rate", "<=", 0.01, false},
+		{" req_per_sec > 1000 ", "req_per_sec", ">", 1000, false},
+		{"dropped_req == 0", "dropped_req", "==", 0, false},
+		{"p99_latency >= 1s", "p99_latency", ">=", float64(time.Second), false},
+		{"p99 < 250", "", "", 0, true},
+		{"p98 < 250ms", "", "", 0, true},
+		{"p99 ~ 250ms", "", "", 0, true},
+		{"req_per_sec > fast", "", "", 0, true},
+		{"", "", "", 0, true},
+	}
+
+	for _, test := range tests {
+		threshold, err := parseThreshold(test.expression)
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected error parsing %q", test.expression)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Can't parse %q: %v", test.expression, err)
+			continue
+		}
+
+		if threshold.metric != test.metric || threshold.operator != test.operator || threshold.value != test.value {
+			t.Errorf("Threshold %q parsed as %s %s %v", test.expression, threshold.metric, threshold.operator, threshold.value)
+		}
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	summary := &Summary{
+		ReqCount:   1000,
+		SuccessReq: 996,
+		FailReq:    4,
+		ReqPerSec:  1250.5,
+		P99ReqTime: 300 * time.Millisecond,
+	}
+
+	thresholds := []string{"p99 < 250ms", "error_rate < 0.5%", "req_per_sec > 1000", "fail_req != 4"}
+
+	results, err := CheckThresholds(summary, thresholds)
+	if err != nil {
+		t.Fatalf("Can't check thresholds: %v", err)
+	}
+
+	expected := []ThresholdResult{
+		{Threshold: "p99 < 250ms", Actual: "300ms", Pass: false},
+		{Threshold: "error_rate < 0.5%", Actual: "0.4%", Pass: true},
+		{Threshold: "req_per_sec > 1000", Actual: "1250.5", Pass: true},
+		{Threshold: "fail_req != 4", Actual: "4", Pass: false},
+	}
+
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("Threshold results mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := CheckThresholds(summary, []string{"p99 <"}); err == nil {
+		t.Errorf("Expected error for invalid threshold")
+	}
+}
@@ -0,0 +1,288 @@
+package katyusha
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// DefaultReplyTimeout is the time to wait for a WebSocket reply when ReadTimeout is not set
+const DefaultReplyTimeout = 10 * time.Second
+
+var errReplyTimeout = errors.New("No reply within read timeout")
+
+// WebSocket configures benchmark of WebSocket server.
+// Every worker opens a connection to URL and sends Message for every request,
+// the request ends when the reply arrives.
+type WebSocket struct {
+	Message string `mapstructure:"message" json:"message"`         // Message template like request body
+	Expect  string `mapstructure:"expect" json:"expect,omitempty"` // Request ends with the reply containing Expect, with the first reply when not set
+}
+
+func (w WebSocket) String() string {
+	if w.Expect == "" {
+		return w.Message
+	}
+
+	return fmt.Sprintf("%s (expect %s)", w.Message, w.Expect)
+}
+
+// WebSocketStat describes WebSocket connections of the benchmark
+type WebSocketStat struct {
+	Connections int       `json:"connections"` // Connections opened by workers
+	Setup       PhaseStat `json:"setup"`       // Time to open connection including the WebSocket handshake
+	Dropped     int       `json:"dropped"`     // Messages without reply, their connections were closed
+}
+
+// webSocket is compiled WebSocket configuration
+type webSocket struct {
+	message *template
+	expect  []byte
+	timeout time.Duration
+}
+
+// newWebSocket validates WebSocket configuration, message can use feeder columns
+func newWebSocket(b *BenchmarkParameters, columns []string) (*webSocket, error) {
+	u, err := url.Parse(b.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("WebSocket requires ws or wss URL: %s", b.URL)
+	}
+
+	if len(b.Scenario) > 0 {
+		return nil, fmt.Errorf("WebSocket benchmark can't have scenario")
+	}
+
+	if b.Protocol != "" && b.Protocol != ProtocolHTTP1 {
+		return nil, fmt.Errorf("WebSocket benchmark can't use protocol %s", b.Protocol)
+	}
+
+	if len(b.Assertions) > 0 || len(b.ExpectedStatus) > 0 {
+		return nil, fmt.Errorf("WebSocket benchmark can't have assertions or expected status, replies are checked with expect")
+	}
+
+	message, err := compileTemplate(b.WebSocket.Message)
+	if err != nil {
+		return nil, fmt.Errorf("WebSocket message: %w", err)
+	}
+
+	if v := message.unknownVariable(columns); v != "" {
+		return nil, fmt.Errorf("Unknown variable %s, WebSocket message variables are set by feeder columns", v)
+	}
+
+	ws := &webSocket{
+		message: message,
+		timeout: b.ReadTimeout,
+	}
+
+	if b.WebSocket.Expect != "" {
+		ws.expect = []byte(b.WebSocket.Expect)
+	}
+
+	if ws.timeout == 0 {
+		ws.timeout = DefaultReplyTimeout
+	}
+
+	return ws, nil
+}
+
+// wsClient sends messages of one worker over its connection.
+// Connection is opened with the first message and again after it was dropped.
+type wsClient struct {
+	b     *Benchmark
+	ws    *webSocket
+	timer *phaseTimer
+	conn  *websocket.Conn
+}
+
+func newWSClient(b *Benchmark) *wsClient {
+	return &wsClient{
+		b:     b,
+		ws:    b.webSocket,
+		timer: newPhaseTimer(b.tlsConfig, b.dial, b.WriteTimeout),
+	}
+}
+
+// connect opens connection to the endpoint URL with the endpoint headers
+func (c *wsClient) connect(e *endpoint, flow *flowState) error {
+	flow.buf = e.url.appendTo(flow.buf[:0], flow.vars)
+	u, err := url.Parse(string(flow.buf))
+	if err != nil {
+		return err
+	}
+
+	isTLS := u.Scheme == "wss"
+	origin := "http://" + u.Host
+	if isTLS {
+		origin = "https://" + u.Host
+	}
+
+	config, err := websocket.NewConfig(u.String(), origin)
+	if err != nil {
+		return err
+	}
+
+	config.Header.Set("User-Agent", KatyushaName)
+	for _, h := range e.headers {
+		flow.buf = h.value.appendTo(flow.buf[:0], flow.vars)
+		config.Header.Set(h.key, string(flow.buf))
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if isTLS {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	c.timer.start(time.Now(), isTLS)
+	conn, err := c.timer.Dial(addr)
+	if err != nil {
+		return err
+	}
+
+	if c.b.WriteTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.b.WriteTimeout))
+	}
+
+	c.conn, err = websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return nil
+}
+
+// send sends message rendered with flow variables and waits for the reply
+func (c *wsClient) send(e *endpoint, flow *flowState) *RequestStat {
+	stat := &RequestStat{}
+
+	start := time.Now()
+	if c.conn == nil {
+		stat.NewConnection = true
+		if err := c.connect(e, flow); err != nil {
+			stat.Start, stat.End = start, time.Now()
+			stat.Duration = stat.End.Sub(start)
+			stat.Error = err
+			return stat
+		}
+
+		stat.Setup = time.Since(start)
+		stat.DNS, stat.Connect, stat.TLS = c.timer.dns, c.timer.connect, c.timer.handshake
+	}
+
+	flow.buf = c.ws.message.appendTo(flow.buf[:0], flow.vars)
+
+	stat.Start = time.Now()
+	reply, err := c.roundTrip(flow.buf)
+	stat.End = time.Now()
+	stat.Duration = stat.End.Sub(stat.Start)
+
+	if err != nil {
+		// Connection is in unknown state after failed write or read
+		c.close()
+		stat.Dropped = true
+		stat.Error = err
+		return stat
+	}
+
+	stat.BodySize = len(reply)
+	stat.Success = true
+
+	return stat
+}
+
+// roundTrip writes the message and returns the expected reply
+func (c *wsClient) roundTrip(message []byte) ([]byte, error) {
+	if c.b.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.b.WriteTimeout))
+	}
+
+	if err := websocket.Message.Send(c.conn, string(message)); err != nil {
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.ws.timeout))
+	for {
+		var reply []byte
+		if err := websocket.Message.Receive(c.conn, &reply); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return nil, errReplyTimeout
+			}
+
+			return nil, fmt.Errorf("Connection closed: %w", err)
+		}
+
+		if c.ws.expect == nil || bytes.Contains(reply, c.ws.expect) {
+			return reply, nil
+		}
+	}
+}
+
+func (c *wsClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// webSocketCollector aggregates connections and drops of WebSocket benchmark
+type webSocketCollector struct {
+	setup   *histogram
+	dropped int
+}
+
+func newWebSocketCollector() *webSocketCollector {
+	return &webSocketCollector{setup: newHistogram()}
+}
+
+func (c *webSocketCollector) add(stat *RequestStat) {
+	if stat.Setup > 0 {
+		c.setup.record(stat.Setup)
+	}
+
+	if stat.Dropped {
+		c.dropped++
+	}
+}
+
+func (c *webSocketCollector) stat() *WebSocketStat {
+	stat := &WebSocketStat{
+		Connections: int(c.setup.count),
+		Dropped:     c.dropped,
+	}
+
+	if c.setup.count > 0 {
+		stat.Setup = c.setup.phaseStat()
+	}
+
+	return stat
+}
+
+// webSocketSnapshot is WebSocket collector sent by agent to the coordinator
+type webSocketSnapshot struct {
+	Setup   histogramSnapshot `json:"setup"`
+	Dropped int               `json:"dropped"`
+}
+
+func (c *webSocketCollector) snapshot() *webSocketSnapshot {
+	return &webSocketSnapshot{Setup: c.setup.snapshot(), Dropped: c.dropped}
+}
+
+// merge adds connections and drops collected by an agent
+func (c *webSocketCollector) merge(s *webSocketSnapshot) error {
+	c.dropped += s.Dropped
+	return c.setup.mergeSnapshot(s.Setup)
+}
@@ -0,0 +1,222 @@
+package katyusha
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// wsServer replies to every message with "pending" and "done: <message>".
+// Message "drop" gets no reply.
+func wsServer(connections *int64) websocket.Handler {
+	return func(ws *websocket.Conn) {
+		atomic.AddInt64(connections, 1)
+		if ws.Request().Header.Get("X-Token") != "secret" {
+			return
+		}
+
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+
+			if msg == "drop" {
+				continue
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			websocket.Message.Send(ws, "pending")
+			time.Sleep(5 * time.Millisecond)
+			websocket.Message.Send(ws, "done: "+msg)
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "websocket")
+	if err != nil {
+		t.Fatalf("Can't create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	feederFile := filepath.Join(dir, "messages.csv")
+	if err := ioutil.WriteFile(feederFile, []byte("user\nanna\ndrop\nmaria\n"), 0600); err != nil {
+		t.Fatalf("Can't write feeder file: %v", err)
+	}
+
+	tt := []struct {
+		name        string
+		tls         bool
+		message     string
+		expect      string
+		feeder      *Feeder
+		minDuration time.Duration
+		success     int
+		dropped     int
+	}{
+		{
+			name:        "First reply",
+			message:     "hello ${seq}",
+			minDuration: 5 * time.Millisecond,
+			success:     30,
+		},
+		{
+			name:        "Expected reply",
+			message:     "hello",
+			expect:      "done: hello",
+			minDuration: 10 * time.Millisecond,
+			success:     30,
+		},
+		{
+			name:        "TLS",
+			tls:         true,
+			message:     "hello",
+			expect:      "done",
+			minDuration: 10 * time.Millisecond,
+			success:     30,
+		},
+		{
+			name:        "Dropped",
+			message:     "${user}",
+			expect:      "done",
+			feeder:      &Feeder{File: feederFile},
+			minDuration: 10 * time.Millisecond,
+			success:     20,
+			dropped:     10,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var connections int64
+
+			var server *httptest.Server
+			if tc.tls {
+				server = httptest.NewTLSServer(wsServer(&connections))
+			} else {
+				server = httptest.NewServer(wsServer(&connections))
+			}
+			defer server.Close()
+
+			benchmark, err := NewBenchmark(&BenchmarkParameters{
+				URL:             "ws" + strings.TrimPrefix(server.URL, "http"),
+				Headers:         headers{"X-Token": "secret"},
+				ConcurrentConns: 3,
+				ReqCount:        30,
+				ReadTimeout:     50 * time.Millisecond,
+				SkipVerify:      true,
+				Feeder:          tc.feeder,
+				WebSocket:       &WebSocket{Message: tc.message, Expect: tc.expect},
+			})
+			if err != nil {
+				t.Fatalf("Can't create benchmark: %v", err)
+			}
+
+			summary := benchmark.StartBenchmark(context.Background())
+
+			if summary.SuccessReq != tc.success || summary.ReqCount != 30 {
+				t.Errorf("Expected %d successful messages, got %d of %d: %v", tc.success, summary.SuccessReq, summary.ReqCount, summary.Errors)
+			}
+
+			ws := summary.WebSocket
+			// Worker opens a new connection after dropped message when it sends the next one
+			if ws == nil || ws.Dropped != tc.dropped || ws.Connections < 3 || ws.Connections > 3+tc.dropped || ws.Setup.Count != ws.Connections {
+				t.Fatalf("Unexpected WebSocket results: %+v", ws)
+			}
+
+			if n := atomic.LoadInt64(&connections); n != int64(ws.Connections) {
+				t.Errorf("Server got %d connections, expected %d", n, ws.Connections)
+			}
+
+			if tc.dropped > 0 && summary.Errors[errReplyTimeout.Error()] != tc.dropped {
+				t.Errorf("Dropped messages should be reported as errors: %v", summary.Errors)
+			}
+
+			if summary.MinReqTime < tc.minDuration {
+				t.Errorf("Round trip should end with the expected reply, min %v", summary.MinReqTime)
+			}
+
+			if summary.Phases.Connect.Count != ws.Connections || tc.tls && summary.Phases.TLS.Count != ws.Connections {
+				t.Errorf("New connections should have phases: %+v", summary.Phases)
+			}
+		})
+	}
+}
+
+func TestWebSocketRate(t *testing.T) {
+	var connections int64
+	server := httptest.NewServer(wsServer(&connections))
+	defer server.Close()
+
+	benchmark, err := NewBenchmark(&BenchmarkParameters{
+		URL:             "ws" + strings.TrimPrefix(server.URL, "http"),
+		Headers:         headers{"X-Token": "secret"},
+		ConcurrentConns: 2,
+		Rate:            100,
+		Duration:        500 * time.Millisecond,
+		WebSocket:       &WebSocket{Message: "hello"},
+	})
+	if err != nil {
+		t.Fatalf("Can't create benchmark: %v", err)
+	}
+
+	summary := benchmark.StartBenchmark(context.Background())
+	if summary.ScheduledReq < 40 || summary.ScheduledReq > 60 || summary.FailReq != 0 {
+		t.Errorf("Messages should be sent at the rate: %d scheduled, %d failed", summary.ScheduledReq, summary.FailReq)
+	}
+
+	if summary.WebSocket.Connections != 2 {
+		t.Errorf("Every worker should open one connection: %+v", summary.WebSocket)
+	}
+}
+
+func TestInvalidWebSocket(t *testing.T) {
+	tt := []struct {
+		name   string
+		params BenchmarkParameters
+	}{
+		{
+			name:   "HTTP URL",
+			params: BenchmarkParameters{URL: "http://127.0.0.1"},
+		},
+		{
+			name:   "Scenario",
+			params: BenchmarkParameters{URL: "ws://127.0.0.1", Scenario: []Endpoint{{URL: "/chat"}}},
+		},
+		{
+			name:   "Protocol",
+			params: BenchmarkParameters{URL: "wss://127.0.0.1", Protocol: ProtocolHTTP2},
+		},
+		{
+			name:   "Assertions",
+			params: BenchmarkParameters{URL: "ws://127.0.0.1", Assertions: []Assertion{{Contains: "ok"}}},
+		},
+		{
+			name:   "Unknown variable",
+			params: BenchmarkParameters{URL: "ws://127.0.0.1", WebSocket: &WebSocket{Message: "${user}"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params.ReqCount = 1
+			if tc.params.WebSocket == nil {
+				tc.params.WebSocket = &WebSocket{Message: "hello"}
+			}
+
+			_, err := NewBenchmark(&tc.params)
+			if err == nil {
+				t.Errorf("Benchmark should not be created")
+			}
+		})
+	}
+}